@@ -0,0 +1,333 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+)
+
+// ContextAttribute describes an attribute of the context which is passed to the Starlark main function.
+type ContextAttribute struct {
+	// Name of the attribute, e.g. full_name.
+	Name string
+	// Type is the Starlark type of the attribute value.
+	Type string
+	// Doc describes the attribute.
+	Doc string
+	// Attributes of struct values.
+	Attributes []ContextAttribute
+	// index of the model field the attribute is generated from.
+	index []int
+}
+
+// Attribute returns the nested attribute with the given name.
+func (a ContextAttribute) Attribute(name string) (ContextAttribute, bool) {
+	i := slices.IndexFunc(a.Attributes, func(attribute ContextAttribute) bool {
+		return attribute.Name == name
+	})
+	if i < 0 {
+		return ContextAttribute{}, false
+	}
+
+	return a.Attributes[i], true
+}
+
+// StarlarkContextSchema describes the ctx value which is passed to main.
+// It is generated from the json names of model.Repo and model.Pipeline,
+// the netrc of the environment is never part of it.
+var StarlarkContextSchema = ContextAttribute{
+	Name: "context",
+	Type: "struct",
+	Doc:  "The context of the pipeline the configuration is generated for.",
+	Attributes: []ContextAttribute{
+		contextSchema("repo", reflect.TypeFor[model.Repo](), []int{0}, "The repository of the pipeline."),
+		contextSchema("build", reflect.TypeFor[model.Pipeline](), []int{1}, "The pipeline the configuration is generated for."),
+	},
+}
+
+// contextAliases keeps the attribute names of earlier versions working.
+var contextAliases = map[string]map[string]string{
+	"repo": {
+		"fullName": "full_name",
+		"branch":   "default_branch",
+	},
+}
+
+// contextExcluded lists model fields which are not known when the configuration is generated.
+var contextExcluded = map[string][]string{
+	"build": {"errors", "workflows", "cancel_info"},
+}
+
+// contextDocs documents the generated context attributes.
+var contextDocs = map[string]string{
+	"repo.id":                              "The woodpecker id of the repository.",
+	"repo.forge_id":                        "The woodpecker id of the forge.",
+	"repo.forge_remote_id":                 "The id of the repository on the forge.",
+	"repo.org_id":                          "The woodpecker id of the organization.",
+	"repo.owner":                           "The owner of the repository.",
+	"repo.name":                            "The name of the repository.",
+	"repo.full_name":                       "The full name of the repository, owner/name.",
+	"repo.fullName":                        "Deprecated alias of full_name.",
+	"repo.avatar_url":                      "The avatar url of the repository.",
+	"repo.forge_url":                       "The url of the repository on the forge.",
+	"repo.clone_url":                       "The http clone url of the repository.",
+	"repo.clone_url_ssh":                   "The ssh clone url of the repository.",
+	"repo.default_branch":                  "The default branch of the repository.",
+	"repo.branch":                          "Deprecated alias of default_branch.",
+	"repo.pr_enabled":                      "Whether pull request pipelines are enabled.",
+	"repo.timeout":                         "The pipeline timeout in minutes.",
+	"repo.visibility":                      "The visibility of the repository, public, private or internal.",
+	"repo.private":                         "Whether the repository is private on the forge.",
+	"repo.trusted":                         "The trusted settings of the repository.",
+	"repo.trusted.network":                 "Whether steps may use custom network settings.",
+	"repo.trusted.volumes":                 "Whether steps may mount volumes.",
+	"repo.trusted.security":                "Whether steps may use privileged security settings.",
+	"repo.require_approval":                "Which pipelines require an approval.",
+	"repo.approval_allowed_users":          "The users whose pipelines do not require an approval.",
+	"repo.active":                          "Whether the repository is active.",
+	"repo.allow_pr":                        "Whether pull requests are allowed.",
+	"repo.allow_deploy":                    "Whether deployments are allowed.",
+	"repo.config_file":                     "The configured configuration path.",
+	"repo.cancel_previous_pipeline_events": "The events whose previous pipelines are canceled.",
+	"repo.netrc_trusted":                   "The plugins which get access to the netrc credentials.",
+	"repo.config_extension_endpoint":       "The configuration extension endpoint of the repository.",
+	"repo.config_extension_exclusive":      "Whether the configuration extension is used exclusively.",
+	"repo.config_extension_netrc":          "Whether the configuration extension receives the netrc credentials.",
+	"repo.registry_extension_endpoint":     "The registry extension endpoint of the repository.",
+	"repo.registry_extension_netrc":        "Whether the registry extension receives the netrc credentials.",
+	"repo.secret_extension_endpoint":       "The secret extension endpoint of the repository.",
+	"repo.secret_extension_netrc":          "Whether the secret extension receives the netrc credentials.",
+	"repo.has_forge_name_conflict":         "Whether the forge reported another repository with the same name.",
+	"repo.has_no_forge_repo":               "Whether the repository no longer exists on the forge.",
+	"build.id":                             "The woodpecker id of the pipeline.",
+	"build.number":                         "The number of the pipeline.",
+	"build.author":                         "The author of the commit.",
+	"build.parent":                         "The number of the pipeline this one was restarted from.",
+	"build.event":                          "The event which triggered the pipeline, e.g. push, pull_request, tag, cron, manual or deployment.",
+	"build.event_reason":                   "The reasons for the event, e.g. the pull request actions.",
+	"build.status":                         "The status of the pipeline.",
+	"build.created":                        "The unix timestamp the pipeline was created at.",
+	"build.updated":                        "The unix timestamp the pipeline was updated at.",
+	"build.started":                        "The unix timestamp the pipeline was started at.",
+	"build.finished":                       "The unix timestamp the pipeline was finished at.",
+	"build.deploy_to":                      "The target environment of a deployment.",
+	"build.deploy_task":                    "The task of a deployment.",
+	"build.commit":                         "The commit sha of the pipeline.",
+	"build.branch":                         "The branch of the pipeline.",
+	"build.ref":                            "The git ref of the pipeline.",
+	"build.refspec":                        "The refspec of a pull request, source:target.",
+	"build.title":                          "The title of the pipeline, e.g. the pull request title.",
+	"build.message":                        "The commit message.",
+	"build.timestamp":                      "The unix timestamp of the commit.",
+	"build.sender":                         "The user who triggered the pipeline, or the name of the cron job.",
+	"build.author_avatar":                  "The avatar url of the author.",
+	"build.author_email":                   "The email address of the author.",
+	"build.forge_url":                      "The url of the commit, pull request or tag on the forge.",
+	"build.reviewed_by":                    "The user who approved the pipeline.",
+	"build.reviewed":                       "The unix timestamp the pipeline was approved at.",
+	"build.changed_files":                  "The files changed by the commit or pull request.",
+	"build.variables":                      "The additional variables of a manual pipeline.",
+	"build.pr_labels":                      "The labels of the pull request.",
+	"build.pr_milestone":                   "The milestone of the pull request.",
+	"build.cron":                           "The name of the cron job which triggered the pipeline.",
+	"build.is_prerelease":                  "Whether the tag is a prerelease.",
+	"build.from_fork":                      "Whether the pull request comes from a fork.",
+	"build.version":                        "The woodpecker version which created the pipeline.",
+}
+
+// contextSchema generates the attributes of the given model type from its json field names.
+func contextSchema(name string, t reflect.Type, index []int, doc string) ContextAttribute {
+	attribute := ContextAttribute{Name: name, Doc: doc, index: index}
+	attribute.Type = starlarkTypeOf(t)
+	if attribute.Type != "struct" {
+		return attribute
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || jsonName == "" || jsonName == "-" || slices.Contains(contextExcluded[name], jsonName) {
+			continue
+		}
+
+		path := name + "." + jsonName
+		child := contextSchema(path, field.Type, []int{i}, contextDocs[path])
+		child.Name = jsonName
+		attribute.Attributes = append(attribute.Attributes, child)
+	}
+
+	for alias, target := range contextAliases[name] {
+		if target, ok := attribute.Attribute(target); ok {
+			target.Name = alias
+			target.Doc = contextDocs[name+"."+alias]
+			attribute.Attributes = append(attribute.Attributes, target)
+		}
+	}
+
+	slices.SortFunc(attribute.Attributes, func(a, b ContextAttribute) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return attribute
+}
+
+// starlarkTypeOf returns the name of the Starlark type the given Go type is mapped to.
+func starlarkTypeOf(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return starlarkTypeOf(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map:
+		return "dict"
+	case reflect.Struct:
+		return "struct"
+	default:
+		return "unknown"
+	}
+}
+
+// starlarkContext builds the ctx value for the given environment from the StarlarkContextSchema.
+func starlarkContext(env Environment) starlark.Value {
+	// IMPORTANT: never add any env.Netrc values to the context, this contains sensitive information!!!
+	return contextValue(StarlarkContextSchema, reflect.ValueOf(struct {
+		Repo     model.Repo
+		Pipeline model.Pipeline
+	}{Repo: env.Repo, Pipeline: env.Pipeline}))
+}
+
+// contextValue converts the given model value into a Starlark value, structs are built from the attributes.
+func contextValue(attribute ContextAttribute, v reflect.Value) starlark.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return starlark.None
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return starlark.String(v.String())
+	case reflect.Bool:
+		return starlark.Bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return starlark.MakeUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(v.Float())
+	case reflect.Slice, reflect.Array:
+		list := make([]starlark.Value, 0, v.Len())
+		for i := range v.Len() {
+			list = append(list, contextValue(ContextAttribute{}, v.Index(i)))
+		}
+
+		return starlark.NewList(list)
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(a.String(), b.String())
+		})
+
+		dict := starlark.NewDict(len(keys))
+		for _, key := range keys {
+			_ = dict.SetKey(contextValue(ContextAttribute{}, key), contextValue(ContextAttribute{}, v.MapIndex(key)))
+		}
+
+		return dict
+	case reflect.Struct:
+		members := starlark.StringDict{}
+		for _, child := range attribute.Attributes {
+			members[child.Name] = contextValue(child, v.FieldByIndex(child.index))
+		}
+
+		return starlarkstruct.FromStringDict(starlark.String(attribute.Name), members)
+	default:
+		return starlark.None
+	}
+}
+
+// contextData builds the context as plain Go values for the given environment from the StarlarkContextSchema,
+// other converters use the same names as Starlark scripts, e.g. repo.full_name.
+func contextData(env Environment) map[string]any {
+	// IMPORTANT: never add any env.Netrc values to the data, this contains sensitive information!!!
+	data, _ := contextGoValue(StarlarkContextSchema, reflect.ValueOf(struct {
+		Repo     model.Repo
+		Pipeline model.Pipeline
+	}{Repo: env.Repo, Pipeline: env.Pipeline})).(map[string]any)
+
+	return data
+}
+
+// contextGoValue converts the given model value into plain Go values, structs become maps of the attributes.
+func contextGoValue(attribute ContextAttribute, v reflect.Value) any {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Slice, reflect.Array:
+		list := make([]any, 0, v.Len())
+		for i := range v.Len() {
+			list = append(list, contextGoValue(ContextAttribute{}, v.Index(i)))
+		}
+
+		return list
+	case reflect.Map:
+		m := make(map[string]any, v.Len())
+		for _, key := range v.MapKeys() {
+			m[key.String()] = contextGoValue(ContextAttribute{}, v.MapIndex(key))
+		}
+
+		return m
+	case reflect.Struct:
+		m := make(map[string]any, len(attribute.Attributes))
+		for _, child := range attribute.Attributes {
+			m[child.Name] = contextGoValue(child, v.FieldByIndex(child.index))
+		}
+
+		return m
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkContextSchema(t *testing.T) {
+	var check func(t *testing.T, attribute wccs.ContextAttribute, path string)
+	check = func(t *testing.T, attribute wccs.ContextAttribute, path string) {
+		t.Helper()
+
+		assert.NotEmpty(t, attribute.Doc, "%s is not documented", path)
+		assert.NotEqual(t, "unknown", attribute.Type, path)
+		assert.NotContains(t, []string{"netrc", "errors", "workflows"}, attribute.Name, path)
+
+		for _, child := range attribute.Attributes {
+			check(t, child, path+"."+child.Name)
+		}
+	}
+
+	check(t, wccs.StarlarkContextSchema, "ctx")
+
+	repo, ok := wccs.StarlarkContextSchema.Attribute("repo")
+	assert.True(t, ok)
+
+	trusted, ok := repo.Attribute("trusted")
+	assert.True(t, ok)
+	assert.Equal(t, "struct", trusted.Type)
+	assert.Len(t, trusted.Attributes, 3)
+}
+
+func TestStarlarkConverter_Context(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	env := wccs.Environment{
+		Netrc: model.Netrc{Machine: "forge", Login: "user", Password: "secret"},
+		Repo: model.Repo{
+			Owner:      "opencloud-eu",
+			Name:       "wccs",
+			FullName:   "opencloud-eu/wccs",
+			Branch:     "main",
+			Visibility: model.VisibilityPublic,
+			Trusted:    model.TrustedConfiguration{Network: true},
+		},
+		Pipeline: model.Pipeline{
+			Number:              42,
+			Event:               model.EventPull,
+			ChangedFiles:        []string{"go.mod", "README.md"},
+			PullRequestLabels:   []string{"dependencies"},
+			AdditionalVariables: map[string]string{"b": "2", "a": "1"},
+			FromFork:            true,
+		},
+	}
+
+	for src, expected := range map[string]string{
+		`ctx.repo.fullName + " " + ctx.repo.full_name`:        " opencloud-eu/wccs opencloud-eu/wccs",
+		`ctx.repo.branch == ctx.repo.default_branch`:          " true",
+		`[ctx.repo.visibility, ctx.repo.trusted.network]`:     "\n  - public\n  - true",
+		`ctx.build.number + 1`:                                " 43",
+		`[ctx.build.event, ctx.build.from_fork]`:              "\n  - pull_request\n  - true",
+		`ctx.build.changed_files + ctx.build.pr_labels`:       "\n  - go.mod\n  - README.md\n  - dependencies",
+		`ctx.build.variables`:                                 "\n  a: \"1\"\n  b: \"2\"",
+		`[hasattr(ctx, "netrc"), hasattr(ctx.repo, "netrc")]`: "\n  - false\n  - false",
+	} {
+		files, err := c.Convert(t.Context(), wccs.File{Name: "ctx.star", Data: `
+def main(ctx):
+  return [{"name": "ctx", "result": ` + src + `}]
+`}, env)
+		assert.NoError(t, err, src)
+		assert.Equal(t, "result:"+expected+"\n", files[0].Data, src)
+	}
+}
@@ -15,26 +15,25 @@
 package wccs
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"log/slog"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/samber/lo"
+	starlarktime "go.starlark.net/lib/time"
 	"go.starlark.net/starlark"
-	"go.starlark.net/starlarkstruct"
 	"go.starlark.net/syntax"
-	"gopkg.in/yaml.v3"
 )
 
 // Converters contains multiple converters.
 type Converters []Converter
 
 // Convert converts multiple files using the available converters.
-func (converters Converters) Convert(files []File, env Environment) ([]File, error) {
+func (converters Converters) Convert(ctx context.Context, files []File, env Environment) ([]File, error) {
 	var results []File
 	for _, file := range files {
 		for _, converter := range converters {
@@ -42,7 +41,7 @@ func (converters Converters) Convert(files []File, env Environment) ([]File, err
 				continue
 			}
 
-			converted, err := converter.Convert(file, env)
+			converted, err := converter.Convert(ctx, file, env)
 			if err != nil {
 				return nil, err
 			}
@@ -63,36 +62,344 @@ func (converters Converters) Convert(files []File, env Environment) ([]File, err
 	return results, nil
 }
 
+// Warnings returns the unique warnings of the given files, files generated from the same source share its warnings.
+func Warnings(files []File) []string {
+	return lo.Uniq(lo.FlatMap(files, func(f File, _ int) []string {
+		return f.Warnings
+	}))
+}
+
+// StarlarkMode selects the context main receives and the documents it has to return.
+type StarlarkMode string
+
+const (
+	// StarlarkModeAuto uses the drone mode for .drone.star files and the woodpecker mode for all others.
+	StarlarkModeAuto StarlarkMode = "auto"
+	// StarlarkModeWoodpecker passes the woodpecker context and expects woodpecker workflows.
+	StarlarkModeWoodpecker StarlarkMode = "woodpecker"
+	// StarlarkModeDrone passes the drone context and translates the returned drone pipelines.
+	StarlarkModeDrone StarlarkMode = "drone"
+)
+
+// droneStarlarkFile is the name drone uses for Starlark configuration files.
+const droneStarlarkFile = ".drone.star"
+
+// StarlarkLimits restricts the resources a conversion may use, a zero value disables the limit.
+type StarlarkLimits struct {
+	// MaxExecutionSteps is the maximal number of steps all modules of a conversion may execute together.
+	MaxExecutionSteps uint64
+	// Timeout of the whole conversion.
+	Timeout time.Duration
+	// MaxOutputSize is the maximal size of all generated workflows in bytes.
+	MaxOutputSize int
+	// MaxWorkflows is the maximal number of generated workflows.
+	MaxWorkflows int
+	// MaxFileReads is the maximal number of files and directories the files module may fetch.
+	MaxFileReads int
+}
+
 // StarlarkConverter is a converter that reads, transpiles and migrates Starlark configuration files.
 type StarlarkConverter struct {
-	logger *slog.Logger
+	logger       *slog.Logger
+	loadRoot     string
+	repositories RepositorySource
+	moduleCache  *ModuleCache
+	modules      []StarlarkModule
+	predeclared  starlark.StringDict
+	mode         StarlarkMode
+	limits       StarlarkLimits
+	programCache *ProgramCache
+	coverage     *Coverage
+	profiler     *StarlarkProfiler
+	globals      *StarlarkGlobals
+	// org holds the predeclared values of the globals.
+	org *starlarkGlobals
+}
+
+// StarlarkOption configures the StarlarkConverter.
+type StarlarkOption func(*StarlarkConverter)
+
+// WithStarlarkLoadRoot restricts load statements to the given directory of the provider,
+// modules are resolved relative to it and must not leave it.
+func WithStarlarkLoadRoot(root string) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.loadRoot = root
+	}
+}
+
+// WithStarlarkRepositories fetches remote modules from the given repositories
+// instead of the forge the entry file comes from.
+func WithStarlarkRepositories(repositories RepositorySource) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.repositories = repositories
+	}
+}
+
+// WithStarlarkModuleCache keeps fetched remote modules in the given cache.
+func WithStarlarkModuleCache(cache ModuleCache) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.moduleCache = &cache
+	}
+}
+
+// WithStarlarkModules sets the predeclared modules, all modules are available by default.
+func WithStarlarkModules(modules ...StarlarkModule) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.modules = modules
+	}
+}
+
+// WithStarlarkMode sets the mode of the converter, the auto mode is used by default.
+func WithStarlarkMode(mode StarlarkMode) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.mode = mode
+	}
+}
+
+// WithStarlarkLimits restricts the resources a conversion may use, conversions are unlimited by default.
+func WithStarlarkLimits(limits StarlarkLimits) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.limits = limits
+	}
+}
+
+// WithStarlarkProgramCache keeps compiled programs in the given cache,
+// files with the same name and content are only parsed and resolved once.
+func WithStarlarkProgramCache(cache *ProgramCache) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.programCache = cache
+	}
+}
+
+// WithStarlarkCoverage records the executed lines of all files except test files in the given coverage.
+func WithStarlarkCoverage(coverage *Coverage) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.coverage = coverage
+	}
+}
+
+// WithStarlarkProfiler profiles every conversion with the given profiler, conversions are no longer executed in parallel.
+func WithStarlarkProfiler(profiler *StarlarkProfiler) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.profiler = profiler
+	}
+}
+
+// WithStarlarkGlobals predeclares the given operator defined values as a struct in every script.
+func WithStarlarkGlobals(globals StarlarkGlobals) StarlarkOption {
+	return func(c *StarlarkConverter) {
+		c.globals = &globals
+	}
 }
 
 // NewStarlarkConverter returns a new StarlarkConverter.
-func NewStarlarkConverter(logger *slog.Logger) (StarlarkConverter, error) {
-	return StarlarkConverter{logger: logger}, nil
+func NewStarlarkConverter(logger *slog.Logger, options ...StarlarkOption) (StarlarkConverter, error) {
+	c := StarlarkConverter{logger: logger, modules: StarlarkModules, mode: StarlarkModeAuto}
+	for _, option := range options {
+		option(&c)
+	}
+
+	if !slices.Contains([]StarlarkMode{StarlarkModeAuto, StarlarkModeWoodpecker, StarlarkModeDrone}, c.mode) {
+		return StarlarkConverter{}, fmt.Errorf("%w: mode %s", ErrUnknownType, c.mode)
+	}
+
+	predeclared, err := starlarkModules(c.modules)
+	if err != nil {
+		return StarlarkConverter{}, err
+	}
+	c.predeclared = predeclared
+	c.predeclared["wccs"] = starlarkWCCSModule
+
+	if c.globals != nil {
+		if c.org, err = newStarlarkGlobals(*c.globals); err != nil {
+			return StarlarkConverter{}, err
+		}
+
+		if c.predeclared.Has(c.org.name) {
+			return StarlarkConverter{}, fmt.Errorf("%w: starlark globals %s", ErrNameConflict, c.org.name)
+		}
+		c.predeclared[c.org.name] = c.org.value
+	}
+
+	return c, nil
 }
 
-func (p StarlarkConverter) Compatible(f File) bool {
-	return slices.Contains([]string{".star"}, filepath.Ext(f.Name))
+// Exec executes the given file the same way Convert does, without calling main.
+// It returns the globals of the file and the thread which must be used to call them,
+// the execution of the file is canceled when the context is done.
+func (p StarlarkConverter) Exec(ctx context.Context, f File, env Environment) (starlark.StringDict, *starlark.Thread, error) {
+	globals, thread, loader, err := p.exec(ctx, f, env)
+	loader.release()
+
+	return globals, thread, p.limitError(ctx, loader, newDiagnostic(err, loader.sources))
 }
 
-// Convert reads, transpiles and migrates Starlark configuration files to the required format.
-func (p StarlarkConverter) Convert(f File, env Environment) ([]File, error) {
-	if f.Data == "" {
-		return nil, ErrNoContent
+func (p StarlarkConverter) exec(ctx context.Context, f File, env Environment) (starlark.StringDict, *starlark.Thread, *starlarkLoader, error) {
+	predeclared := p.predeclared
+	if p.org != nil {
+		predeclared = p.org.predeclared(predeclared, env)
+	}
+
+	loader := &starlarkLoader{
+		ctx:          ctx,
+		entry:        f,
+		root:         p.loadRoot,
+		repositories: p.repositories,
+		cache:        p.moduleCache,
+		programs:     p.programCache,
+		coverage:     p.coverage,
+		options:      syntax.LegacyFileOptions(),
+		predeclared:  predeclared,
+		sources:      map[string]string{f.Name: f.Data},
+		modules:      map[string]*starlarkModule{},
+		files:        newRepoFiles(f.Source, p.limits.MaxFileReads),
+		maxSteps:     p.limits.MaxExecutionSteps,
+	}
+	loader.thread = func() *starlark.Thread {
+		thread := p.newThread(ctx, env, loader)
+		loader.threads = append(loader.threads, thread)
+		loader.limitSteps(thread)
+
+		return thread
+	}
+
+	thread := loader.thread()
+	globals, err := loader.execFile(thread, f.Name, f.Data)
+
+	return globals, thread, loader, err
+}
+
+// limitError returns a distinct error if the given error was caused by an exceeded limit or a canceled context.
+func (p StarlarkConverter) limitError(ctx context.Context, loader *starlarkLoader, err error) error {
+	return p.threadLimitError(ctx, loader.threads, err)
+}
+
+// threadLimitError is limitError for the given threads, only their execution steps are counted.
+func (p StarlarkConverter) threadLimitError(ctx context.Context, threads []*starlark.Thread, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", context.Cause(ctx), err)
 	}
 
+	if limit := p.limits.MaxExecutionSteps; limit != 0 && executionSteps(threads) >= limit {
+		return fmt.Errorf("%w: max execution steps %d: %w", ErrLimitExceeded, limit, err)
+	}
+
+	return err
+}
+
+// executionSteps returns the steps the given threads executed together.
+func executionSteps(threads []*starlark.Thread) uint64 {
+	var steps uint64
+	for _, thread := range threads {
+		steps += thread.ExecutionSteps()
+	}
+
+	return steps
+}
+
+// newThread returns a thread for the given environment, every module of a conversion runs in its own thread.
+func (p StarlarkConverter) newThread(ctx context.Context, env Environment, loader *starlarkLoader) *starlark.Thread {
 	thread := &starlark.Thread{
 		Name: "drone",
 		Print: func(_ *starlark.Thread, msg string) {
 			p.logger.Debug(msg)
 		},
+		Load: loader.Load,
 	}
+	thread.SetLocal(starlarkRepoFilesKey, loader.files)
+
+	if p.limits.MaxExecutionSteps != 0 {
+		thread.SetMaxExecutionSteps(p.limits.MaxExecutionSteps)
+	}
+
+	// the thread is canceled as soon as the conversion times out or the request is gone
+	loader.stops = append(loader.stops, context.AfterFunc(ctx, func() {
+		thread.Cancel(context.Cause(ctx).Error())
+	}))
+
+	// time.now returns the pipeline timestamp, this keeps conversions reproducible
+	starlarktime.SetNow(thread, func() (time.Time, error) {
+		return pipelineTime(env), nil
+	})
+
+	return thread
+}
+
+// pipelineTime returns the commit timestamp of the pipeline, or its creation time if the timestamp is unknown.
+func pipelineTime(env Environment) time.Time {
+	timestamp := env.Pipeline.Timestamp
+	if timestamp == 0 {
+		timestamp = env.Pipeline.Created
+	}
+
+	return time.Unix(timestamp, 0).UTC()
+}
+
+// fileMode returns the mode which is used for the given file.
+func (p StarlarkConverter) fileMode(f File) StarlarkMode {
+	if p.mode != StarlarkModeAuto {
+		return p.mode
+	}
+
+	if name := filepath.Base(f.Name); name == droneStarlarkFile || strings.HasSuffix(name, droneStarlarkFile) {
+		return StarlarkModeDrone
+	}
+
+	return StarlarkModeWoodpecker
+}
+
+// mainContext returns the context which is passed to main of the given file.
+func (p StarlarkConverter) mainContext(f File, env Environment) starlark.Value {
+	if p.fileMode(f) == StarlarkModeDrone {
+		return droneContext(env)
+	}
+
+	return starlarkContext(env)
+}
 
-	globals, err := starlark.ExecFileOptions(syntax.LegacyFileOptions(), thread, "", f.Data, nil)
+func (p StarlarkConverter) Compatible(f File) bool {
+	return slices.Contains([]string{".star"}, filepath.Ext(f.Name))
+}
+
+// Convert reads, transpiles and migrates Starlark configuration files to the required format.
+func (p StarlarkConverter) Convert(ctx context.Context, f File, env Environment) ([]File, error) {
+	if f.Data == "" {
+		return nil, ErrNoContent
+	}
+
+	if p.profiler == nil {
+		return p.convert(ctx, f, env)
+	}
+
+	var files []File
+	err := p.profiler.profile(ctx, env.Repo.FullName+"-"+f.Name, func() error {
+		var err error
+		files, err = p.convert(ctx, f, env)
+
+		return err
+	})
+
+	return files, err
+}
+
+func (p StarlarkConverter) convert(ctx context.Context, f File, env Environment) ([]File, error) {
+	if p.limits.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, p.limits.Timeout, fmt.Errorf("%w: timeout %s", ErrLimitExceeded, p.limits.Timeout))
+		defer cancel()
+	}
+
+	globals, thread, loader, err := p.exec(ctx, f, env)
+	defer loader.release()
+	if p.programCache != nil {
+		p.logger.Debug("executed file", "file", f.Name, "program_cache", p.programCache.Stats())
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%w: error executing file", err)
+		return nil, fmt.Errorf("%w: error executing file", p.limitError(ctx, loader, newDiagnostic(err, loader.sources)))
 	}
 
 	entrypoint, ok := globals["main"]
@@ -100,63 +407,43 @@ func (p StarlarkConverter) Convert(f File, env Environment) ([]File, error) {
 		return nil, fmt.Errorf("%w: main", ErrNoEntrypoint)
 	}
 
-	v, err := starlark.Call(thread, entrypoint, []starlark.Value{
-		starlarkstruct.FromStringDict(
-			starlark.String("context"),
-			starlark.StringDict{
-				// IMPORTANT: just a hint, never add any env.Netrc values to the context, this contains sensitive information!!!
-				"repo": starlarkstruct.FromStringDict(starlark.String("repo"), starlark.StringDict{
-					"owner":    starlark.String(env.Repo.Owner),
-					"name":     starlark.String(env.Repo.Name),
-					"fullName": starlark.String(env.Repo.FullName),
-					"branch":   starlark.String(env.Repo.Branch),
-				}),
-				"build": starlarkstruct.FromStringDict(starlark.String("build"), starlark.StringDict{
-					"event":   starlark.String(env.Pipeline.Event),
-					"title":   starlark.String(env.Pipeline.Title),
-					"commit":  starlark.String(env.Pipeline.Commit),
-					"ref":     starlark.String(env.Pipeline.Ref),
-					"branch":  starlark.String(env.Pipeline.Branch),
-					"message": starlark.String(env.Pipeline.Message),
-					"sender":  starlark.String(env.Pipeline.Sender),
-				}),
-			},
-		),
-	}, nil)
+	v, err := starlark.Call(thread, entrypoint, []starlark.Value{p.mainContext(f, env)}, nil)
 	if err != nil {
-		return nil, fmt.Errorf("%w: error building conf", err)
+		return nil, fmt.Errorf("%w: error building conf", p.limitError(ctx, loader, newDiagnostic(err, loader.sources)))
 	}
 
-	// toDo: shame on me....
-	hacky := v.String()
-	hacky = strings.ReplaceAll(hacky, "False", "false")
-	hacky = strings.ReplaceAll(hacky, "True", "true")
-	hacky = strings.ReplaceAll(hacky, "None", "[]")
+	if p.fileMode(f) == StarlarkModeDrone {
+		if v, err = (droneTranslator{}).workflows(v); err != nil {
+			return nil, err
+		}
+	}
 
-	var workflows []map[string]any
-	if err := json.Unmarshal([]byte(hacky), &workflows); err != nil {
+	files, err := starlarkFiles(v, f.Name)
+	if err != nil {
 		return nil, err
 	}
 
-	var files []File
-	for _, workflow := range workflows {
-		name, ok := workflow["name"].(string)
-		if !ok || name == "" {
-			return nil, fmt.Errorf("%w: name", ErrMissingParam)
-		}
-		delete(workflow, "name")
+	return files, p.limits.check(files)
+}
 
-		buf := new(bytes.Buffer)
-		enc := yaml.NewEncoder(buf)
-		enc.SetIndent(2) //nolint:mnd
-		if err := enc.Encode(workflow); err != nil {
-			return nil, err
+// check returns an error if the generated files exceed the workflow or output size limit.
+func (l StarlarkLimits) check(files []File) error {
+	return checkOutput(files, l.MaxWorkflows, l.MaxOutputSize)
+}
+
+// checkOutput returns an error if the generated files exceed the given number of workflows or size in bytes,
+// a zero value disables the limit.
+func checkOutput(files []File, maxWorkflows, maxOutputSize int) error {
+	if maxWorkflows != 0 && len(files) > maxWorkflows {
+		return fmt.Errorf("%w: max workflows %d, got %d", ErrLimitExceeded, maxWorkflows, len(files))
+	}
+
+	var size int
+	for _, file := range files {
+		if size += len(file.Data); maxOutputSize != 0 && size > maxOutputSize {
+			return fmt.Errorf("%w: max output size %d bytes", ErrLimitExceeded, maxOutputSize)
 		}
-		files = append(files, File{
-			Name: strings.TrimSuffix(name, filepath.Ext(name)) + ".yaml",
-			Data: buf.String(),
-		})
 	}
 
-	return files, nil
+	return nil
 }
@@ -15,8 +15,12 @@
 package wccs_test
 
 import (
+	"context"
 	_ "embed"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.woodpecker-ci.org/woodpecker/v3/server/model"
@@ -42,24 +46,24 @@ func TestStarlarkConverter_Convert(t *testing.T) {
 	assert.Nil(t, err)
 
 	t.Run("fails without content", func(t *testing.T) {
-		_, err := c.Convert(wccs.File{}, wccs.Environment{})
+		_, err := c.Convert(t.Context(), wccs.File{}, wccs.Environment{})
 		assert.ErrorIs(t, err, wccs.ErrNoContent)
 	})
 
 	t.Run("fails if the main entrypoint does not exist", func(t *testing.T) {
-		_, err := c.Convert(wccs.File{Data: `foo = "bar"`}, wccs.Environment{})
+		_, err := c.Convert(t.Context(), wccs.File{Data: `foo = "bar"`}, wccs.Environment{})
 		assert.ErrorIs(t, err, wccs.ErrNoEntrypoint)
 	})
 
 	t.Run("fails without a name", func(t *testing.T) {
-		_, err := c.Convert(wccs.File{Data: environmentStar}, wccs.Environment{})
+		_, err := c.Convert(t.Context(), wccs.File{Data: environmentStar}, wccs.Environment{})
 		assert.ErrorIs(t, err, wccs.ErrMissingParam)
 		assert.Contains(t, err.Error(), "name")
 	})
 
 	t.Run("adds the YAML extension", func(t *testing.T) {
 		build := func(name string) wccs.File {
-			files, err := c.Convert(wccs.File{Data: environmentStar}, wccs.Environment{Repo: model.Repo{Name: name}})
+			files, err := c.Convert(t.Context(), wccs.File{Data: environmentStar}, wccs.Environment{Repo: model.Repo{Name: name}})
 			assert.Nil(t, err)
 			assert.Len(t, files, 1)
 			return files[0]
@@ -70,7 +74,7 @@ func TestStarlarkConverter_Convert(t *testing.T) {
 	})
 
 	t.Run("converts the environment", func(t *testing.T) {
-		files, err := c.Convert(wccs.File{Data: environmentStar}, wccs.Environment{Repo: model.Repo{Name: "testing"}, Pipeline: model.Pipeline{Title: "tests"}})
+		files, err := c.Convert(t.Context(), wccs.File{Data: environmentStar}, wccs.Environment{Repo: model.Repo{Name: "testing"}, Pipeline: model.Pipeline{Title: "tests"}})
 		assert.Nil(t, err)
 		assert.Len(t, files, 1)
 		file := files[0]
@@ -97,4 +101,181 @@ func TestStarlarkConverter_Convert(t *testing.T) {
 			assert.Len(t, data.None, 0)
 		})
 	})
+	t.Run("keeps the workflow key order", func(t *testing.T) {
+		files, err := c.Convert(t.Context(), wccs.File{Data: `
+def main(ctx):
+  return [{
+    "name": "ordered",
+    "steps": {"test": {"commands": ["echo True"]}, "build": {"commands": ["echo None"]}},
+    "when": {"event": "push"},
+  }]
+`}, wccs.Environment{})
+		assert.Nil(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "steps:\n  test:\n    commands:\n      - echo True\n  build:\n    commands:\n      - echo None\nwhen:\n  event: push\n", files[0].Data)
+	})
+
+	t.Run("fails on values that cannot be serialized", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Data: `
+def main(ctx):
+  return [{"name": "broken", "steps": main}]
+`}, wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+		assert.Contains(t, err.Error(), "function")
+	})
+}
+
+func TestStarlarkConverter_Limits(t *testing.T) {
+	convert := func(t *testing.T, limits wccs.StarlarkLimits, src string) error {
+		t.Helper()
+
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkLimits(limits))
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "limits.star", Data: src}, wccs.Environment{})
+		return err
+	}
+
+	const endless = `
+def main(ctx):
+  for i in range(1 << 62):
+    pass
+`
+
+	t.Run("max execution steps", func(t *testing.T) {
+		err := convert(t, wccs.StarlarkLimits{MaxExecutionSteps: 1000}, endless)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+		assert.ErrorContains(t, err, "max execution steps 1000")
+	})
+
+	t.Run("max execution steps of loaded modules", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkLimits(wccs.StarlarkLimits{MaxExecutionSteps: 1000}))
+		assert.NoError(t, err)
+
+		source := newMapSource(map[string]string{"endless.star": "[i for i in range(1 << 62)]"})
+		_, err = c.Convert(t.Context(), wccs.File{Name: "limits.star", Data: `load("endless.star", "x")`, Source: source}, wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+	})
+
+	t.Run("max execution steps across loaded modules", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkLimits(wccs.StarlarkLimits{MaxExecutionSteps: 1000}))
+		assert.NoError(t, err)
+
+		// every module stays below the limit, together they exceed it
+		files := map[string]string{}
+		src := ""
+		for _, name := range []string{"a", "b", "c"} {
+			files[name+".star"] = name + " = [i for i in range(60)]"
+			src += fmt.Sprintf("load(%q, %q)\n", name+".star", name)
+		}
+		src += "def main(ctx):\n  return [{\"name\": str(len([i for i in range(60)]))}]\n"
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "limits.star", Data: src, Source: newMapSource(files)}, wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+		assert.ErrorContains(t, err, "max execution steps 1000")
+
+		// the same modules pass a limit which covers all of them
+		c, err = wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkLimits(wccs.StarlarkLimits{MaxExecutionSteps: 10000}))
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "limits.star", Data: src, Source: newMapSource(files)}, wccs.Environment{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		err := convert(t, wccs.StarlarkLimits{Timeout: 10 * time.Millisecond}, endless)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+		assert.ErrorContains(t, err, "timeout 10ms")
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err = c.Convert(ctx, wccs.File{Name: "limits.star", Data: endless}, wccs.Environment{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.NotErrorIs(t, err, wccs.ErrLimitExceeded)
+	})
+
+	t.Run("max workflows", func(t *testing.T) {
+		err := convert(t, wccs.StarlarkLimits{MaxWorkflows: 2}, `
+def main(ctx):
+  return [{"name": str(i)} for i in range(3)]
+`)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+		assert.ErrorContains(t, err, "max workflows 2")
+	})
+
+	t.Run("max output size", func(t *testing.T) {
+		err := convert(t, wccs.StarlarkLimits{MaxOutputSize: 1024}, `
+def main(ctx):
+  return [{"name": "large", "data": "x" * 2048}]
+`)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+		assert.ErrorContains(t, err, "max output size 1024")
+	})
+
+	t.Run("within the limits", func(t *testing.T) {
+		err := convert(t, wccs.StarlarkLimits{MaxExecutionSteps: 1000, Timeout: time.Second, MaxOutputSize: 1024, MaxWorkflows: 1}, `
+def main(ctx):
+  return [{"name": "small"}]
+`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("releases the cancellation of its threads", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger)
+		assert.NoError(t, err)
+
+		ctx := newAfterFuncContext()
+		source := newMapSource(map[string]string{"lib.star": `image = "golang"`})
+		_, err = c.Convert(ctx, wccs.File{Name: "limits.star", Data: `load("lib.star", "image")
+def main(ctx):
+  return [{"name": "build", "image": image}]
+`, Source: source}, wccs.Environment{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, ctx.registered)
+		assert.Equal(t, 0, ctx.pending)
+	})
+}
+
+// afterFuncContext is never done, it counts the functions registered with context.AfterFunc
+// and those which are not stopped yet.
+type afterFuncContext struct {
+	context.Context
+	done       chan struct{}
+	mu         sync.Mutex
+	registered int
+	pending    int
+}
+
+func newAfterFuncContext() *afterFuncContext {
+	return &afterFuncContext{Context: context.Background(), done: make(chan struct{})}
+}
+
+func (c *afterFuncContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *afterFuncContext) AfterFunc(func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registered++
+	c.pending++
+
+	var once sync.Once
+	return func() bool {
+		stopped := false
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.pending--
+			stopped = true
+		})
+
+		return stopped
+	}
 }
@@ -0,0 +1,259 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// starlarkCoverageBuiltin is the name of the builtin which is called in front of every instrumented statement.
+const starlarkCoverageBuiltin = "__wccs_coverage__"
+
+// Coverage records which lines of Starlark files are executed, a line is every line a statement starts at.
+// It is safe for concurrent use, executions of any number of conversions and tests are accumulated.
+type Coverage struct {
+	mu    sync.Mutex
+	files map[string]*coverageFile
+}
+
+type coverageFile struct {
+	source string
+	// hits by line, every statement line has an entry.
+	hits map[int32]uint64
+}
+
+// NewCoverage returns an empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{files: map[string]*coverageFile{}}
+}
+
+// Lines returns the number of covered lines and the number of all lines of all executed files.
+func (c *Coverage) Lines() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var covered, total int
+	for _, f := range c.files {
+		for _, hits := range f.hits {
+			total++
+			if hits != 0 {
+				covered++
+			}
+		}
+	}
+
+	return covered, total
+}
+
+// program compiles the given file, a call of the coverage builtin is inserted in front of every statement.
+// Instrumented programs are never cached, the positions of the inserted calls are the positions of the statements.
+func (c *Coverage) program(options *syntax.FileOptions, name, data string, predeclared starlark.StringDict) (*starlark.Program, error) {
+	f, err := options.Parse(name, data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	file, ok := c.files[name]
+	if !ok || file.source != data {
+		// a file with the same name but a different content replaces the recorded one
+		file = &coverageFile{source: data, hits: map[int32]uint64{}}
+		c.files[name] = file
+	}
+	f.Stmts = instrumentStatements(f.Stmts, file.hits)
+	c.mu.Unlock()
+
+	return starlark.FileProgram(f, predeclared.Has)
+}
+
+// predeclared returns the given predeclared values with the coverage builtin.
+func (c *Coverage) predeclared(predeclared starlark.StringDict) starlark.StringDict {
+	predeclared = maps.Clone(predeclared)
+	predeclared[starlarkCoverageBuiltin] = starlark.NewBuiltin(starlarkCoverageBuiltin, func(thread *starlark.Thread, _ *starlark.Builtin, _ starlark.Tuple, _ []starlark.Tuple) (starlark.Value, error) {
+		// the caller is positioned at the statement the call was inserted for
+		pos := thread.CallFrame(1).Pos
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if f, ok := c.files[pos.Filename()]; ok {
+			f.hits[pos.Line]++
+		}
+
+		return starlark.None, nil
+	})
+
+	return predeclared
+}
+
+// instrumentStatements inserts a call of the coverage builtin in front of every statement,
+// the lines of the statements are added to the given hits.
+func instrumentStatements(stmts []syntax.Stmt, hits map[int32]uint64) []syntax.Stmt {
+	// the span of an if statement depends on whether its else branch is nil
+	if stmts == nil {
+		return nil
+	}
+
+	instrumented := make([]syntax.Stmt, 0, 2*len(stmts)) //nolint: mnd
+	for _, stmt := range stmts {
+		pos := syntax.Start(stmt)
+		switch stmt := stmt.(type) {
+		case *syntax.DefStmt:
+			stmt.Body = instrumentStatements(stmt.Body, hits)
+		case *syntax.ForStmt:
+			stmt.Body = instrumentStatements(stmt.Body, hits)
+		case *syntax.WhileStmt:
+			stmt.Body = instrumentStatements(stmt.Body, hits)
+		case *syntax.IfStmt:
+			stmt.True = instrumentStatements(stmt.True, hits)
+			stmt.False = instrumentStatements(stmt.False, hits)
+		case *syntax.ExprStmt:
+			// doc strings are no statements worth covering and must stay the first statement of a function
+			if literal, ok := stmt.X.(*syntax.Literal); ok && literal.Token == syntax.STRING {
+				instrumented = append(instrumented, stmt)
+				continue
+			}
+		}
+
+		if _, ok := hits[pos.Line]; !ok {
+			hits[pos.Line] = 0
+		}
+
+		instrumented = append(instrumented, &syntax.ExprStmt{X: &syntax.CallExpr{
+			Fn:     &syntax.Ident{NamePos: pos, Name: starlarkCoverageBuiltin},
+			Lparen: pos,
+			Rparen: pos,
+		}}, stmt)
+	}
+
+	return instrumented
+}
+
+// LCOV writes the coverage in the LCOV tracefile format.
+func (c *Coverage) LCOV(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, name := range slices.Sorted(maps.Keys(c.files)) {
+		f := c.files[name]
+		_, _ = fmt.Fprintf(bw, "TN:\nSF:%s\n", name)
+
+		var covered int
+		for _, line := range slices.Sorted(maps.Keys(f.hits)) {
+			_, _ = fmt.Fprintf(bw, "DA:%d,%d\n", line, f.hits[line])
+			if f.hits[line] != 0 {
+				covered++
+			}
+		}
+
+		_, _ = fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(f.hits), covered)
+	}
+
+	return bw.Flush()
+}
+
+// coverageHTMLTemplate renders the coverage report, every file is listed with its annotated source.
+var coverageHTMLTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Starlark coverage</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table.summary td, table.summary th { padding: 0.2em 1em; text-align: left; }
+table.source { border-collapse: collapse; font-family: monospace; width: 100%; }
+table.source td { padding: 0 0.5em; white-space: pre; }
+td.number { color: #888; text-align: right; user-select: none; }
+tr.covered { background: #dfd; }
+tr.uncovered { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>Starlark coverage</h1>
+<table class="summary">
+<tr><th>File</th><th>Lines</th><th>Coverage</th></tr>
+{{- range $i, $f := .}}
+<tr><td><a href="#file-{{$i}}">{{$f.Name}}</a></td><td>{{$f.Covered}}/{{$f.Total}}</td><td>{{printf "%.1f" $f.Percent}}%</td></tr>
+{{- end}}
+</table>
+{{- range $i, $f := .}}
+<h2 id="file-{{$i}}">{{$f.Name}}</h2>
+<table class="source">
+{{- range $f.Lines}}
+<tr class="{{.Class}}"><td class="number">{{.Number}}</td><td class="number">{{if .Class}}{{.Hits}}{{end}}</td><td>{{.Source}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+type coverageHTMLFile struct {
+	Name           string
+	Covered, Total int
+	Percent        float64
+	Lines          []coverageHTMLLine
+}
+
+type coverageHTMLLine struct {
+	Number int
+	Source string
+	Hits   uint64
+	// Class is covered, uncovered or empty for lines without statements.
+	Class string
+}
+
+// HTML writes the coverage as a single HTML page with the annotated sources of all files.
+func (c *Coverage) HTML(w io.Writer) error {
+	c.mu.Lock()
+	files := make([]coverageHTMLFile, 0, len(c.files))
+	for _, name := range slices.Sorted(maps.Keys(c.files)) {
+		f := c.files[name]
+		file := coverageHTMLFile{Name: name, Total: len(f.hits)}
+		for i, source := range strings.Split(f.source, "\n") {
+			line := coverageHTMLLine{Number: i + 1, Source: strings.TrimRight(source, "\r")}
+			if hits, ok := f.hits[int32(i+1)]; ok {
+				line.Hits = hits
+				line.Class = "uncovered"
+				if hits != 0 {
+					line.Class = "covered"
+					file.Covered++
+				}
+			}
+
+			file.Lines = append(file.Lines, line)
+		}
+
+		if file.Total != 0 {
+			file.Percent = 100 * float64(file.Covered) / float64(file.Total) //nolint: mnd
+		}
+
+		files = append(files, file)
+	}
+	c.mu.Unlock()
+
+	return coverageHTMLTemplate.Execute(w, files)
+}
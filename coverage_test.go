@@ -0,0 +1,116 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkConverter_Coverage(t *testing.T) {
+	coverage := wccs.NewCoverage()
+	c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkCoverage(coverage))
+	assert.NoError(t, err)
+
+	source := newMapSource(map[string]string{"lib.star": `
+def image(event):
+  """Returns the image of the event."""
+  if event == "tag":
+    return "alpine"
+  elif event == "push":
+    return "golang"
+  else:
+    return "busybox"
+`})
+	main := wccs.File{Name: "main.star", Data: `load("lib.star", "image")
+
+def main(ctx):
+  steps = []
+  for i in range(2):
+    steps.append({"name": "step-%d" % i, "image": image(ctx.build.event)})
+  return {"steps": steps}
+`, Source: source}
+
+	for _, event := range []string{"push", "push", "tag"} {
+		_, err := c.Convert(t.Context(), main, wccs.Environment{Pipeline: model.Pipeline{Event: model.WebhookEvent(event)}})
+		assert.NoError(t, err)
+	}
+
+	lcov := new(bytes.Buffer)
+	assert.NoError(t, coverage.LCOV(lcov))
+	assert.Equal(t, `TN:
+SF:lib.star
+DA:2,3
+DA:4,6
+DA:5,2
+DA:6,4
+DA:7,4
+DA:9,0
+LF:6
+LH:5
+end_of_record
+TN:
+SF:main.star
+DA:1,3
+DA:3,3
+DA:4,3
+DA:5,3
+DA:6,6
+DA:7,3
+LF:6
+LH:6
+end_of_record
+`, lcov.String())
+
+	covered, total := coverage.Lines()
+	assert.Equal(t, 11, covered)
+	assert.Equal(t, 12, total)
+
+	html := new(bytes.Buffer)
+	assert.NoError(t, coverage.HTML(html))
+	assert.Contains(t, html.String(), `<tr><td><a href="#file-0">lib.star</a></td><td>5/6</td><td>83.3%</td></tr>`)
+	assert.Contains(t, html.String(), `<tr class="uncovered"><td class="number">9</td><td class="number">0</td><td>    return &#34;busybox&#34;</td></tr>`)
+	assert.Contains(t, html.String(), `<tr class=""><td class="number">3</td><td class="number"></td><td>  &#34;&#34;&#34;Returns the image of the event.&#34;&#34;&#34;</td></tr>`)
+
+	t.Run("keeps error positions", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Name: "broken.star", Data: "def main(ctx):\n  x = 1\n  fail(\"broken\")\n"}, wccs.Environment{})
+		assert.ErrorContains(t, err, "broken.star:3:7: broken")
+	})
+
+	t.Run("ignores test files", func(t *testing.T) {
+		coverage := wccs.NewCoverage()
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkCoverage(coverage))
+		assert.NoError(t, err)
+
+		results, err := c.Test(t.Context(), wccs.File{Name: "lib_test.star", Data: `
+load("lib.star", "image")
+
+def test_image():
+  assert.eq(image("tag"), "alpine")
+`, Source: source})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, results.Failed())
+
+		lcov := new(bytes.Buffer)
+		assert.NoError(t, coverage.LCOV(lcov))
+		assert.NotContains(t, lcov.String(), "lib_test.star")
+		assert.Contains(t, lcov.String(), "SF:lib.star\nDA:2,1\nDA:4,1\nDA:5,1\nDA:6,0\n")
+	})
+}
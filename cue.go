@@ -0,0 +1,229 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// cueExtension is the extension of the files the CueConverter evaluates.
+const cueExtension = ".cue"
+
+// cueSchemaFile is the name the bundled schema is reported with.
+const cueSchemaFile = "types/woodpecker.cue"
+
+// cueSchema is the schema of the woodpecker workflow format, the workflows of every CUE file are unified with it.
+//
+//go:embed types/woodpecker.cue
+var cueSchema string
+
+var (
+	// cueEnvPath is the path the environment is filled in.
+	cueEnvPath = cue.ParsePath("env")
+	// cueWorkflowsPath is the path of the exported workflows.
+	cueWorkflowsPath = cue.ParsePath("workflows")
+)
+
+// CueLimits restricts the resources a conversion may use, a zero value disables the limit.
+type CueLimits struct {
+	// Timeout of the whole conversion.
+	Timeout time.Duration
+	// MaxOutputSize is the maximal size of all generated workflows in bytes.
+	MaxOutputSize int
+	// MaxWorkflows is the maximal number of generated workflows.
+	MaxWorkflows int
+}
+
+// CueConverter evaluates CUE files, the context Starlark scripts receive is unified with their env field
+// and every field of their workflows field becomes a workflow.
+type CueConverter struct {
+	logger *slog.Logger
+	limits CueLimits
+}
+
+// CueOption configures the CueConverter.
+type CueOption func(*CueConverter)
+
+// WithCueLimits restricts the resources a conversion may use, conversions are unlimited by default.
+func WithCueLimits(limits CueLimits) CueOption {
+	return func(c *CueConverter) {
+		c.limits = limits
+	}
+}
+
+// NewCueConverter returns a new CueConverter.
+func NewCueConverter(logger *slog.Logger, options ...CueOption) CueConverter {
+	c := CueConverter{logger: logger}
+	for _, option := range options {
+		option(&c)
+	}
+
+	return c
+}
+
+func (c CueConverter) Compatible(f File) bool {
+	return path.Ext(f.Name) == cueExtension
+}
+
+// Convert unifies the file with the environment and the bundled schema, the workflows field is exported
+// into a file per field which is named after the field.
+func (c CueConverter) Convert(ctx context.Context, f File, env Environment) ([]File, error) {
+	if f.Data == "" {
+		return nil, ErrNoContent
+	}
+
+	if c.limits.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, c.limits.Timeout, fmt.Errorf("%w: timeout %s", ErrLimitExceeded, c.limits.Timeout))
+		defer cancel()
+	}
+
+	type evaluation struct {
+		files []File
+		err   error
+	}
+	done := make(chan evaluation, 1)
+	go func() {
+		files, err := c.evaluate(f, env)
+		done <- evaluation{files: files, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// CUE cannot be interrupted, the evaluation ends in the background and its result is dropped
+		return nil, context.Cause(ctx)
+	case result := <-done:
+		if result.err != nil {
+			return nil, result.err
+		}
+
+		return result.files, checkOutput(result.files, c.limits.MaxWorkflows, c.limits.MaxOutputSize)
+	}
+}
+
+// evaluate builds the value of the file and exports its workflows, errors are reported at their position.
+func (c CueConverter) evaluate(f File, env Environment) ([]File, error) {
+	cctx := cuecontext.New()
+	sources := map[string]string{f.Name: f.Data, cueSchemaFile: cueSchema}
+
+	schema := cctx.CompileString(cueSchema, cue.Filename(cueSchemaFile))
+	if err := schema.Err(); err != nil {
+		return nil, err
+	}
+
+	v := cctx.CompileString(f.Data, cue.Filename(f.Name))
+	if err := v.Err(); err != nil {
+		return nil, cueDiagnostic(err, DiagnosticKindSyntax, f.Name, sources)
+	}
+
+	if !v.LookupPath(cueWorkflowsPath).Exists() {
+		return nil, fmt.Errorf("%w: workflows", ErrMissingParam)
+	}
+
+	v = v.FillPath(cueEnvPath, contextData(env)).Unify(schema)
+	if err := v.Validate(); err != nil {
+		return nil, cueDiagnostic(err, DiagnosticKindRuntime, f.Name, sources)
+	}
+
+	// only the workflows are exported, all other fields may be incomplete
+	workflows := v.LookupPath(cueWorkflowsPath)
+	if err := workflows.Validate(cue.Concrete(true)); err != nil {
+		return nil, cueDiagnostic(err, DiagnosticKindRuntime, f.Name, sources)
+	}
+
+	iter, err := workflows.Fields()
+	if err != nil {
+		return nil, cueDiagnostic(err, DiagnosticKindRuntime, f.Name, sources)
+	}
+
+	var files []File
+	for iter.Next() {
+		data, err := iter.Value().MarshalJSON()
+		if err != nil {
+			return nil, cueDiagnostic(err, DiagnosticKindRuntime, f.Name, sources)
+		}
+
+		var document yaml.Node
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+
+		workflow, err := YAMLToStarlark(&document)
+		if err != nil {
+			return nil, err
+		}
+
+		name := iter.Selector().Unquoted()
+		file, err := starlarkFile(strings.TrimSuffix(name, path.Ext(name))+".yaml", workflow)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// cueDiagnostic turns the most specific error of CUE into a Diagnostic, it points to the entry file if one of
+// the positions of the error is in it. The sources are used to add the failing line.
+func cueDiagnostic(err error, kind DiagnosticKind, entry string, sources map[string]string) error {
+	errs := cueerrors.Errors(err)
+	if len(errs) == 0 {
+		return err
+	}
+
+	// disjunctions report a summary and an error per alternative, the deepest error is the one which failed last
+	specific := errs[0]
+	for _, e := range errs[1:] {
+		if len(cueerrors.Positions(e)) != 0 && (len(cueerrors.Positions(specific)) == 0 || len(e.Path()) > len(specific.Path())) {
+			specific = e
+		}
+	}
+
+	format, args := specific.Msg()
+	d := &Diagnostic{Kind: kind, Message: fmt.Sprintf(format, args...), err: err}
+	if p := specific.Path(); len(p) != 0 {
+		d.Message = strings.Join(p, ".") + ": " + d.Message
+	}
+
+	positions := cueerrors.Positions(specific)
+	for _, pos := range positions {
+		if pos.Filename() == entry {
+			d.File, d.Line, d.Column = pos.Filename(), int32(pos.Line()), int32(pos.Column())
+			break
+		}
+	}
+	if d.File == "" && len(positions) != 0 {
+		d.File, d.Line, d.Column = positions[0].Filename(), int32(positions[0].Line()), int32(positions[0].Column())
+	}
+
+	if lines := strings.Split(sources[d.File], "\n"); d.Line > 0 && int(d.Line) <= len(lines) {
+		d.Source = strings.TrimRight(lines[d.Line-1], "\r")
+	}
+
+	return d
+}
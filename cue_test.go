@@ -0,0 +1,119 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestCueConverter_Compatible(t *testing.T) {
+	c := wccs.NewCueConverter(noopLogger)
+	for name, compatible := range map[string]bool{
+		".woodpecker/build.cue":  true,
+		".woodpecker/build.yaml": false,
+		".woodpecker/cue.mod":    false,
+	} {
+		assert.Equal(t, compatible, c.Compatible(wccs.File{Name: name}), name)
+	}
+}
+
+func TestCueConverter_Convert(t *testing.T) {
+	env := wccs.Environment{
+		Repo:     model.Repo{FullName: "opencloud-eu/web", Owner: "opencloud-eu", Name: "web"},
+		Pipeline: model.Pipeline{Event: model.EventPush, Branch: "main"},
+		Netrc:    model.Netrc{Login: "secret", Password: "secret"},
+	}
+	c := wccs.NewCueConverter(noopLogger)
+
+	t.Run("exports a file per workflow", func(t *testing.T) {
+		files, err := c.Convert(t.Context(), wccs.File{Name: ".woodpecker/build.cue", Data: `
+import "strings"
+
+env: _
+
+#image: "golang:1.26"
+
+workflows: build: {
+	when: event: env.build.event
+	steps: [{name: "test", image: #image, commands: ["make " + strings.ToUpper(env.repo.name)]}]
+}
+
+workflows: "lint.yaml": steps: lint: image: #image
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{
+			{Name: "build.yaml", Data: "when:\n  event: push\nsteps:\n  - name: test\n    image: golang:1.26\n    commands:\n      - make WEB\n"},
+			{Name: "lint.yaml", Data: "steps:\n  lint:\n    image: golang:1.26\n"},
+		}, files)
+	})
+
+	t.Run("unifies the env with the environment", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Name: "ci.cue", Data: "env: build: event: \"tag\"\nworkflows: release: steps: []\n"}, env)
+		var diagnostic *wccs.Diagnostic
+		assert.True(t, errors.As(err, &diagnostic))
+		assert.Equal(t, "ci.cue", diagnostic.File)
+		assert.Equal(t, int32(1), diagnostic.Line)
+		assert.Contains(t, diagnostic.Message, `env.build.event: conflicting values "push" and "tag"`)
+
+		files, err := c.Convert(t.Context(), wccs.File{Name: "ci.cue", Data: "env: netrc?: _\nworkflows: build: {steps: [], labels: netrc: \"\\(env.netrc != _|_)\"}\n"}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, "labels:\n  netrc: \"false\"\nsteps: []\n", files[0].Data)
+	})
+
+	t.Run("enforces the schema", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Name: "ci.cue", Data: "workflows: build: steps: [{\n\tname: \"test\"\n\timage: 1\n}]\n"}, env)
+		var diagnostic *wccs.Diagnostic
+		assert.True(t, errors.As(err, &diagnostic))
+		assert.Equal(t, wccs.DiagnosticKindRuntime, diagnostic.Kind)
+		assert.Equal(t, "ci.cue:3:9", diagnostic.Error()[:len("ci.cue:3:9")])
+		assert.Contains(t, diagnostic.Message, "workflows.build.steps.0.image: conflicting values 1 and string")
+		assert.Equal(t, "\timage: 1", diagnostic.Source)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "ci.cue", Data: "workflows: build: steps: [{name: \"test\"}]\n"}, env)
+		assert.ErrorContains(t, err, "workflows.build.steps.0.image: incomplete value string")
+	})
+
+	t.Run("reports syntax errors", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Name: "ci.cue", Data: "workflows: build: steps: [{\n"}, env)
+		var diagnostic *wccs.Diagnostic
+		assert.True(t, errors.As(err, &diagnostic))
+		assert.Equal(t, wccs.DiagnosticKindSyntax, diagnostic.Kind)
+		assert.Equal(t, "ci.cue", diagnostic.File)
+	})
+
+	t.Run("fails without workflows", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Name: "ci.cue", Data: "steps: []\n"}, env)
+		assert.ErrorIs(t, err, wccs.ErrMissingParam)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "ci.cue"}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoContent)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		for data, limits := range map[string]wccs.CueLimits{
+			"workflows: {for i in [1, 2, 3] {\"w\\(i)\": steps: []}}":                                  {MaxWorkflows: 2},
+			"import \"strings\"\nworkflows: build: {steps: [], labels: x: strings.Repeat(\"x\", 100)}": {MaxOutputSize: 50},
+		} {
+			_, err := wccs.NewCueConverter(noopLogger, wccs.WithCueLimits(limits)).Convert(t.Context(), wccs.File{Name: "ci.cue", Data: data}, env)
+			assert.ErrorIs(t, err, wccs.ErrLimitExceeded, data)
+		}
+	})
+}
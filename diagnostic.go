@@ -0,0 +1,174 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// DiagnosticKind classifies a Diagnostic.
+type DiagnosticKind string
+
+const (
+	// DiagnosticKindSyntax is used for files which cannot be parsed or resolved.
+	DiagnosticKindSyntax DiagnosticKind = "syntax"
+	// DiagnosticKindFail is used for scripts which called fail, the error is intended by the author.
+	DiagnosticKindFail DiagnosticKind = "fail"
+	// DiagnosticKindRuntime is used for all other errors of a script.
+	DiagnosticKindRuntime DiagnosticKind = "runtime"
+)
+
+// starlarkFailPrefix is the prefix of the errors the fail builtin returns.
+const starlarkFailPrefix = "fail: "
+
+// Diagnostic describes where and why a Starlark script failed.
+type Diagnostic struct {
+	Kind    DiagnosticKind `json:"kind"`
+	File    string         `json:"file"`
+	Line    int32          `json:"line"`
+	Column  int32          `json:"column"`
+	Message string         `json:"message"`
+	// Source is the line of the file the diagnostic points to, empty if the file is unknown.
+	Source string `json:"source,omitempty"`
+	// Backtrace lists the calls which led to the error, the innermost call comes last.
+	Backtrace []DiagnosticFrame `json:"backtrace,omitempty"`
+
+	err error
+}
+
+// DiagnosticFrame is a call of a Diagnostic backtrace.
+type DiagnosticFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int32  `json:"line"`
+	Column   int32  `json:"column"`
+}
+
+// Error implements the error interface.
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// Unwrap returns the original Starlark error.
+func (d *Diagnostic) Unwrap() error {
+	return d.err
+}
+
+// Report returns a human readable report with the source line, a caret pointing to the column and the backtrace.
+func (d *Diagnostic) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d:%d: %s error: %s\n", d.File, d.Line, d.Column, d.Kind, d.Message)
+
+	if d.Source != "" {
+		fmt.Fprintf(&b, "  %s\n", d.Source)
+		if d.Column > 0 {
+			// keep tabs to align the caret with the source line
+			indent := strings.Map(func(r rune) rune {
+				if r == '\t' {
+					return r
+				}
+
+				return ' '
+			}, string([]rune(d.Source)[:min(int(d.Column)-1, len([]rune(d.Source)))]))
+			fmt.Fprintf(&b, "  %s^\n", indent)
+		}
+	}
+
+	if len(d.Backtrace) != 0 {
+		b.WriteString("backtrace:\n")
+		for _, frame := range d.Backtrace {
+			// builtins have no position
+			if frame.Line == 0 {
+				fmt.Fprintf(&b, "  %s: in %s\n", frame.File, frame.Function)
+				continue
+			}
+
+			fmt.Fprintf(&b, "  %s:%d:%d: in %s\n", frame.File, frame.Line, frame.Column, frame.Function)
+		}
+	}
+
+	return b.String()
+}
+
+// newDiagnostic turns the innermost Starlark error of the given error into a Diagnostic,
+// the sources are used to add the failing line. Other errors are returned unchanged.
+func newDiagnostic(err error, sources map[string]string) error {
+	var d *Diagnostic
+	walkErrors(err, func(err error) {
+		switch e := err.(type) { //nolint:errorlint
+		case *starlark.EvalError:
+			d = &Diagnostic{Kind: DiagnosticKindRuntime, Message: e.Msg}
+			if cause := errors.Unwrap(e); cause != nil && strings.HasPrefix(cause.Error(), starlarkFailPrefix) {
+				d.Kind = DiagnosticKindFail
+				d.Message = strings.TrimPrefix(cause.Error(), starlarkFailPrefix)
+			}
+
+			for _, frame := range e.CallStack {
+				d.Backtrace = append(d.Backtrace, DiagnosticFrame{
+					Function: frame.Name,
+					File:     frame.Pos.Filename(),
+					Line:     frame.Pos.Line,
+					Column:   frame.Pos.Col,
+				})
+			}
+
+			// the innermost frame of a builtin has no position, the error is reported at its caller
+			for i := len(e.CallStack) - 1; i >= 0; i-- {
+				if pos := e.CallStack[i].Pos; pos.Line != 0 {
+					d.File, d.Line, d.Column = pos.Filename(), pos.Line, pos.Col
+					break
+				}
+			}
+		case syntax.Error:
+			d = &Diagnostic{Kind: DiagnosticKindSyntax, Message: e.Msg, File: e.Pos.Filename(), Line: e.Pos.Line, Column: e.Pos.Col}
+		case resolve.ErrorList:
+			d = &Diagnostic{Kind: DiagnosticKindSyntax, Message: e[0].Msg, File: e[0].Pos.Filename(), Line: e[0].Pos.Line, Column: e[0].Pos.Col}
+		}
+	})
+	if d == nil {
+		return err
+	}
+
+	d.err = err
+	if lines := strings.Split(sources[d.File], "\n"); d.Line > 0 && int(d.Line) <= len(lines) {
+		d.Source = strings.TrimRight(lines[d.Line-1], "\r")
+	}
+
+	return d
+}
+
+// walkErrors calls f for the given error and all errors it wraps, outer errors first.
+func walkErrors(err error, f func(error)) {
+	if err == nil {
+		return
+	}
+
+	f(err)
+
+	switch e := err.(type) { //nolint:errorlint
+	case interface{ Unwrap() error }:
+		walkErrors(e.Unwrap(), f)
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			walkErrors(err, f)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkConverter_Diagnostic(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	diagnose := func(t *testing.T, f wccs.File) *wccs.Diagnostic {
+		t.Helper()
+
+		_, err := c.Convert(t.Context(), f, wccs.Environment{})
+
+		var diagnostic *wccs.Diagnostic
+		assert.True(t, errors.As(err, &diagnostic), err)
+
+		return diagnostic
+	}
+
+	t.Run("syntax errors", func(t *testing.T) {
+		d := diagnose(t, wccs.File{Name: "main.star", Data: "def main(ctx):\n  return [\n"})
+		assert.Equal(t, wccs.DiagnosticKindSyntax, d.Kind)
+		assert.Equal(t, "main.star", d.File)
+		assert.Equal(t, int32(3), d.Line)
+
+		d = diagnose(t, wccs.File{Name: "main.star", Data: "def main(ctx):\n  return unknown\n"})
+		assert.Equal(t, wccs.DiagnosticKindSyntax, d.Kind)
+		assert.Equal(t, "undefined: unknown", d.Message)
+		assert.Equal(t, "  return unknown", d.Source)
+	})
+
+	t.Run("runtime errors", func(t *testing.T) {
+		d := diagnose(t, wccs.File{Name: "main.star", Data: "def steps():\n  return 1 + \"a\"\n\ndef main(ctx):\n  return steps()\n"})
+		assert.Equal(t, wccs.DiagnosticKindRuntime, d.Kind)
+		assert.Equal(t, int32(2), d.Line)
+		assert.Equal(t, int32(12), d.Column)
+		assert.Equal(t, []wccs.DiagnosticFrame{
+			{Function: "main", File: "main.star", Line: 5, Column: 15},
+			{Function: "steps", File: "main.star", Line: 2, Column: 12},
+		}, d.Backtrace)
+		assert.Equal(t, `main.star:2:12: runtime error: unknown binary op: int + string
+    return 1 + "a"
+             ^
+backtrace:
+  main.star:5:15: in main
+  main.star:2:12: in steps
+`, d.Report())
+	})
+
+	t.Run("fail is reported as user error", func(t *testing.T) {
+		d := diagnose(t, wccs.File{Name: "main.star", Data: "def main(ctx):\n  fail(\"missing\", \"image\")\n"})
+		assert.Equal(t, wccs.DiagnosticKindFail, d.Kind)
+		assert.Equal(t, "missing image", d.Message)
+		assert.Equal(t, int32(2), d.Line)
+		assert.Equal(t, int32(7), d.Column)
+	})
+
+	t.Run("errors of loaded modules", func(t *testing.T) {
+		source := newMapSource(map[string]string{"lib.star": "x = 1\ny = x / 0\n"})
+		d := diagnose(t, wccs.File{Name: "main.star", Data: `load("lib.star", "y")`, Source: source})
+		assert.Equal(t, "lib.star", d.File)
+		assert.Equal(t, int32(2), d.Line)
+		assert.Equal(t, "y = x / 0", d.Source)
+	})
+}
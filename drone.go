@@ -0,0 +1,596 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"reflect"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+	"gopkg.in/yaml.v3"
+)
+
+// droneEvents maps woodpecker events to the events drone knows.
+var droneEvents = map[model.WebhookEvent]string{
+	model.EventPush:         "push",
+	model.EventPull:         "pull_request",
+	model.EventPullClosed:   "pull_request",
+	model.EventPullMetadata: "pull_request",
+	model.EventTag:          "tag",
+	model.EventRelease:      "tag",
+	model.EventDeploy:       "promote",
+	model.EventCron:         "cron",
+	model.EventManual:       "custom",
+}
+
+// woodpeckerEvents maps drone events in trigger and when conditions to woodpecker events.
+var woodpeckerEvents = map[string]string{
+	"promote":  string(model.EventDeploy),
+	"rollback": string(model.EventDeploy),
+	"custom":   string(model.EventManual),
+}
+
+// droneContext builds the ctx value drone passes to main for the given environment.
+func droneContext(env Environment) starlark.Value {
+	// IMPORTANT: never add any env.Netrc values to the context, this contains sensitive information!!!
+	source, target := env.Pipeline.Branch, env.Pipeline.Branch
+	if s, t, ok := strings.Cut(env.Pipeline.Refspec, ":"); ok {
+		source, target = s, t
+	}
+
+	action := ""
+	if len(env.Pipeline.EventReason) != 0 {
+		action = env.Pipeline.EventReason[0]
+	}
+	if env.Pipeline.Event == model.EventPullClosed {
+		action = "closed"
+	}
+
+	params := contextValue(ContextAttribute{}, reflect.ValueOf(env.Pipeline.AdditionalVariables))
+
+	return starlarkstruct.FromStringDict(starlark.String("context"), starlark.StringDict{
+		"repo": starlarkstruct.FromStringDict(starlark.String("repo"), starlark.StringDict{
+			"uid":          starlark.String(env.Repo.ForgeRemoteID),
+			"name":         starlark.String(env.Repo.Name),
+			"namespace":    starlark.String(env.Repo.Owner),
+			"slug":         starlark.String(env.Repo.FullName),
+			"git_http_url": starlark.String(env.Repo.Clone),
+			"git_ssh_url":  starlark.String(env.Repo.CloneSSH),
+			"link":         starlark.String(env.Repo.ForgeURL),
+			"branch":       starlark.String(env.Repo.Branch),
+			"config":       starlark.String(env.Repo.Config),
+			"private":      starlark.Bool(env.Repo.IsSCMPrivate),
+			"visibility":   starlark.String(env.Repo.Visibility),
+			"active":       starlark.Bool(env.Repo.IsActive),
+			"trusted":      starlark.Bool(env.Repo.Trusted.Security),
+			"protected":    starlark.Bool(env.Repo.RequireApproval != model.RequireApprovalNone),
+			"ignore_forks": starlark.False,
+			"ignore_pulls": starlark.Bool(!env.Repo.AllowPull),
+		}),
+		"build": starlarkstruct.FromStringDict(starlark.String("build"), starlark.StringDict{
+			"event":         starlark.String(droneEvents[env.Pipeline.Event]),
+			"action":        starlark.String(action),
+			"cron":          starlark.String(env.Pipeline.Cron),
+			"environment":   starlark.String(env.Pipeline.DeployTo),
+			"link":          starlark.String(env.Pipeline.ForgeURL),
+			"branch":        starlark.String(env.Pipeline.Branch),
+			"source":        starlark.String(source),
+			"target":        starlark.String(target),
+			"source_repo":   starlark.String(""),
+			"before":        starlark.String(""),
+			"after":         starlark.String(env.Pipeline.Commit),
+			"ref":           starlark.String(env.Pipeline.Ref),
+			"commit":        starlark.String(env.Pipeline.Commit),
+			"title":         starlark.String(env.Pipeline.Title),
+			"message":       starlark.String(env.Pipeline.Message),
+			"author_login":  starlark.String(env.Pipeline.Author),
+			"author_name":   starlark.String(env.Pipeline.Author),
+			"author_email":  starlark.String(env.Pipeline.Email),
+			"author_avatar": starlark.String(env.Pipeline.Avatar),
+			"sender":        starlark.String(env.Pipeline.Sender),
+			"debug":         starlark.False,
+			"params":        params,
+		}),
+	})
+}
+
+const (
+	// droneDefaultOS is the operating system drone uses if a pipeline does not define one.
+	droneDefaultOS = "linux"
+	// droneDefaultArch is the architecture drone uses if a pipeline does not define one.
+	droneDefaultArch = "amd64"
+)
+
+// droneYAMLFiles are the names drone uses for YAML configuration files.
+var droneYAMLFiles = []string{".drone.yml", ".drone.yaml"}
+
+// droneDefaultPipelineName is the name drone uses for pipelines without a name.
+const droneDefaultPipelineName = "default"
+
+// DroneYAMLConverter translates the pipelines of drone YAML files into woodpecker workflows.
+// Documents and settings without a woodpecker counterpart are logged as warning and left out,
+// the warnings are added to every generated file.
+type DroneYAMLConverter struct {
+	logger *slog.Logger
+}
+
+// NewDroneYAMLConverter returns a new DroneYAMLConverter.
+func NewDroneYAMLConverter(logger *slog.Logger) DroneYAMLConverter {
+	return DroneYAMLConverter{logger: logger}
+}
+
+func (c DroneYAMLConverter) Compatible(f File) bool {
+	name := path.Base(f.Name)
+	for _, droneFile := range droneYAMLFiles {
+		if strings.HasSuffix(name, droneFile) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Convert translates every kind: pipeline document into a workflow named after the pipeline.
+func (c DroneYAMLConverter) Convert(_ context.Context, f File, env Environment) ([]File, error) {
+	if f.Data == "" {
+		return nil, ErrNoContent
+	}
+
+	documents := starlark.NewList(nil)
+	dec := yaml.NewDecoder(strings.NewReader(f.Data))
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+
+		// drone files often start with a document separator
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+
+		document, err := YAMLToStarlark(&node)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+
+		if d, ok := document.(*starlark.Dict); ok && dictGet(d, "name") == nil && dictString(d, "kind") == "pipeline" {
+			_ = d.SetKey(starlark.String("name"), starlark.String(droneDefaultPipelineName))
+		}
+
+		_ = documents.Append(document)
+	}
+
+	var warnings []string
+	translator := droneTranslator{warn: func(msg string) {
+		c.logger.Warn(msg, "file", f.Name, "repo", env.Repo.FullName)
+		warnings = append(warnings, msg)
+	}}
+	workflows, err := translator.workflows(documents)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+
+	// woodpecker would run nothing, the warnings tell why
+	if workflows.Len() == 0 {
+		if len(warnings) == 0 {
+			return nil, fmt.Errorf("%w: %s has no pipeline", ErrNoContent, f.Name)
+		}
+
+		return nil, fmt.Errorf("%w: %s has no pipeline left: %s", ErrNoContent, f.Name, strings.Join(warnings, ", "))
+	}
+
+	files, err := starlarkFiles(workflows, f.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range files {
+		files[i].Warnings = warnings
+	}
+
+	return files, nil
+}
+
+// droneTranslator translates drone pipelines into woodpecker workflows.
+// Documents and settings without a woodpecker counterpart fail the translation, unless warn is set,
+// then they are reported to warn and left out, e.g. to serve legacy repositories while they are migrated.
+type droneTranslator struct {
+	warn func(msg string)
+}
+
+// unsupported returns the given error, or reports it and returns nil if the translation is lenient.
+func (t droneTranslator) unsupported(err error) error {
+	if t.warn == nil {
+		return err
+	}
+
+	t.warn(err.Error())
+
+	return nil
+}
+
+// workflows translates the documents returned by a drone main function into woodpecker workflows.
+// Drone returns either a single document or a list of documents, only pipelines are translated.
+func (t droneTranslator) workflows(v starlark.Value) (*starlark.List, error) {
+	documents, ok := v.(*starlark.List)
+	if !ok {
+		documents = starlark.NewList([]starlark.Value{v})
+	}
+
+	workflows := starlark.NewList(nil)
+	for i := range documents.Len() {
+		document, ok := documents.Index(i).(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("%w: drone document must be a dict, got %s", ErrUnsupportedType, documents.Index(i).Type())
+		}
+
+		// secrets, signatures and other documents have no woodpecker counterpart
+		if kind := dictString(document, "kind"); kind != "" && kind != "pipeline" {
+			if err := t.unsupported(fmt.Errorf("%w: document %d: kind %s is not supported", ErrUnsupportedType, i+1, kind)); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		workflow, err := t.workflow(document)
+		if err != nil {
+			return nil, err
+		}
+		if workflow == nil {
+			continue
+		}
+
+		if err := workflows.Append(workflow); err != nil {
+			return nil, err
+		}
+	}
+
+	return workflows, nil
+}
+
+// workflow translates a single drone pipeline into a woodpecker workflow,
+// it returns nil if the pipeline is left out.
+func (t droneTranslator) workflow(pipeline *starlark.Dict) (*starlark.Dict, error) {
+	name := dictString(pipeline, "name")
+	if pipelineType := dictString(pipeline, "type"); pipelineType != "" && pipelineType != "docker" && pipelineType != "kubernetes" {
+		return nil, t.unsupported(fmt.Errorf("%w: pipeline %s has type %s", ErrUnsupportedType, name, pipelineType))
+	}
+
+	volumes := map[string]string{}
+	if v, ok := dictGet(pipeline, "volumes").(*starlark.List); ok {
+		for i := range v.Len() {
+			volume, ok := v.Index(i).(*starlark.Dict)
+			if !ok {
+				continue
+			}
+
+			// temporary volumes become named volumes, host volumes are mounted from the host path
+			volumeName := dictString(volume, "name")
+			volumes[volumeName] = volumeName
+			if host, ok := dictGet(volume, "host").(*starlark.Dict); ok {
+				volumes[volumeName] = dictString(host, "path")
+			}
+		}
+	}
+
+	environment, _ := dictGet(pipeline, "environment").(*starlark.Dict)
+
+	workflow := starlark.NewDict(pipeline.Len())
+	for _, item := range pipeline.Items() {
+		key, _ := starlark.AsString(item[0])
+		var err error
+		switch key {
+		// handled above or by the steps
+		case "kind", "type", "volumes", "environment":
+		case "name", "depends_on":
+			err = workflow.SetKey(item[0], item[1])
+		case "platform":
+			platform, _ := item[1].(*starlark.Dict)
+			// both default independently, e.g. a pipeline which only sets the arch runs on linux
+			os, arch := dictString(platform, "os"), dictString(platform, "arch")
+			if os == "" {
+				os = droneDefaultOS
+			}
+			if arch == "" {
+				arch = droneDefaultArch
+			}
+
+			err = droneLabels(workflow).SetKey(starlark.String("platform"), starlark.String(os+"/"+arch))
+		case "node":
+			// the runners of both select pipelines by their labels
+			node, ok := item[1].(*starlark.Dict)
+			if !ok {
+				err = fmt.Errorf("%w: node must be a dict, got %s", ErrUnsupportedType, item[1].Type())
+				break
+			}
+
+			labels := droneLabels(workflow)
+			for _, label := range node.Items() {
+				if err = labels.SetKey(label[0], label[1]); err != nil {
+					break
+				}
+			}
+		case "clone":
+			err = droneClone(workflow, item[1])
+		case "workspace":
+			workspace, _ := item[1].(*starlark.Dict)
+			if p := dictString(workspace, "path"); p != "" {
+				w := starlark.NewDict(2) //nolint:mnd
+				_ = w.SetKey(starlark.String("base"), starlark.String(path.Dir(p)))
+				_ = w.SetKey(starlark.String("path"), starlark.String(path.Base(p)))
+				err = workflow.SetKey(starlark.String("workspace"), w)
+			}
+		case "trigger":
+			var when *starlark.Dict
+			// left out conditions may leave none, the workflow then runs unconditionally
+			when, err = t.conditions(item[1])
+			if err == nil && when.Len() != 0 {
+				err = workflow.SetKey(starlark.String("when"), when)
+			}
+		case "steps", "services":
+			var steps starlark.Value
+			steps, err = t.steps(item[1], environment, volumes)
+			if err == nil {
+				err = workflow.SetKey(item[0], steps)
+			}
+		// drone only settings without a woodpecker counterpart, e.g. concurrency or image_pull_secrets
+		default:
+			if err := t.unsupported(fmt.Errorf("%w: pipeline %s: %s is not supported", ErrUnsupportedType, name, key)); err != nil {
+				return nil, err
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: pipeline %s", err, name)
+		}
+	}
+
+	return workflow, nil
+}
+
+// droneLabels returns the labels of the workflow, they are added if the workflow has none yet.
+func droneLabels(workflow *starlark.Dict) *starlark.Dict {
+	if labels, ok := dictGet(workflow, "labels").(*starlark.Dict); ok {
+		return labels
+	}
+
+	labels := starlark.NewDict(1)
+	_ = workflow.SetKey(starlark.String("labels"), labels)
+
+	return labels
+}
+
+// droneClone translates the drone clone settings.
+func droneClone(workflow *starlark.Dict, v starlark.Value) error {
+	clone, _ := v.(*starlark.Dict)
+	if disable, _ := dictGet(clone, "disable").(starlark.Bool); disable {
+		return workflow.SetKey(starlark.String("skip_clone"), starlark.True)
+	}
+
+	depth := dictGet(clone, "depth")
+	if depth == nil {
+		return nil
+	}
+
+	settings := starlark.NewDict(1)
+	_ = settings.SetKey(starlark.String("depth"), depth)
+
+	step := starlark.NewDict(3) //nolint:mnd
+	_ = step.SetKey(starlark.String("name"), starlark.String("clone"))
+	_ = step.SetKey(starlark.String("image"), starlark.String("woodpeckerci/plugin-git"))
+	_ = step.SetKey(starlark.String("settings"), settings)
+
+	return workflow.SetKey(starlark.String("clone"), starlark.NewList([]starlark.Value{step}))
+}
+
+// steps translates drone steps or services, the pipeline environment is merged into every step.
+func (t droneTranslator) steps(v starlark.Value, environment *starlark.Dict, volumes map[string]string) (starlark.Value, error) {
+	list, ok := v.(*starlark.List)
+	if !ok {
+		return nil, fmt.Errorf("%w: steps must be a list, got %s", ErrUnsupportedType, v.Type())
+	}
+
+	steps := make([]starlark.Value, 0, list.Len())
+	for i := range list.Len() {
+		step, ok := list.Index(i).(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("%w: step must be a dict, got %s", ErrUnsupportedType, list.Index(i).Type())
+		}
+
+		translated, err := t.step(step, environment, volumes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: step %s", err, dictString(step, "name"))
+		}
+
+		steps = append(steps, translated)
+	}
+
+	return starlark.NewList(steps), nil
+}
+
+// step translates a single drone step or service.
+func (t droneTranslator) step(step, environment *starlark.Dict, volumes map[string]string) (*starlark.Dict, error) {
+	translated := starlark.NewDict(step.Len())
+	if environment != nil && dictGet(step, "environment") == nil {
+		_ = translated.SetKey(starlark.String("environment"), environment)
+	}
+
+	for _, item := range step.Items() {
+		key, _ := starlark.AsString(item[0])
+		value := item[1]
+		switch key {
+		case "command":
+			if err := t.unsupported(fmt.Errorf("%w: command of step %s, use entrypoint or commands", ErrUnsupportedType, dictString(step, "name"))); err != nil {
+				return nil, err
+			}
+
+			continue
+		case "environment":
+			merged := starlark.NewDict(0)
+			for _, env := range []starlark.Value{environment, value} {
+				d, ok := env.(*starlark.Dict)
+				if !ok {
+					continue
+				}
+
+				for _, e := range d.Items() {
+					_ = merged.SetKey(e[0], e[1])
+				}
+			}
+			value = merged
+		case "pull":
+			// woodpecker only knows to always pull, if-not-exists is its default
+			if dictString(step, "pull") != "always" {
+				continue
+			}
+			value = starlark.True
+		case "when":
+			when, err := t.conditions(value)
+			if err != nil {
+				return nil, err
+			}
+			if when.Len() == 0 {
+				continue
+			}
+			value = when
+		case "volumes":
+			list, ok := value.(*starlark.List)
+			if !ok {
+				return nil, fmt.Errorf("%w: volumes must be a list, got %s", ErrUnsupportedType, value.Type())
+			}
+
+			mounts := make([]starlark.Value, 0, list.Len())
+			for i := range list.Len() {
+				mount, _ := list.Index(i).(*starlark.Dict)
+				source, ok := volumes[dictString(mount, "name")]
+				if !ok {
+					return nil, fmt.Errorf("%w: volume %s", ErrMissingParam, dictString(mount, "name"))
+				}
+
+				mounts = append(mounts, starlark.String(source+":"+dictString(mount, "path")))
+			}
+			value = starlark.NewList(mounts)
+		case "name", "image", "commands", "entrypoint", "settings", "privileged", "detach", "failure", "depends_on":
+		// drone only settings without a woodpecker counterpart, e.g. resources, network_mode, dns or user
+		default:
+			if err := t.unsupported(fmt.Errorf("%w: step %s: %s is not supported", ErrUnsupportedType, dictString(step, "name"), key)); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if err := translated.SetKey(item[0], value); err != nil {
+			return nil, err
+		}
+	}
+
+	return translated, nil
+}
+
+// conditions translates drone trigger and when conditions into woodpecker when conditions.
+func (t droneTranslator) conditions(v starlark.Value) (*starlark.Dict, error) {
+	conditions, ok := v.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("%w: conditions must be a dict, got %s", ErrUnsupportedType, v.Type())
+	}
+
+	when := starlark.NewDict(conditions.Len())
+	for _, item := range conditions.Items() {
+		key, _ := starlark.AsString(item[0])
+		var err error
+		switch key {
+		case "event":
+			err = when.SetKey(item[0], mapStrings(item[1], func(event string) string {
+				if e, ok := woodpeckerEvents[event]; ok {
+					return e
+				}
+
+				return event
+			}))
+		case "target":
+			err = when.SetKey(starlark.String("environment"), item[1])
+		case "paths":
+			err = when.SetKey(starlark.String("path"), item[1])
+		case "branch", "ref", "repo", "status", "cron", "instance":
+			err = when.SetKey(item[0], item[1])
+		default:
+			err = t.unsupported(fmt.Errorf("%w: condition %s", ErrUnsupportedType, key))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return when, nil
+}
+
+// mapStrings applies f to the given string, to the strings of a list
+// and to the include and exclude lists of a dict.
+func mapStrings(v starlark.Value, f func(string) string) starlark.Value {
+	switch v := v.(type) {
+	case starlark.String:
+		return starlark.String(f(string(v)))
+	case *starlark.List:
+		list := make([]starlark.Value, 0, v.Len())
+		for i := range v.Len() {
+			list = append(list, mapStrings(v.Index(i), f))
+		}
+
+		return starlark.NewList(list)
+	case *starlark.Dict:
+		d := starlark.NewDict(v.Len())
+		for _, item := range v.Items() {
+			_ = d.SetKey(item[0], mapStrings(item[1], f))
+		}
+
+		return d
+	default:
+		return v
+	}
+}
+
+// dictGet returns the value of the given key, or nil.
+func dictGet(d *starlark.Dict, key string) starlark.Value {
+	if d == nil {
+		return nil
+	}
+
+	v, found, _ := d.Get(starlark.String(key))
+	if !found {
+		return nil
+	}
+
+	return v
+}
+
+// dictString returns the string value of the given key, or an empty string.
+func dictString(d *starlark.Dict, key string) string {
+	s, _ := starlark.AsString(dictGet(d, key))
+	return s
+}
@@ -0,0 +1,400 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+const droneStar = `
+def main(ctx):
+  return [
+    {
+      "kind": "pipeline",
+      "type": "docker",
+      "name": "test-" + ctx.build.source,
+      "platform": {"os": "linux", "arch": "arm64"},
+      "clone": {"depth": 1},
+      "environment": {"CGO_ENABLED": "0"},
+      "steps": [
+        {
+          "name": "test",
+          "image": "golang",
+          "pull": "always",
+          "commands": ["go test ./..."],
+          "volumes": [{"name": "cache", "path": "/go"}],
+          "when": {"event": {"exclude": ["promote"]}},
+        },
+        {
+          "name": "publish",
+          "image": "plugins/docker",
+          "environment": {"TOKEN": {"from_secret": "token"}},
+          "settings": {"repo": ctx.repo.slug},
+        },
+      ],
+      "volumes": [{"name": "cache", "temp": {}}],
+      "trigger": {"event": ["push", "custom"], "target": ["production"]},
+      "depends_on": ["lint"],
+    },
+  ]
+`
+
+const droneYAML = `labels:
+  platform: linux/arm64
+clone:
+  - name: clone
+    image: woodpeckerci/plugin-git
+    settings:
+      depth: 1
+steps:
+  - environment:
+      CGO_ENABLED: "0"
+    name: test
+    image: golang
+    pull: true
+    commands:
+      - go test ./...
+    volumes:
+      - cache:/go
+    when:
+      event:
+        exclude:
+          - deployment
+  - name: publish
+    image: plugins/docker
+    environment:
+      CGO_ENABLED: "0"
+      TOKEN:
+        from_secret: token
+    settings:
+      repo: opencloud-eu/wccs
+when:
+  event:
+    - push
+    - manual
+  environment:
+    - production
+depends_on:
+  - lint
+`
+
+func TestStarlarkConverter_Drone(t *testing.T) {
+	env := wccs.Environment{
+		Repo: model.Repo{Owner: "opencloud-eu", Name: "wccs", FullName: "opencloud-eu/wccs"},
+		Pipeline: model.Pipeline{
+			Event:       model.EventPull,
+			Refspec:     "feature:main",
+			Commit:      "abc",
+			EventReason: []string{"opened"},
+		},
+	}
+
+	t.Run("translates drone pipelines of .drone.star files", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger)
+		assert.NoError(t, err)
+
+		files, err := c.Convert(t.Context(), wccs.File{Name: ".drone.star", Data: droneStar}, env)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "test-feature.yaml", files[0].Name)
+		assert.Equal(t, droneYAML, files[0].Data)
+	})
+
+	t.Run("passes the drone context", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkMode(wccs.StarlarkModeDrone))
+		assert.NoError(t, err)
+
+		files, err := c.Convert(t.Context(), wccs.File{Name: "ci.star", Data: `
+def main(ctx):
+  b = ctx.build
+  commands = [ctx.repo.namespace, b.event, b.action, b.source, b.target, b.after]
+  return {"kind": "pipeline", "name": "ctx", "steps": [{"name": "ctx", "image": "alpine", "commands": commands}]}
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, "steps:\n  - name: ctx\n    image: alpine\n    commands:\n      - opencloud-eu\n      - pull_request\n      - opened\n      - feature\n      - main\n      - abc\n", files[0].Data)
+	})
+
+	t.Run("keeps the woodpecker mode for other files", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger)
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: ".woodpecker.star", Data: droneStar}, env)
+		assert.Error(t, err)
+	})
+
+	t.Run("defaults the os and arch of the platform independently", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkMode(wccs.StarlarkModeDrone))
+		assert.NoError(t, err)
+
+		for platform, label := range map[string]string{
+			`{"arch": "arm64"}`: "linux/arm64",
+			`{"os": "windows"}`: "windows/amd64",
+			`{}`:                "linux/amd64",
+		} {
+			files, err := c.Convert(t.Context(), wccs.File{Name: "ci.star", Data: `
+def main(ctx):
+  return {"kind": "pipeline", "name": "build", "platform": ` + platform + `, "steps": []}
+`}, env)
+			assert.NoError(t, err)
+			assert.Equal(t, "labels:\n  platform: "+label+"\nsteps: []\n", files[0].Data, platform)
+		}
+	})
+
+	t.Run("fails on unsupported settings", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkMode(wccs.StarlarkModeDrone))
+		assert.NoError(t, err)
+
+		for pipeline, expected := range map[string]string{
+			`{"name": "exec", "type": "exec", "steps": []}`:                                                  "pipeline exec has type exec",
+			`{"name": "command", "steps": [{"name": "step", "command": ["test"]}]}`:                          "command of step step",
+			`{"name": "action", "steps": [], "trigger": {"action": ["opened"]}}`:                             "condition action",
+			`{"name": "volume", "steps": [{"name": "step", "volumes": [{"name": "unknown", "path": "/"}]}]}`: "volume unknown",
+			`[{"kind": "secret", "name": "token"}]`:                                                          "document 1: kind secret is not supported",
+			`{"name": "limit", "steps": [], "concurrency": {"limit": 1}}`:                                    "pipeline limit: concurrency is not supported",
+			`{"name": "user", "steps": [{"name": "step", "user": "root"}]}`:                                  "step step: user is not supported",
+		} {
+			_, err = c.Convert(t.Context(), wccs.File{Name: "ci.star", Data: "def main(ctx):\n  return " + pipeline}, env)
+			assert.ErrorContains(t, err, expected, pipeline)
+		}
+	})
+
+	t.Run("fails on unknown modes", func(t *testing.T) {
+		_, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkMode("unknown"))
+		assert.ErrorIs(t, err, wccs.ErrUnknownType)
+	})
+}
+
+func TestDroneYAMLConverter_Compatible(t *testing.T) {
+	c := wccs.NewDroneYAMLConverter(noopLogger)
+	for name, compatible := range map[string]bool{
+		".drone.yml":        true,
+		".drone.yaml":       true,
+		"ci/web.drone.yml":  true,
+		".woodpecker.yaml":  false,
+		".drone.star":       false,
+		".drone.yml.backup": false,
+	} {
+		assert.Equal(t, compatible, c.Compatible(wccs.File{Name: name}), name)
+	}
+}
+
+func TestDroneYAMLConverter_Convert(t *testing.T) {
+	env := wccs.Environment{Repo: model.Repo{FullName: "opencloud-eu/wccs"}}
+
+	t.Run("translates the pipelines", func(t *testing.T) {
+		files, err := wccs.NewDroneYAMLConverter(noopLogger).Convert(t.Context(), wccs.File{Name: ".drone.yml", Data: `---
+kind: pipeline
+type: docker
+name: test
+
+platform: &platform
+  os: linux
+  arch: arm64
+
+environment:
+  CGO_ENABLED: "0"
+
+steps:
+  - name: test
+    image: golang
+    commands:
+      - go test ./...
+    volumes:
+      - name: cache
+        path: /go
+  - name: publish
+    image: plugins/docker
+    environment:
+      TOKEN:
+        from_secret: token
+    when:
+      event: [tag]
+
+services:
+  - name: redis
+    image: redis
+
+volumes:
+  - name: cache
+    temp: {}
+
+trigger:
+  branch: [main]
+  event: [push, tag, promote]
+
+---
+kind: pipeline
+platform: *platform
+node:
+  instance: builder
+
+steps:
+  - name: build
+    image: node
+
+depends_on:
+  - test
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{
+			{Name: "test.yaml", Data: `labels:
+  platform: linux/arm64
+steps:
+  - environment:
+      CGO_ENABLED: "0"
+    name: test
+    image: golang
+    commands:
+      - go test ./...
+    volumes:
+      - cache:/go
+  - name: publish
+    image: plugins/docker
+    environment:
+      CGO_ENABLED: "0"
+      TOKEN:
+        from_secret: token
+    when:
+      event:
+        - tag
+services:
+  - environment:
+      CGO_ENABLED: "0"
+    name: redis
+    image: redis
+when:
+  branch:
+    - main
+  event:
+    - push
+    - tag
+    - deployment
+`},
+			{Name: "default.yaml", Data: `labels:
+  platform: linux/arm64
+  instance: builder
+steps:
+  - name: build
+    image: node
+depends_on:
+  - test
+`},
+		}, files)
+	})
+
+	t.Run("reports unsupported documents and settings as warnings", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		c := wccs.NewDroneYAMLConverter(slog.New(slog.NewTextHandler(buf, nil)))
+
+		files, err := c.Convert(t.Context(), wccs.File{Name: ".drone.yml", Data: `
+kind: pipeline
+type: exec
+name: exec
+steps: []
+---
+kind: pipeline
+name: build
+concurrency:
+  limit: 1
+steps:
+  - name: build
+    image: golang
+    command: [build]
+    resources:
+      limits:
+        memory: 1GiB
+    network_mode: host
+    user: root
+trigger:
+  action: [opened]
+---
+kind: secret
+name: token
+get:
+  path: secrets
+  name: token
+`}, env)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "build.yaml", files[0].Name)
+		assert.Equal(t, "steps:\n  - name: build\n    image: golang\n", files[0].Data)
+
+		for _, warning := range []string{
+			"pipeline exec has type exec",
+			"pipeline build: concurrency is not supported",
+			"command of step build",
+			"step build: resources is not supported",
+			"step build: network_mode is not supported",
+			"step build: user is not supported",
+			"condition action",
+			"document 3: kind secret is not supported",
+		} {
+			assert.Contains(t, buf.String(), warning)
+			assert.Condition(t, func() bool {
+				return slices.ContainsFunc(files[0].Warnings, func(w string) bool { return strings.Contains(w, warning) })
+			}, warning)
+		}
+		assert.Contains(t, buf.String(), "repo=opencloud-eu/wccs")
+	})
+
+	t.Run("fails if no pipeline is left", func(t *testing.T) {
+		_, err := wccs.NewDroneYAMLConverter(noopLogger).Convert(t.Context(), wccs.File{Name: ".drone.yml", Data: "kind: pipeline\ntype: exec\nname: exec\nsteps: []\n---\nkind: secret\nname: token\n"}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoContent)
+		assert.ErrorContains(t, err, "pipeline exec has type exec")
+		assert.ErrorContains(t, err, "kind secret is not supported")
+
+		_, err = wccs.NewDroneYAMLConverter(noopLogger).Convert(t.Context(), wccs.File{Name: ".drone.yml", Data: "---\n"}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoContent)
+	})
+
+	t.Run("defaults the os and arch of a partial platform", func(t *testing.T) {
+		for platform, label := range map[string]string{
+			"arch: arm64": "linux/arm64",
+			"os: windows": "windows/amd64",
+		} {
+			files, err := wccs.NewDroneYAMLConverter(noopLogger).Convert(t.Context(), wccs.File{Name: ".drone.yml", Data: "kind: pipeline\nname: build\nplatform:\n  " + platform + "\nsteps: []\n"}, env)
+			assert.NoError(t, err)
+			assert.Equal(t, []wccs.File{{Name: "build.yaml", Data: "labels:\n  platform: " + label + "\nsteps: []\n"}}, files, platform)
+		}
+	})
+
+	t.Run("fails on invalid files", func(t *testing.T) {
+		c := wccs.NewDroneYAMLConverter(noopLogger)
+
+		_, err := c.Convert(t.Context(), wccs.File{Name: ".drone.yml", Data: "kind: pipeline\nsteps: [\n"}, env)
+		assert.ErrorContains(t, err, ".drone.yml")
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: ".drone.yml", Data: "kind: pipeline\nname: test\nsteps: {}\n"}, env)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: ".drone.yml"}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoContent)
+	})
+
+	t.Run("fails on cyclic anchors", func(t *testing.T) {
+		_, err := wccs.NewDroneYAMLConverter(noopLogger).Convert(t.Context(), wccs.File{Name: ".drone.yml", Data: "kind: pipeline\nname: build\nsteps: &steps [*steps]\n"}, env)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+		assert.ErrorContains(t, err, ".drone.yml: unsupported type: alias cycle")
+	})
+}
@@ -0,0 +1,322 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"gopkg.in/yaml.v3"
+)
+
+// StarlarkToYAML converts the given Starlark value into a YAML node tree.
+// Dict insertion order is kept, struct and module fields are sorted by name.
+func StarlarkToYAML(v starlark.Value) (*yaml.Node, error) {
+	return (&yamlEncoder{seen: map[starlark.Value]bool{}}).encode(v, "")
+}
+
+// yamlMaxNodes limits the number of nodes YAMLToStarlark expands, aliases may repeat a node exponentially often.
+const yamlMaxNodes = 1 << 20
+
+// YAMLToStarlark converts the given YAML node tree into Starlark values, mappings keep their order.
+// Aliases which refer to themselves and trees which expand to more than yamlMaxNodes nodes are rejected.
+func YAMLToStarlark(node *yaml.Node) (starlark.Value, error) {
+	return (&yamlDecoder{expanding: map[*yaml.Node]bool{}}).decode(node)
+}
+
+// yamlDecoder walks YAML nodes and keeps track of the aliases on the current path to detect cycles.
+type yamlDecoder struct {
+	expanding map[*yaml.Node]bool
+	nodes     int
+}
+
+func (d *yamlDecoder) decode(node *yaml.Node) (starlark.Value, error) {
+	if d.nodes++; d.nodes > yamlMaxNodes {
+		return nil, fmt.Errorf("%w: max yaml nodes %d", ErrLimitExceeded, yamlMaxNodes)
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return starlark.None, nil
+		}
+
+		return d.decode(node.Content[0])
+	case yaml.AliasNode:
+		if d.expanding[node] {
+			return nil, fmt.Errorf("%w: alias cycle at line %d", ErrUnsupportedType, node.Line)
+		}
+		d.expanding[node] = true
+		defer delete(d.expanding, node)
+
+		return d.decode(node.Alias)
+	case yaml.SequenceNode:
+		list := make([]starlark.Value, 0, len(node.Content))
+		for _, item := range node.Content {
+			v, err := d.decode(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+
+		return starlark.NewList(list), nil
+	case yaml.MappingNode:
+		dict := starlark.NewDict(len(node.Content) / 2) //nolint:mnd
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, err := d.decode(node.Content[i])
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := d.decode(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+
+			if node.Content[i].Tag != "!!merge" {
+				if err := dict.SetKey(k, v); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			// merged values never override keys which are set explicitly
+			if err := mergeYAML(dict, v); err != nil {
+				return nil, err
+			}
+		}
+
+		return dict, nil
+	case yaml.ScalarNode:
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return nil, err
+		}
+
+		switch v := v.(type) {
+		case nil:
+			return starlark.None, nil
+		case bool:
+			return starlark.Bool(v), nil
+		case int:
+			return starlark.MakeInt(v), nil
+		case int64:
+			return starlark.MakeInt64(v), nil
+		case uint64:
+			return starlark.MakeUint64(v), nil
+		case float64:
+			return starlark.Float(v), nil
+		case string:
+			return starlark.String(v), nil
+		default:
+			// timestamps and binaries are kept as written
+			return starlark.String(node.Value), nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown yaml node kind %d", ErrUnsupportedType, node.Kind)
+	}
+}
+
+func mergeYAML(dict *starlark.Dict, v starlark.Value) error {
+	switch v := v.(type) {
+	case *starlark.Dict:
+		for _, item := range v.Items() {
+			if _, found, _ := dict.Get(item[0]); found {
+				continue
+			}
+
+			if err := dict.SetKey(item[0], item[1]); err != nil {
+				return err
+			}
+		}
+	case *starlark.List:
+		for i := range v.Len() {
+			if err := mergeYAML(dict, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: cannot merge %s", ErrUnsupportedType, v.Type())
+	}
+
+	return nil
+}
+
+// encodeYAML renders the given node as YAML document.
+func encodeYAML(node *yaml.Node) (string, error) {
+	buf := new(bytes.Buffer)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2) //nolint:mnd
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// yamlEncoder walks Starlark values and keeps track of the containers on the current path to detect cycles.
+type yamlEncoder struct {
+	seen map[starlark.Value]bool
+}
+
+func (e *yamlEncoder) encode(v starlark.Value, path string) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	case starlark.Bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(bool(v))}, nil
+	case starlark.Int:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}, nil
+	case starlark.Float:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: formatYAMLFloat(float64(v))}, nil
+	case starlark.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(v)}, nil
+	case *starlark.List:
+		return e.encodeSequence(v, v, path)
+	case starlark.Tuple:
+		return e.encodeSequence(nil, v, path)
+	case *starlark.Dict:
+		if e.seen[v] {
+			return nil, fmt.Errorf("%w: cycle detected at %s", ErrUnsupportedType, displayPath(path))
+		}
+		e.seen[v] = true
+		defer delete(e.seen, v)
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, item := range v.Items() {
+			key, err := e.encodeKey(item[0], path)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := e.encode(item[1], joinPath(path, key.Value))
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content = append(node.Content, key, value)
+		}
+
+		return node, nil
+	case *starlarkstruct.Struct:
+		return e.encodeAttrs(v, v.AttrNames(), path)
+	case *starlarkstruct.Module:
+		names := make([]string, 0, len(v.Members))
+		for name := range v.Members {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return e.encodeAttrs(v, names, path)
+	default:
+		return nil, fmt.Errorf("%w: cannot serialize %s at %s", ErrUnsupportedType, v.Type(), displayPath(path))
+	}
+}
+
+func (e *yamlEncoder) encodeSequence(list *starlark.List, seq starlark.Indexable, path string) (*yaml.Node, error) {
+	if list != nil {
+		if e.seen[list] {
+			return nil, fmt.Errorf("%w: cycle detected at %s", ErrUnsupportedType, displayPath(path))
+		}
+		e.seen[list] = true
+		defer delete(e.seen, list)
+	}
+
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for i := range seq.Len() {
+		value, err := e.encode(seq.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+
+		node.Content = append(node.Content, value)
+	}
+
+	return node, nil
+}
+
+func (e *yamlEncoder) encodeAttrs(v starlark.HasAttrs, names []string, path string) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, name := range names {
+		attr, err := v.Attr(name)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := e.encode(attr, joinPath(path, name))
+		if err != nil {
+			return nil, err
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, value)
+	}
+
+	return node, nil
+}
+
+// encodeKey converts a dict key, only scalar keys are allowed.
+func (e *yamlEncoder) encodeKey(k starlark.Value, path string) (*yaml.Node, error) {
+	switch k.(type) {
+	case starlark.String, starlark.Int, starlark.Bool:
+		return e.encode(k, path)
+	default:
+		return nil, fmt.Errorf("%w: cannot use %s as key at %s", ErrUnsupportedType, k.Type(), displayPath(path))
+	}
+}
+
+func formatYAMLFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	default:
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+
+		return s
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "top level"
+	}
+
+	return path
+}
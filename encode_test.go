@@ -0,0 +1,156 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"gopkg.in/yaml.v3"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func evalStarlark(t *testing.T, expr string) starlark.Value {
+	t.Helper()
+
+	globals, err := starlark.ExecFile(&starlark.Thread{}, "test.star", "value = "+expr, starlark.StringDict{
+		"struct": starlark.NewBuiltin("struct", starlarkstruct.Make),
+	})
+	assert.NoError(t, err)
+
+	return globals["value"]
+}
+
+func encodeStarlark(t *testing.T, expr string) (string, error) {
+	t.Helper()
+
+	node, err := wccs.StarlarkToYAML(evalStarlark(t, expr))
+	if err != nil {
+		return "", err
+	}
+
+	out, err := yaml.Marshal(node)
+	assert.NoError(t, err)
+
+	return string(out), nil
+}
+
+func TestStarlarkToYAML(t *testing.T) {
+	t.Run("encodes scalars", func(t *testing.T) {
+		for expr, expected := range map[string]string{
+			`None`:         "null\n",
+			`True`:         "true\n",
+			`False`:        "false\n",
+			`42`:           "42\n",
+			`1.5`:          "1.5\n",
+			`2.0`:          "2.0\n",
+			`float("inf")`: ".inf\n",
+			`"hello"`:      "hello\n",
+			`"True"`:       "\"True\"\n",
+			`"42"`:         "\"42\"\n",
+			`"null"`:       "\"null\"\n",
+		} {
+			out, err := encodeStarlark(t, expr)
+			assert.NoError(t, err, expr)
+			assert.Equal(t, expected, out, expr)
+		}
+	})
+
+	t.Run("keeps the dict insertion order", func(t *testing.T) {
+		out, err := encodeStarlark(t, `{"zeta": 1, "alpha": {"steps": {"test": 1, "build": 2}}, "beta": [1, (2, 3)]}`)
+		assert.NoError(t, err)
+		assert.Equal(t, "zeta: 1\nalpha:\n    steps:\n        test: 1\n        build: 2\nbeta:\n    - 1\n    - - 2\n      - 3\n", out)
+	})
+
+	t.Run("keeps keywords inside of strings", func(t *testing.T) {
+		out, err := encodeStarlark(t, `{"message": "True, False and None are fine", "none": None}`)
+		assert.NoError(t, err)
+
+		data := map[string]any{}
+		assert.NoError(t, yaml.Unmarshal([]byte(out), &data))
+		assert.Equal(t, "True, False and None are fine", data["message"])
+		assert.Nil(t, data["none"])
+	})
+
+	t.Run("encodes structs with sorted fields", func(t *testing.T) {
+		out, err := encodeStarlark(t, `struct(image = "golang", commands = ["go test"])`)
+		assert.NoError(t, err)
+		assert.Equal(t, "commands:\n    - go test\nimage: golang\n", out)
+	})
+
+	t.Run("fails on values that cannot be serialized", func(t *testing.T) {
+		_, err := encodeStarlark(t, `{"steps": [{"run": len}]}`)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+		assert.Contains(t, err.Error(), "builtin_function_or_method")
+		assert.Contains(t, err.Error(), "steps[0].run")
+	})
+
+	t.Run("fails on unsupported keys", func(t *testing.T) {
+		_, err := encodeStarlark(t, `{(1, 2): "tuple"}`)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+	})
+
+	t.Run("fails on cycles", func(t *testing.T) {
+		list := starlark.NewList(nil)
+		assert.NoError(t, list.Append(list))
+
+		_, err := wccs.StarlarkToYAML(list)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+		assert.Contains(t, err.Error(), "cycle")
+	})
+
+	t.Run("allows shared values", func(t *testing.T) {
+		out, err := encodeStarlark(t, `[[1], [1]]`)
+		assert.NoError(t, err)
+		assert.Equal(t, "- - 1\n- - 1\n", out)
+	})
+}
+
+func TestYAMLToStarlark(t *testing.T) {
+	decode := func(t *testing.T, src string) (starlark.Value, error) {
+		t.Helper()
+
+		var node yaml.Node
+		assert.NoError(t, yaml.Unmarshal([]byte(src), &node))
+
+		return wccs.YAMLToStarlark(&node)
+	}
+
+	t.Run("expands aliases", func(t *testing.T) {
+		v, err := decode(t, "a: &a [1]\nb: [*a, *a]\n")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a": [1], "b": [[1], [1]]}`, v.String())
+	})
+
+	t.Run("fails on alias cycles", func(t *testing.T) {
+		_, err := decode(t, "a: &a [*a]\n")
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+		assert.Contains(t, err.Error(), "alias cycle")
+	})
+
+	t.Run("limits the expanded nodes", func(t *testing.T) {
+		src := "a: &a [x, x, x, x, x, x, x, x, x, x]\n"
+		for i := 'b'; i <= 'i'; i++ {
+			src += fmt.Sprintf("%c: &%c [*%c, *%c, *%c, *%c, *%c, *%c, *%c, *%c, *%c, *%c]\n", i, i, i-1, i-1, i-1, i-1, i-1, i-1, i-1, i-1, i-1, i-1)
+		}
+
+		_, err := decode(t, src)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+	})
+}
@@ -0,0 +1,598 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.starlark.net/syntax"
+)
+
+// starlarkIndent is the indentation of a block or a multi-line collection.
+const starlarkIndent = "  "
+
+// FormatStarlark returns the canonical layout of the given Starlark file, comments are kept.
+// Collections and calls which span multiple lines are printed with one element per line and a trailing comma,
+// all others are printed on a single line. Strings use double quotes and the leading load statements are sorted.
+func FormatStarlark(name, data string) (string, error) {
+	f, err := syntax.LegacyFileOptions().Parse(name, data, syntax.RetainComments)
+	if err != nil {
+		return "", err
+	}
+
+	p := &starlarkPrinter{lineStart: true}
+	p.file(f)
+	formatted := p.b.String()
+
+	// every comment is attached to a node which is printed, losing one is a bug of the printer
+	g, err := syntax.LegacyFileOptions().Parse(name, formatted, syntax.RetainComments)
+	if err != nil {
+		return "", fmt.Errorf("%w: formatted file is invalid: %w", ErrUnsupportedType, err)
+	}
+	if countComments(f) != countComments(g) {
+		return "", fmt.Errorf("%w: formatting %s would lose comments", ErrUnsupportedType, name)
+	}
+
+	return formatted, nil
+}
+
+// starlarkPrinter prints a syntax tree in the canonical layout.
+type starlarkPrinter struct {
+	b      strings.Builder
+	indent int
+	// lineStart is set if nothing but the indentation has been written on the current line.
+	lineStart bool
+	// pending suffix comments are written at the end of the current line.
+	pending []syntax.Comment
+}
+
+func (p *starlarkPrinter) write(s ...string) {
+	if p.lineStart {
+		p.b.WriteString(strings.Repeat(starlarkIndent, p.indent))
+		p.lineStart = false
+	}
+
+	for _, s := range s {
+		p.b.WriteString(s)
+	}
+}
+
+// newline ends the current line with its pending comments, the indentation is written with the next token.
+func (p *starlarkPrinter) newline() {
+	for _, c := range p.pending {
+		p.write("  ", strings.TrimSpace(c.Text))
+	}
+	p.pending = nil
+
+	p.b.WriteString("\n")
+	p.lineStart = true
+}
+
+// before writes the comments in front of the node on lines of their own,
+// comments of nodes which do not start a line are moved to its end.
+func (p *starlarkPrinter) before(n syntax.Node) {
+	comments := n.Comments()
+	if comments == nil {
+		return
+	}
+
+	for i, c := range comments.Before {
+		if !p.lineStart {
+			p.pending = append(p.pending, c)
+			continue
+		}
+
+		if i > 0 && c.Start.Line > comments.Before[i-1].Start.Line+1 {
+			p.newline()
+		}
+
+		p.write(strings.TrimSpace(c.Text))
+		p.newline()
+	}
+
+	if len(comments.Before) != 0 && p.lineStart && syntax.Start(n).Line > comments.Before[len(comments.Before)-1].Start.Line+1 {
+		p.newline()
+	}
+}
+
+// after queues the suffix comments of the node.
+func (p *starlarkPrinter) after(n syntax.Node) {
+	if comments := n.Comments(); comments != nil {
+		p.pending = append(p.pending, comments.Suffix...)
+	}
+}
+
+func (p *starlarkPrinter) file(f *syntax.File) {
+	stmts := sortLoads(f.Stmts)
+	for i, stmt := range stmts {
+		if i > 0 {
+			p.newline()
+
+			prev := stmts[i-1]
+			_, isLoad := stmt.(*syntax.LoadStmt)
+			_, prevIsLoad := prev.(*syntax.LoadStmt)
+			_, isDef := stmt.(*syntax.DefStmt)
+			_, prevIsDef := prev.(*syntax.DefStmt)
+			if isDef || prevIsDef || isLoad != prevIsLoad || separated(prev, stmt) {
+				p.newline()
+			}
+		}
+
+		p.stmt(stmt)
+	}
+
+	if comments := f.Comments(); comments != nil {
+		for i, c := range comments.After {
+			if len(stmts) != 0 || i > 0 {
+				p.newline()
+			}
+
+			if (i == 0 && len(stmts) != 0 && c.Start.Line > syntax.End(stmts[len(stmts)-1]).Line+1) ||
+				(i > 0 && c.Start.Line > comments.After[i-1].Start.Line+1) {
+				p.newline()
+			}
+
+			p.write(strings.TrimSpace(c.Text))
+		}
+	}
+
+	if p.b.Len() != 0 || len(p.pending) != 0 {
+		p.newline()
+	}
+}
+
+// sortLoads sorts the leading load statements by module and their symbols by name.
+func sortLoads(stmts []syntax.Stmt) []syntax.Stmt {
+	n := 0
+	for n < len(stmts) {
+		load, ok := stmts[n].(*syntax.LoadStmt)
+		if !ok {
+			break
+		}
+
+		symbols := make([]int, len(load.To))
+		for i := range symbols {
+			symbols[i] = i
+		}
+		slices.SortStableFunc(symbols, func(a, b int) int {
+			return strings.Compare(load.To[a].Name, load.To[b].Name)
+		})
+
+		from, to := slices.Clone(load.From), slices.Clone(load.To)
+		for i, symbol := range symbols {
+			load.From[i], load.To[i] = from[symbol], to[symbol]
+		}
+
+		n++
+	}
+
+	if n == 0 {
+		return stmts
+	}
+
+	// the comments in front of the first statement are the header of the file and stay in front
+	var header []syntax.Comment
+	if comments := stmts[0].Comments(); comments != nil {
+		header, comments.Before = comments.Before, nil
+	}
+
+	stmts = slices.Clone(stmts)
+	slices.SortStableFunc(stmts[:n], func(a, b syntax.Stmt) int {
+		return strings.Compare(a.(*syntax.LoadStmt).ModuleName(), b.(*syntax.LoadStmt).ModuleName()) //nolint:forcetypeassert
+	})
+
+	stmts[0].AllocComments()
+	stmts[0].Comments().Before = append(header, stmts[0].Comments().Before...)
+
+	return stmts
+}
+
+// separated reports whether the source has a blank line between the given nodes,
+// nodes without position are never separated.
+func separated(prev, next syntax.Node) bool {
+	if !syntax.Start(prev).IsValid() || !syntax.Start(next).IsValid() {
+		return false
+	}
+
+	start := syntax.Start(next).Line
+	if comments := next.Comments(); comments != nil && len(comments.Before) != 0 {
+		start = comments.Before[0].Start.Line
+	}
+
+	return start > syntax.End(prev).Line+1
+}
+
+func (p *starlarkPrinter) stmts(stmts []syntax.Stmt) {
+	for i, stmt := range stmts {
+		if i > 0 {
+			p.newline()
+			if separated(stmts[i-1], stmt) {
+				p.newline()
+			}
+		}
+
+		p.stmt(stmt)
+	}
+}
+
+// block writes the statements of a compound statement.
+func (p *starlarkPrinter) block(stmts []syntax.Stmt) {
+	p.write(":")
+	p.indent++
+	p.newline()
+	p.stmts(stmts)
+	p.indent--
+}
+
+func (p *starlarkPrinter) stmt(stmt syntax.Stmt) {
+	p.before(stmt)
+
+	switch stmt := stmt.(type) {
+	case *syntax.ExprStmt:
+		p.expr(stmt.X)
+	case *syntax.AssignStmt:
+		p.expr(stmt.LHS)
+		p.write(" ", stmt.Op.String(), " ")
+		p.expr(stmt.RHS)
+	case *syntax.DefStmt:
+		p.write("def ", stmt.Name.Name)
+		p.list("(", ")", stmt.Lparen, stmt.Rparen, stmt.Params, false)
+		p.block(stmt.Body)
+	case *syntax.IfStmt:
+		p.ifStmt(stmt, "if ")
+	case *syntax.ForStmt:
+		p.write("for ")
+		p.expr(stmt.Vars)
+		p.write(" in ")
+		p.expr(stmt.X)
+		p.block(stmt.Body)
+	case *syntax.WhileStmt:
+		p.write("while ")
+		p.expr(stmt.Cond)
+		p.block(stmt.Body)
+	case *syntax.ReturnStmt:
+		p.write("return")
+		if stmt.Result != nil {
+			p.write(" ")
+			p.expr(stmt.Result)
+		}
+	case *syntax.BranchStmt:
+		p.write(stmt.Token.String())
+	case *syntax.LoadStmt:
+		args := []syntax.Expr{stmt.Module}
+		for i, to := range stmt.To {
+			from := stmt.From[i]
+			var arg syntax.Expr = &syntax.Literal{Token: syntax.STRING, Raw: `"` + from.Name + `"`}
+			if to.Name != from.Name {
+				arg = &syntax.BinaryExpr{X: &syntax.Ident{Name: to.Name}, Op: syntax.EQ, Y: arg}
+			}
+
+			// the comments of a symbol are attached to either of its identifiers, which are the same without an alias
+			arg.AllocComments()
+			for _, id := range slices.Compact([]*syntax.Ident{to, from}) {
+				if comments := id.Comments(); comments != nil {
+					arg.Comments().Before = append(arg.Comments().Before, comments.Before...)
+					arg.Comments().Suffix = append(arg.Comments().Suffix, comments.Suffix...)
+				}
+			}
+
+			args = append(args, arg)
+		}
+
+		p.write("load")
+		p.list("(", ")", stmt.Load, stmt.Rparen, args, false)
+	}
+
+	p.after(stmt)
+}
+
+// ifStmt writes an if statement, an else branch which only holds an if statement written as elif is kept.
+func (p *starlarkPrinter) ifStmt(stmt *syntax.IfStmt, keyword string) {
+	p.write(keyword)
+	p.expr(stmt.Cond)
+	p.block(stmt.True)
+	if len(stmt.False) == 0 {
+		return
+	}
+
+	p.newline()
+	if elif, ok := stmt.False[0].(*syntax.IfStmt); ok && len(stmt.False) == 1 && elif.If == stmt.ElsePos {
+		p.before(elif)
+		p.ifStmt(elif, "elif ")
+		p.after(elif)
+
+		return
+	}
+
+	p.write("else")
+	p.block(stmt.False)
+}
+
+func (p *starlarkPrinter) expr(e syntax.Expr) {
+	p.before(e)
+
+	switch e := e.(type) {
+	case *syntax.Ident:
+		p.write(e.Name)
+	case *syntax.Literal:
+		if e.Token == syntax.STRING || e.Token == syntax.BYTES {
+			p.write(starlarkQuote(e.Raw))
+			break
+		}
+
+		p.write(e.Raw)
+	case *syntax.ParenExpr:
+		// the parentheses of a tuple belong to the enclosing expression
+		if tuple, ok := e.X.(*syntax.TupleExpr); ok && !tuple.Lparen.IsValid() {
+			p.before(tuple)
+			p.list("(", ")", e.Lparen, e.Rparen, tuple.List, len(tuple.List) == 1)
+			p.after(tuple)
+
+			break
+		}
+
+		if e.Lparen.Line == e.Rparen.Line {
+			p.write("(")
+			p.expr(e.X)
+			p.write(")")
+			break
+		}
+
+		p.write("(")
+		p.indent++
+		p.newline()
+		p.expr(e.X)
+		p.indent--
+		p.newline()
+		p.write(")")
+	case *syntax.ListExpr:
+		p.list("[", "]", e.Lbrack, e.Rbrack, e.List, false)
+	case *syntax.TupleExpr:
+		if !e.Lparen.IsValid() {
+			for i, x := range e.List {
+				if i > 0 {
+					p.write(", ")
+				}
+				p.expr(x)
+			}
+			if len(e.List) == 1 {
+				p.write(",")
+			}
+
+			break
+		}
+
+		p.list("(", ")", e.Lparen, e.Rparen, e.List, len(e.List) == 1)
+	case *syntax.DictExpr:
+		p.list("{", "}", e.Lbrace, e.Rbrace, e.List, false)
+	case *syntax.DictEntry:
+		p.expr(e.Key)
+		p.write(": ")
+		p.expr(e.Value)
+	case *syntax.CallExpr:
+		p.expr(e.Fn)
+		p.list("(", ")", e.Lparen, e.Rparen, e.Args, false)
+	case *syntax.DotExpr:
+		p.expr(e.X)
+		p.write(".", e.Name.Name)
+	case *syntax.IndexExpr:
+		p.expr(e.X)
+		p.write("[")
+		p.expr(e.Y)
+		p.write("]")
+	case *syntax.SliceExpr:
+		p.expr(e.X)
+		p.write("[")
+		for i, x := range []syntax.Expr{e.Lo, e.Hi, e.Step} {
+			if i == 2 && x == nil {
+				break
+			}
+			if i > 0 {
+				p.write(":")
+			}
+			if x != nil {
+				p.expr(x)
+			}
+		}
+		p.write("]")
+	case *syntax.UnaryExpr:
+		switch {
+		case e.Op == syntax.NOT:
+			p.write("not ")
+		default:
+			p.write(e.Op.String())
+		}
+		if e.X != nil {
+			p.expr(e.X)
+		}
+	case *syntax.BinaryExpr:
+		p.expr(e.X)
+		p.write(" ", e.Op.String(), " ")
+		p.expr(e.Y)
+	case *syntax.CondExpr:
+		p.expr(e.True)
+		p.write(" if ")
+		p.expr(e.Cond)
+		p.write(" else ")
+		p.expr(e.False)
+	case *syntax.LambdaExpr:
+		p.write("lambda")
+		for i, param := range e.Params {
+			if i == 0 {
+				p.write(" ")
+			} else {
+				p.write(", ")
+			}
+			p.expr(param)
+		}
+		p.write(": ")
+		p.expr(e.Body)
+	case *syntax.Comprehension:
+		open, closing := "[", "]"
+		if e.Curly {
+			open, closing = "{", "}"
+		}
+
+		p.write(open)
+		p.expr(e.Body)
+		for _, clause := range e.Clauses {
+			switch clause := clause.(type) {
+			case *syntax.ForClause:
+				p.write(" for ")
+				p.expr(clause.Vars)
+				p.write(" in ")
+				p.expr(clause.X)
+			case *syntax.IfClause:
+				p.write(" if ")
+				p.expr(clause.Cond)
+			}
+		}
+		p.write(closing)
+	}
+
+	p.after(e)
+}
+
+// list writes the elements of a collection, call or definition.
+// Lists which span multiple lines in the source get one element per line and a trailing comma,
+// a single multi-line element which starts and ends with the list is hugged by the brackets.
+func (p *starlarkPrinter) list(open, closing string, start, end syntax.Position, list []syntax.Expr, trailingComma bool) {
+	p.write(open)
+	defer p.write(closing)
+
+	if len(list) == 0 {
+		return
+	}
+
+	if start.Line == end.Line {
+		for i, x := range list {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expr(x)
+		}
+		if trailingComma {
+			p.write(",")
+		}
+
+		return
+	}
+
+	if len(list) == 1 && hugged(list[0], start, end) {
+		p.expr(list[0])
+		return
+	}
+
+	p.indent++
+	for i, x := range list {
+		p.newline()
+		if i > 0 && separated(list[i-1], x) {
+			p.newline()
+		}
+
+		p.expr(x)
+		p.write(",")
+	}
+	p.indent--
+	p.newline()
+}
+
+// hugged reports whether the single element of a multi-line list is printed without breaking the lines around it.
+func hugged(x syntax.Expr, start, end syntax.Position) bool {
+	if comments := x.Comments(); comments != nil && len(comments.Before)+len(comments.Suffix) != 0 {
+		return false
+	}
+
+	switch x := x.(type) {
+	case *syntax.DictExpr:
+		return x.Lbrace.Line == start.Line && x.Rbrace.Line == end.Line && x.Lbrace.Line != x.Rbrace.Line
+	case *syntax.ListExpr:
+		return x.Lbrack.Line == start.Line && x.Rbrack.Line == end.Line && x.Lbrack.Line != x.Rbrack.Line
+	case *syntax.CallExpr:
+		return syntax.Start(x).Line == start.Line && x.Rparen.Line == end.Line && x.Lparen.Line != x.Rparen.Line
+	default:
+		return false
+	}
+}
+
+// starlarkQuote returns the string literal with double quotes if this does not change its value.
+func starlarkQuote(raw string) string {
+	prefix := raw[:strings.IndexAny(raw, `"'`)]
+	body := raw[len(prefix):]
+
+	quote := body[:1]
+	if strings.HasPrefix(body, "'''") || strings.HasPrefix(body, `"""`) {
+		quote = body[:3]
+	}
+	if quote[0] == '"' {
+		return raw
+	}
+
+	inner := body[len(quote) : len(body)-len(quote)]
+	if strings.Contains(inner, `"`) {
+		return raw
+	}
+
+	if strings.Contains(inner, `\'`) {
+		// the backslash of an escaped quote is part of the value of raw strings
+		if strings.ContainsAny(prefix, "rR") {
+			return raw
+		}
+
+		inner = strings.ReplaceAll(inner, `\'`, `'`)
+	}
+
+	return prefix + strings.Repeat(`"`, len(quote)) + inner + strings.Repeat(`"`, len(quote))
+}
+
+// countComments returns the number of comments of the file.
+func countComments(f *syntax.File) int {
+	var n int
+	walkSyntax(f, func(node syntax.Node) bool {
+		if node == nil {
+			return true
+		}
+
+		if comments := node.Comments(); comments != nil {
+			n += len(comments.Before) + len(comments.Suffix) + len(comments.After)
+		}
+
+		return true
+	})
+
+	return n
+}
+
+// walkSyntax traverses a syntax tree like syntax.Walk, which panics on while loops.
+func walkSyntax(n syntax.Node, f func(syntax.Node) bool) {
+	syntax.Walk(n, func(n syntax.Node) bool {
+		stmt, ok := n.(*syntax.WhileStmt)
+		if !ok {
+			return f(n)
+		}
+
+		if f(stmt) {
+			walkSyntax(stmt.Cond, f)
+			for _, s := range stmt.Body {
+				walkSyntax(s, f)
+			}
+			f(nil)
+		}
+
+		return false
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestFormatStarlark(t *testing.T) {
+	for name, test := range map[string]struct {
+		data     string
+		expected string
+	}{
+		"indentation and spacing": {
+			data:     "def f(a,b=2,*args,**kwargs):\n    if a and not b:\n        return -a\n    elif b:\n        pass\n    else:\n        x=a[1:2]+a[::2]\n    return x if x else lambda y:y\n",
+			expected: "def f(a, b = 2, *args, **kwargs):\n  if a and not b:\n    return -a\n  elif b:\n    pass\n  else:\n    x = a[1:2] + a[::2]\n  return x if x else lambda y: y\n",
+		},
+		"sorted loads": {
+			data:     "# header\n\nload('z.star', 'b', 'a')\nload(\"a.star\", x = \"y\")\nx = 1\n",
+			expected: "# header\n\nload(\"a.star\", x = \"y\")\nload(\"z.star\", \"a\", \"b\")\n\nx = 1\n",
+		},
+		"trailing commas in multi-line collections": {
+			data:     "x = [1,\n  2]\ny = {'a': 1,\n  'b': (1,\n    2)}\nz = f(a,\n  b = 1)\nw = [1, 2]\n",
+			expected: "x = [\n  1,\n  2,\n]\ny = {\n  \"a\": 1,\n  \"b\": (\n    1,\n    2,\n  ),\n}\nz = f(\n  a,\n  b = 1,\n)\nw = [1, 2]\n",
+		},
+		"hugged collections": {
+			data:     "def main(ctx):\n    return [{\n        'name': 'test',\n    }]\n",
+			expected: "def main(ctx):\n  return [{\n    \"name\": \"test\",\n  }]\n",
+		},
+		"quotes": {
+			data:     "x = ['a', 'it\\'s', 'say \"hi\"', r'\\d', '''doc''', b'x', \"b\"]\n",
+			expected: "x = [\"a\", \"it's\", 'say \"hi\"', r\"\\d\", \"\"\"doc\"\"\", b\"x\", \"b\"]\n",
+		},
+		"comments": {
+			data:     "x = 1  # one\n\n\n# two\ny = [\n  1,  # three\n\n  # four\n  2,\n]\nif x:  # five\n  pass\n# six\n",
+			expected: "x = 1  # one\n\n# two\ny = [\n  1,  # three\n\n  # four\n  2,\n]\nif x:  # five\n  pass\n# six\n",
+		},
+		"blank lines around definitions": {
+			data:     "x = 1\ndef f():\n  pass\ny = f()\n",
+			expected: "x = 1\n\ndef f():\n  pass\n\ny = f()\n",
+		},
+		"while loops": {
+			data:     "def f(x):\n    while x>0:\n        x-=1\n    return x\n",
+			expected: "def f(x):\n  while x > 0:\n    x -= 1\n  return x\n",
+		},
+		"empty": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			formatted, err := wccs.FormatStarlark("test.star", test.data)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, formatted)
+
+			again, err := wccs.FormatStarlark("test.star", formatted)
+			assert.NoError(t, err)
+			assert.Equal(t, formatted, again, "not idempotent")
+		})
+	}
+
+	t.Run("testdata and the library are formatted", func(t *testing.T) {
+		paths, err := filepath.Glob("testdata/*.star")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, paths)
+
+		library, err := filepath.Glob("library/*.star")
+		assert.NoError(t, err)
+		paths = append(paths, library...)
+
+		for _, p := range paths {
+			data, err := os.ReadFile(p)
+			assert.NoError(t, err)
+
+			formatted, err := wccs.FormatStarlark(p, string(data))
+			assert.NoError(t, err)
+			assert.Equal(t, string(data), formatted, p)
+		}
+	})
+
+	t.Run("returns syntax errors", func(t *testing.T) {
+		_, err := wccs.FormatStarlark("test.star", "def f(:\n")
+		assert.ErrorContains(t, err, "test.star:1:8")
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultStarlarkGlobalsName is the name of the predeclared globals struct if no name is set.
+const defaultStarlarkGlobalsName = "org"
+
+// StarlarkGlobals are operator defined values, e.g. registry hosts or default images,
+// which are predeclared as a struct in every script.
+type StarlarkGlobals struct {
+	// Name of the predeclared struct, org if empty.
+	Name string `yaml:"name"`
+	// Values are the fields of the struct, nested maps and lists become dicts and lists.
+	Values map[string]any `yaml:"values"`
+	// Overrides are merged into the values for the repositories of the owner they are keyed by.
+	Overrides map[string]map[string]any `yaml:"overrides"`
+}
+
+// ReadStarlarkGlobals reads the globals from the given YAML or JSON file.
+func ReadStarlarkGlobals(name string) (StarlarkGlobals, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return StarlarkGlobals{}, err
+	}
+
+	var g StarlarkGlobals
+	if err := yaml.Unmarshal(data, &g); err != nil {
+		return StarlarkGlobals{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return g, nil
+}
+
+// Merge returns the globals with the name, values and overrides of o merged over them, nested maps are merged as well.
+func (g StarlarkGlobals) Merge(o StarlarkGlobals) StarlarkGlobals {
+	merged := StarlarkGlobals{
+		Name:      g.Name,
+		Values:    mergeGlobalValues(g.Values, o.Values),
+		Overrides: map[string]map[string]any{},
+	}
+	if o.Name != "" {
+		merged.Name = o.Name
+	}
+
+	for owner, values := range g.Overrides {
+		merged.Overrides[owner] = mergeGlobalValues(values, nil)
+	}
+	for owner, values := range o.Overrides {
+		merged.Overrides[owner] = mergeGlobalValues(merged.Overrides[owner], values)
+	}
+
+	return merged
+}
+
+// mergeGlobalValues returns a copy of a with the values of b merged over it.
+func mergeGlobalValues(a, b map[string]any) map[string]any {
+	merged := maps.Clone(a)
+	if merged == nil {
+		merged = map[string]any{}
+	}
+
+	for k, v := range b {
+		am, aok := merged[k].(map[string]any)
+		bm, bok := v.(map[string]any)
+		if aok && bok {
+			merged[k] = mergeGlobalValues(am, bm)
+			continue
+		}
+
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// starlarkGlobals are the predeclared values of the globals, the default value and one per owner with overrides.
+type starlarkGlobals struct {
+	name   string
+	value  starlark.Value
+	owners map[string]starlark.Value
+}
+
+// newStarlarkGlobals converts the globals into frozen structs.
+func newStarlarkGlobals(g StarlarkGlobals) (*starlarkGlobals, error) {
+	sg := &starlarkGlobals{name: g.Name, owners: map[string]starlark.Value{}}
+	if sg.name == "" {
+		sg.name = defaultStarlarkGlobalsName
+	}
+
+	var err error
+	if sg.value, err = globalsStruct(sg.name, g.Values); err != nil {
+		return nil, err
+	}
+
+	for owner, values := range g.Overrides {
+		// the configuration does not keep the case of keys, owners are matched case insensitive
+		if sg.owners[strings.ToLower(owner)], err = globalsStruct(sg.name, mergeGlobalValues(g.Values, values)); err != nil {
+			return nil, fmt.Errorf("owner %s: %w", owner, err)
+		}
+	}
+
+	return sg, nil
+}
+
+// predeclared returns the predeclared values with the globals for the given environment.
+func (g *starlarkGlobals) predeclared(predeclared starlark.StringDict, env Environment) starlark.StringDict {
+	v, ok := g.owners[strings.ToLower(env.Repo.Owner)]
+	if !ok {
+		return predeclared
+	}
+
+	predeclared = maps.Clone(predeclared)
+	predeclared[g.name] = v
+
+	return predeclared
+}
+
+// globalsStruct converts the values into a frozen struct with the given name.
+func globalsStruct(name string, values map[string]any) (starlark.Value, error) {
+	members := starlark.StringDict{}
+	for k, v := range values {
+		node := new(yaml.Node)
+		if err := node.Encode(v); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrUnsupportedType, k, err)
+		}
+
+		value, err := YAMLToStarlark(node)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		members[k] = value
+	}
+
+	s := starlarkstruct.FromStringDict(starlark.String(name), members)
+	s.Freeze()
+
+	return s, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkGlobals(t *testing.T) {
+	globals := wccs.StarlarkGlobals{
+		Values: map[string]any{
+			"registry": "registry.example.com",
+			"images":   map[string]any{"go": "golang:1.26", "node": "node:24"},
+			"labels":   []any{"amd64", "arm64"},
+			"retries":  3,
+		},
+		Overrides: map[string]map[string]any{
+			"opencloud-eu": {"images": map[string]any{"node": "node:22"}},
+		},
+	}
+	src := `
+value = "%s %s %s %s %d" % (org.registry, org.images["go"], org.images["node"], ",".join(org.labels), org.retries)
+`
+
+	c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkGlobals(globals))
+	assert.NoError(t, err)
+
+	for owner, expected := range map[string]string{
+		"":             "registry.example.com golang:1.26 node:24 amd64,arm64 3",
+		"OpenCloud-EU": "registry.example.com golang:1.26 node:22 amd64,arm64 3",
+	} {
+		env := wccs.Environment{Repo: model.Repo{Owner: owner}}
+		values, _, err := c.Exec(t.Context(), wccs.File{Name: "main.star", Data: src}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, `"`+expected+`"`, values["value"].String())
+	}
+
+	t.Run("are frozen", func(t *testing.T) {
+		_, _, err := c.Exec(t.Context(), wccs.File{Name: "main.star", Data: `org.labels.append("s390x")`}, wccs.Environment{})
+		assert.ErrorContains(t, err, "frozen")
+	})
+
+	t.Run("use the configured name", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkGlobals(wccs.StarlarkGlobals{Name: "acme", Values: globals.Values}))
+		assert.NoError(t, err)
+
+		values, _, err := c.Exec(t.Context(), wccs.File{Name: "main.star", Data: `registry = acme.registry`}, wccs.Environment{})
+		assert.NoError(t, err)
+		assert.Equal(t, `"registry.example.com"`, values["registry"].String())
+
+		_, err = wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkGlobals(wccs.StarlarkGlobals{Name: "json", Values: globals.Values}))
+		assert.ErrorIs(t, err, wccs.ErrNameConflict)
+	})
+
+	t.Run("are read from a file and merged", func(t *testing.T) {
+		fp := filepath.Join(t.TempDir(), "globals.json")
+		assert.NoError(t, os.WriteFile(fp, []byte(`{
+  "name": "acme",
+  "values": {"registry": "registry.acme.com", "images": {"go": "golang:1.25"}},
+  "overrides": {"opencloud-eu": {"registry": "registry.opencloud.eu"}}
+}`), 0o600))
+
+		read, err := wccs.ReadStarlarkGlobals(fp)
+		assert.NoError(t, err)
+
+		merged := read.Merge(globals)
+		assert.Equal(t, "acme", merged.Name)
+		assert.Equal(t, "registry.example.com", merged.Values["registry"])
+		assert.Equal(t, map[string]any{"go": "golang:1.26", "node": "node:24"}, merged.Values["images"])
+		assert.Equal(t, map[string]any{
+			"registry": "registry.opencloud.eu",
+			"images":   map[string]any{"node": "node:22"},
+		}, merged.Overrides["opencloud-eu"])
+
+		_, err = wccs.ReadStarlarkGlobals(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
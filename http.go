@@ -19,6 +19,7 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -50,8 +51,20 @@ func ConfigurationHandler(logger *slog.Logger, converters Converters, providers
 			return
 		}
 
-		configurationFiles, err := converters.Convert(providedFiles, env)
-		if err != nil {
+		configurationFiles, err := converters.Convert(r.Context(), providedFiles, env)
+		var diagnostic *Diagnostic
+		switch {
+		// a script which exceeds its limits is a problem of the repository, not of the service
+		case errors.Is(err, ErrLimitExceeded):
+			logger.Warn(err.Error())
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		// the report is shown as pipeline error, it tells the author where the script failed
+		case errors.As(err, &diagnostic):
+			logger.Warn(err.Error(), "kind", diagnostic.Kind, "repo", env.Repo.FullName)
+			http.Error(w, diagnostic.Report(), http.StatusUnprocessableEntity)
+			return
+		case err != nil:
 			logger.Error(err.Error())
 			http.Error(w, "Failed to get convert", http.StatusInternalServerError)
 			return
@@ -72,7 +85,13 @@ func ConfigurationHandler(logger *slog.Logger, converters Converters, providers
 			configurationFiles[i] = file
 		}
 
-		if err := json.NewEncoder(w).Encode(map[string]any{"configs": configurationFiles}); err != nil {
+		response := map[string]any{"configs": configurationFiles}
+		// woodpecker ignores the warnings, they tell other clients which settings were left out
+		if warnings := Warnings(configurationFiles); len(warnings) != 0 {
+			response["warnings"] = warnings
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			logger.Error(err.Error())
 			return
 		}
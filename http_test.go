@@ -0,0 +1,69 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestConfigurationHandler(t *testing.T) {
+	root := t.TempDir()
+	provider, err := wccs.NewFSProvider(filepath.Join(root, "*.yml"), noopLogger)
+	assert.NoError(t, err)
+
+	handler := wccs.ConfigurationHandler(noopLogger, wccs.Converters{wccs.NewDroneYAMLConverter(noopLogger)}, wccs.Providers{provider})
+	serve := func(t *testing.T, data string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		assert.NoError(t, os.WriteFile(filepath.Join(root, ".drone.yml"), []byte(data), 0o600))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"repo": {"full_name": "opencloud-eu/wccs"}}`)))
+
+		return w
+	}
+
+	t.Run("returns the warnings next to the configs", func(t *testing.T) {
+		w := serve(t, "kind: pipeline\nname: build\nsteps:\n  - name: build\n    image: golang\n    user: root\n---\nkind: secret\nname: token\n")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Configs  []wccs.File `json:"configs"`
+			Warnings []string    `json:"warnings"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []wccs.File{{Name: "build", Data: "steps:\n  - name: build\n    image: golang\n"}}, response.Configs)
+		assert.Equal(t, []string{"unsupported type: step build: user is not supported", "unsupported type: document 2: kind secret is not supported"}, response.Warnings)
+	})
+
+	t.Run("leaves out empty warnings", func(t *testing.T) {
+		w := serve(t, "kind: pipeline\nname: build\nsteps: []\n")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.NotContains(t, response, "warnings")
+	})
+}
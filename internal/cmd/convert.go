@@ -16,8 +16,8 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"slices"
@@ -25,6 +25,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.starlark.net/starlark"
 
 	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
 )
@@ -40,49 +41,119 @@ type convertConfiguration struct {
 			Source string
 		}
 	}
+	// starlark converter configuration.
+	Starlark starlarkConfiguration
+	// template converter configuration.
+	Template templateConfiguration
+	// javascript converter configuration.
+	JavaScript javaScriptConfiguration
+	// jsonnet converter configuration.
+	Jsonnet jsonnetConfiguration
+	// cue converter configuration.
+	Cue cueConfiguration
 }
 
 var convertCmd = &cobra.Command{
-	Use:   "convert",
+	Use:   "convert <env>...",
 	Short: "convert configurations",
-	Args:  cobra.MatchAll(cobra.ExactArgs(1)),
+	Long:  "convert the configurations of every given environment, the outputs of multiple environments are written to a directory per environment",
+	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		envP := args[0]
-		if envP == "" {
-			log.Fatal("no env provided") //nolint: forbidigo
+		// the profile and the reports are written before the command exits
+		err := runConvert(cmd, args)
+		var diagnostic *wccs.Diagnostic
+		if errors.As(err, &diagnostic) {
+			_, _ = fmt.Fprint(os.Stderr, diagnostic.Report())
+			os.Exit(1)
 		}
+		wccs.Must(err)
+	},
+}
+
+// runConvert converts the configurations of the given environments, failures are returned to stop the profile first.
+func runConvert(cmd *cobra.Command, args []string) (err error) {
+	var providers wccs.Providers
+	if slices.Contains(cfg.Convert.Providers, wccs.ProviderTypeForge) {
+		provider, err := wccs.NewForgeProvider(logger)
+		if err != nil {
+			return err
+		}
+		providers = append(providers, provider)
+	}
+
+	if slices.Contains(cfg.Convert.Providers, wccs.ProviderTypeFS) {
+		provider, err := wccs.NewFSProvider(cfg.Convert.Provider.FS.Source, logger)
+		if err != nil {
+			return err
+		}
+		providers = append(providers, provider)
+	}
+
+	options := starlarkOptions(cfg.Convert.Starlark)
+	coverage := starlarkCoverage(cmd)
+	if coverage != nil {
+		options = append(options, wccs.WithStarlarkCoverage(coverage))
+	}
+
+	starlarkConverter, err := wccs.NewStarlarkConverter(logger, options...)
+	if err != nil {
+		return err
+	}
+
+	converters := wccs.Converters{
+		starlarkConverter,
+		wccs.NewTemplateConverter(logger, templateOptions(cfg.Convert.Template)...),
+		wccs.NewJavaScriptConverter(logger, javaScriptOptions(cfg.Convert.JavaScript)...),
+		wccs.NewJsonnetConverter(logger, jsonnetOptions(cfg.Convert.Jsonnet)...),
+		wccs.NewCueConverter(logger, cueOptions(cfg.Convert.Cue)...),
+		wccs.NewDroneYAMLConverter(logger),
+	}
 
-		var env wccs.Environment
-		wccs.Must(json.Unmarshal([]byte(
-			os.ExpandEnv(
-				string(
-					wccs.Must1(os.ReadFile(envP)),
-				),
-			),
-		), &env))
-
-		var providers wccs.Providers
-		if slices.Contains(cfg.Convert.Providers, wccs.ProviderTypeForge) {
-			providers = append(providers, wccs.Must1(wccs.NewForgeProvider(logger)))
+	if profileP := cmd.Flag("profile").Value.String(); profileP != "" {
+		profile, err := os.Create(profileP)
+		if err != nil {
+			return err
 		}
 
-		if slices.Contains(cfg.Convert.Providers, wccs.ProviderTypeFS) {
-			providers = append(providers, wccs.Must1(wccs.NewFSProvider(cfg.Convert.Provider.FS.Source, logger)))
+		if err := starlark.StartProfile(profile); err != nil {
+			return errors.Join(err, profile.Close())
+		}
+		defer func() {
+			err = errors.Join(err, starlark.StopProfile(), profile.Close())
+		}()
+	}
+
+	for _, envP := range args {
+		env, err := readEnvironment(envP)
+		if err != nil {
+			return err
 		}
 
-		converters := wccs.Converters{
-			wccs.Must1(wccs.NewStarlarkConverter(logger)),
+		providedFiles, err := providers.Get(cmd.Context(), env)
+		if err != nil {
+			return err
 		}
 
-		providedFiles := wccs.Must1(providers.Get(cmd.Context(), env))
-		configurationFiles := wccs.Must1(converters.Convert(providedFiles, env))
+		configurationFiles, err := converters.Convert(cmd.Context(), providedFiles, env)
+		if err != nil {
+			return err
+		}
+
+		for _, warning := range wccs.Warnings(configurationFiles) {
+			_, _ = fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		}
 
 		out := cmd.Flag("out")
 		var report func(f wccs.File) error
 		switch {
 		case out != nil && out.Value.String() != "":
+			dir := out.Value.String()
+			if len(args) > 1 {
+				dir = filepath.Join(dir, strings.TrimSuffix(filepath.Base(envP), filepath.Ext(envP)))
+			}
+
 			report = func(c wccs.File) error {
-				fp := filepath.Join(out.Value.String(), c.Name)
+				fp := filepath.Join(dir, c.Name)
 				if err := os.MkdirAll(filepath.Dir(fp), 0o770); err != nil { //nolint: mnd
 					return err
 				}
@@ -105,16 +176,69 @@ var convertCmd = &cobra.Command{
 			}
 		}
 		for _, f := range configurationFiles {
-			wccs.Must(report(f))
+			if err := report(f); err != nil {
+				return err
+			}
 		}
-	},
+	}
+
+	return writeCoverage(cmd, coverage)
+}
+
+// readEnvironment reads the environment JSON file at the given path, environment variables in it are expanded.
+func readEnvironment(envP string) (wccs.Environment, error) {
+	if envP == "" {
+		return wccs.Environment{}, fmt.Errorf("%w: no env provided", wccs.ErrMissingParam)
+	}
+
+	data, err := os.ReadFile(envP)
+	if err != nil {
+		return wccs.Environment{}, err
+	}
+
+	var env wccs.Environment
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(data))), &env); err != nil {
+		return wccs.Environment{}, fmt.Errorf("%s: %w", envP, err)
+	}
+
+	return env, nil
 }
 
 func init() {
 	viper.SetDefault("convert.providers", []wccs.ProviderType{wccs.ProviderTypeForge})
 	viper.SetDefault("convert.provider.fs.source", "")
+	viper.SetDefault("convert.starlark.load_root", "")
+	viper.SetDefault("convert.starlark.modules", wccs.StarlarkModules)
+	viper.SetDefault("convert.starlark.mode", wccs.StarlarkModeAuto)
+	viper.SetDefault("convert.starlark.program_cache_size", defaultStarlarkProgramCacheSize)
+	viper.SetDefault("convert.starlark.limits.max_execution_steps", defaultStarlarkMaxExecutionSteps)
+	viper.SetDefault("convert.starlark.limits.timeout", defaultStarlarkTimeout)
+	viper.SetDefault("convert.starlark.limits.max_output_size", defaultStarlarkMaxOutputSize)
+	viper.SetDefault("convert.starlark.limits.max_workflows", defaultStarlarkMaxWorkflows)
+	viper.SetDefault("convert.starlark.limits.max_file_reads", defaultStarlarkMaxFileReads)
+	viper.SetDefault("convert.starlark.remote.url", "")
+	viper.SetDefault("convert.starlark.remote.timeout", defaultStarlarkRemoteTimeout)
+	viper.SetDefault("convert.starlark.remote.max_module_size", defaultStarlarkRemoteMaxModuleSize)
+	viper.SetDefault("convert.starlark.remote.cache_dir", "")
+	viper.SetDefault("convert.template.limits.timeout", defaultTemplateTimeout)
+	viper.SetDefault("convert.template.limits.max_range", defaultTemplateMaxRange)
+	viper.SetDefault("convert.template.limits.max_output_size", defaultTemplateMaxOutputSize)
+	viper.SetDefault("convert.template.limits.max_workflows", defaultTemplateMaxWorkflows)
+	viper.SetDefault("convert.javascript.limits.timeout", defaultJavaScriptTimeout)
+	viper.SetDefault("convert.javascript.limits.max_call_stack_size", defaultJavaScriptMaxCallStackSize)
+	viper.SetDefault("convert.javascript.limits.max_output_size", defaultJavaScriptMaxOutputSize)
+	viper.SetDefault("convert.javascript.limits.max_workflows", defaultJavaScriptMaxWorkflows)
+	viper.SetDefault("convert.jsonnet.limits.timeout", defaultJsonnetTimeout)
+	viper.SetDefault("convert.jsonnet.limits.max_stack", defaultJsonnetMaxStack)
+	viper.SetDefault("convert.jsonnet.limits.max_output_size", defaultJsonnetMaxOutputSize)
+	viper.SetDefault("convert.jsonnet.limits.max_workflows", defaultJsonnetMaxWorkflows)
+	viper.SetDefault("convert.cue.limits.timeout", defaultCueTimeout)
+	viper.SetDefault("convert.cue.limits.max_output_size", defaultCueMaxOutputSize)
+	viper.SetDefault("convert.cue.limits.max_workflows", defaultCueMaxWorkflows)
 
 	convertCmd.Flags().String("out", "", "output directory path")
+	convertCmd.Flags().String("profile", "", "write a CPU profile of the starlark execution in the pprof format to the given file")
+	addCoverageFlags(convertCmd)
 
 	rootCmd.AddCommand(convertCmd)
 }
@@ -0,0 +1,95 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [pattern]...",
+	Short: "format starlark files",
+	Long:  "rewrite the starlark files matching the patterns in the canonical layout, by default all *.star files below the root",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = []string{"**/*.star"}
+		}
+
+		root := cmd.Flag("root").Value.String()
+		check := cmd.Flag("check").Value.String() == "true"
+		diff := cmd.Flag("diff").Value.String() == "true"
+
+		provider := wccs.Must1(wccs.NewFSProvider(filepath.Join(root, "**"), logger))
+		var files []wccs.File
+		for _, pattern := range args {
+			files = append(files, wccs.Must1(provider.Get(cmd.Context(), wccs.Environment{Repo: model.Repo{Config: pattern}}))...)
+		}
+
+		failed := false
+		for _, f := range files {
+			formatted, err := wccs.FormatStarlark(f.Name, f.Data)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				failed = true
+
+				continue
+			}
+
+			if formatted == f.Data {
+				continue
+			}
+
+			switch {
+			case diff:
+				wccs.Must1(fmt.Fprint(os.Stdout, wccs.Must1(difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+					A:        difflib.SplitLines(f.Data),
+					B:        difflib.SplitLines(formatted),
+					FromFile: filepath.Join("a", f.Name),
+					ToFile:   filepath.Join("b", f.Name),
+					Context:  3, //nolint: mnd
+				}))))
+				failed = true
+			case check:
+				wccs.Must1(fmt.Fprintln(os.Stdout, f.Name))
+				failed = true
+			default:
+				p := filepath.Join(root, f.Name)
+				info := wccs.Must1(os.Stat(p))
+				wccs.Must(os.WriteFile(p, []byte(formatted), info.Mode()))
+				wccs.Must1(fmt.Fprintln(os.Stdout, f.Name))
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	fmtCmd.Flags().String("root", ".", "the directory files are searched in")
+	fmtCmd.Flags().Bool("check", false, "list the files which are not formatted instead of rewriting them, exits non-zero if there are any")
+	fmtCmd.Flags().Bool("diff", false, "print the changes instead of rewriting the files, exits non-zero if there are any")
+
+	rootCmd.AddCommand(fmtCmd)
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+const (
+	// defaultJavaScriptTimeout is the default timeout of a conversion.
+	defaultJavaScriptTimeout = 10 * time.Second
+	// defaultJavaScriptMaxCallStackSize is the default depth of calls.
+	defaultJavaScriptMaxCallStackSize = 1024
+	// defaultJavaScriptMaxOutputSize is the default size of all generated workflows in bytes.
+	defaultJavaScriptMaxOutputSize = 1 << 20
+	// defaultJavaScriptMaxWorkflows is the default number of generated workflows.
+	defaultJavaScriptMaxWorkflows = 100
+)
+
+type javaScriptConfiguration struct {
+	// resource limits of a conversion, 0 disables a limit.
+	Limits struct {
+		// the timeout of a conversion.
+		Timeout time.Duration
+		// the maximal depth of calls.
+		MaxCallStackSize int `mapstructure:"max_call_stack_size"`
+		// the maximal size of all generated workflows in bytes.
+		MaxOutputSize int `mapstructure:"max_output_size"`
+		// the maximal number of generated workflows.
+		MaxWorkflows int `mapstructure:"max_workflows"`
+	}
+}
+
+// javaScriptOptions returns the converter options for the given configuration.
+func javaScriptOptions(c javaScriptConfiguration) []wccs.JavaScriptOption {
+	return []wccs.JavaScriptOption{
+		wccs.WithJavaScriptLimits(wccs.JavaScriptLimits(c.Limits)),
+	}
+}
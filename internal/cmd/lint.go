@@ -0,0 +1,68 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint <pattern>...",
+	Short: "statically check starlark files",
+	Long:  "check the starlark files matching the patterns and the local modules they load, exits non-zero if an error is found",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var write func(findings wccs.LintFindings, w io.Writer) error
+		switch format := cmd.Flag("format").Value.String(); format {
+		case "text":
+			write = wccs.LintFindings.Text
+		case "json":
+			write = wccs.LintFindings.JSON
+		case "sarif":
+			write = wccs.LintFindings.SARIF
+		default:
+			wccs.Must(fmt.Errorf("%w: format %s", wccs.ErrUnknownType, format))
+		}
+
+		provider := wccs.Must1(wccs.NewFSProvider(filepath.Join(cmd.Flag("root").Value.String(), "**"), logger))
+		var files []wccs.File
+		for _, pattern := range args {
+			files = append(files, wccs.Must1(provider.Get(cmd.Context(), wccs.Environment{Repo: model.Repo{Config: pattern}}))...)
+		}
+
+		converter := wccs.Must1(wccs.NewStarlarkConverter(logger, starlarkOptions(cfg.Convert.Starlark)...))
+		findings := converter.Lint(files...)
+		wccs.Must(write(findings, os.Stdout))
+
+		if findings.Errors() != 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	lintCmd.Flags().String("root", ".", "the directory files are searched in and local modules are resolved against")
+	lintCmd.Flags().String("format", "text", "the output format, text, json or sarif")
+
+	rootCmd.AddCommand(lintCmd)
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "manage the lock file of remote starlark modules",
+}
+
+var lockUpdateCmd = &cobra.Command{
+	Use:   "update <file>...",
+	Short: "refresh the digests of the remote modules the given starlark files load",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		root := cmd.Flag("root").Value.String()
+		source := wccs.Must1(wccs.NewFSProvider(filepath.Join(root, "*"), logger))
+
+		var repositories wccs.RepositorySource
+		switch envP := cmd.Flag("env").Value.String(); {
+		case cfg.Convert.Starlark.Remote.URL != "":
+			repositories = wccs.NewURLRepositories(cfg.Convert.Starlark.Remote.URL, cfg.Convert.Starlark.Remote.Timeout, cfg.Convert.Starlark.Remote.MaxModuleSize)
+		case envP != "":
+			var env wccs.Environment
+			wccs.Must(json.Unmarshal([]byte(os.ExpandEnv(string(wccs.Must1(os.ReadFile(envP))))), &env))
+			repositories = wccs.Must1(wccs.Must1(wccs.NewForgeProvider(logger)).Repositories(cmd.Context(), env))
+		default:
+			log.Fatal("either convert.starlark.remote.url or --env must be provided") //nolint: forbidigo
+		}
+
+		// entry files in the same directory share one lock file
+		locks := map[string]wccs.Lock{}
+		for _, fp := range args {
+			name := wccs.Must1(filepath.Rel(root, fp))
+			file := wccs.Must1(source.Source().Read(filepath.ToSlash(name)))
+			lock := wccs.Must1(wccs.LockStarlark(cmd.Context(), file, cfg.Convert.Starlark.LoadRoot, repositories))
+
+			lockP := filepath.Join(filepath.Dir(fp), wccs.LockFileName)
+			if locks[lockP] == nil {
+				locks[lockP] = wccs.Lock{}
+			}
+			maps.Copy(locks[lockP], lock)
+		}
+
+		for lockP, lock := range locks {
+			wccs.Must(os.WriteFile(lockP, []byte(lock.String()), 0o640)) //nolint: mnd
+			wccs.Must1(fmt.Fprintf(os.Stdout, "updated %s with %d modules\n", lockP, len(lock)))
+		}
+	},
+}
+
+func init() {
+	lockUpdateCmd.Flags().String("root", ".", "the directory local modules are resolved against")
+	lockUpdateCmd.Flags().String("env", "", "environment file with the forge credentials, used if no remote url is configured")
+
+	lockCmd.AddCommand(lockUpdateCmd)
+	rootCmd.AddCommand(lockCmd)
+}
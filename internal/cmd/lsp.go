@@ -0,0 +1,44 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "run a language server for starlark files",
+	Long:  "speak the language server protocol over stdin and stdout, diagnostics are the findings of the linter",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		// stdout belongs to the protocol
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+			Level: cfg.LogLevel,
+		}))
+
+		converter := wccs.Must1(wccs.NewStarlarkConverter(logger, starlarkOptions(cfg.Convert.Starlark)...))
+		wccs.Must(wccs.NewLanguageServer(converter, logger).Serve(cmd.Context(), os.Stdin, os.Stdout))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
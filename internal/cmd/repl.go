@@ -0,0 +1,145 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+	"go.starlark.net/starlark"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+var replCmd = &cobra.Command{
+	Use:   "repl <env> <file>",
+	Short: "evaluate starlark interactively",
+	Long:  "execute the starlark file with the context of the environment and evaluate input with its globals, ctx and main, values are printed as yaml",
+	Args:  cobra.ExactArgs(2), //nolint: mnd
+	Run: func(cmd *cobra.Command, args []string) {
+		env := wccs.Must1(readEnvironment(args[0]))
+
+		// the file is opened as it is named, local modules are resolved against the root
+		provider := wccs.Must1(wccs.NewFSProvider(filepath.Join(cmd.Flag("root").Value.String(), "**"), logger))
+		file := wccs.Must1(provider.Source().Read(filepath.ToSlash(args[1])))
+
+		converter := wccs.Must1(wccs.NewStarlarkConverter(logger, starlarkOptions(cfg.Convert.Starlark)...))
+		repl, err := converter.REPL(cmd.Context(), file, env, os.Stdout)
+		if err != nil {
+			replError(err)
+			os.Exit(1)
+		}
+
+		history := cmd.Flag("history").Value.String()
+		if history != "" {
+			wccs.Must(os.MkdirAll(filepath.Dir(history), 0o770)) //nolint: mnd
+		}
+
+		rl := wccs.Must1(readline.NewEx(&readline.Config{
+			HistoryFile:     history,
+			InterruptPrompt: "^C",
+			AutoComplete:    replCompleter{repl},
+		}))
+		defer func() {
+			_ = rl.Close()
+		}()
+
+		for {
+			rl.SetPrompt(">>> ")
+
+			// an interrupt cancels the running evaluation, while reading it cancels the input
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			v, err := repl.Eval(ctx, func() ([]byte, error) {
+				line, err := rl.Readline()
+				rl.SetPrompt("... ")
+				if err != nil {
+					return nil, err
+				}
+
+				return []byte(line + "\n"), nil
+			})
+			stop()
+
+			switch {
+			case errors.Is(err, io.EOF):
+				return
+			case errors.Is(err, readline.ErrInterrupt):
+				continue
+			case err != nil:
+				replError(err)
+			case v != starlark.None:
+				wccs.Must1(fmt.Fprint(os.Stdout, repl.Format(v)))
+			}
+		}
+	},
+}
+
+// replError prints the report of the given error.
+func replError(err error) {
+	var diagnostic *wccs.Diagnostic
+	if errors.As(err, &diagnostic) {
+		_, _ = fmt.Fprint(os.Stderr, diagnostic.Report())
+		return
+	}
+
+	_, _ = fmt.Fprintln(os.Stderr, err)
+}
+
+// replCompleter completes the names of the globals.
+type replCompleter struct {
+	repl *wccs.StarlarkREPL
+}
+
+func (c replCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && (line[start-1] == '_' || unicode.IsLetter(line[start-1]) || unicode.IsDigit(line[start-1])) {
+		start--
+	}
+
+	// attributes are not completed
+	if start > 0 && line[start-1] == '.' {
+		return nil, 0
+	}
+
+	prefix := string(line[start:pos])
+	var candidates [][]rune
+	for _, name := range c.repl.Names() {
+		if name != prefix && strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, []rune(strings.TrimPrefix(name, prefix)))
+		}
+	}
+
+	return candidates, pos - start
+}
+
+func init() {
+	var history string
+	if dir, err := os.UserCacheDir(); err == nil {
+		history = filepath.Join(dir, "wccs", "repl_history")
+	}
+
+	replCmd.Flags().String("root", ".", "the directory the file is read from and local modules are resolved against")
+	replCmd.Flags().String("history", history, "the file the input history is kept in, empty disables the history")
+
+	rootCmd.AddCommand(replCmd)
+}
@@ -17,6 +17,7 @@ package cmd
 import (
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/justinas/alice"
 	"github.com/spf13/cobra"
@@ -25,6 +26,9 @@ import (
 	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
 )
 
+// defaultProfileThreshold is the default duration a conversion must take to be profiled.
+const defaultProfileThreshold = time.Second
+
 type serverConfiguration struct {
 	// which host to listen on.
 	Address string
@@ -44,12 +48,30 @@ type serverConfiguration struct {
 			Source string
 		}
 	}
+	// starlark converter configuration.
+	Starlark starlarkConfiguration
+	// template converter configuration.
+	Template templateConfiguration
+	// javascript converter configuration.
+	JavaScript javaScriptConfiguration
+	// jsonnet converter configuration.
+	Jsonnet jsonnetConfiguration
+	// cue converter configuration.
+	Cue cueConfiguration
+	// debug configuration.
+	Debug struct {
+		// the directory cpu profiles of slow starlark conversions are written to, profiling is disabled if empty.
+		// the profiler is process wide, starlark conversions are serialized while it is enabled.
+		ProfileDir string `mapstructure:"profile_dir"`
+		// the duration a conversion must take for its profile to be kept.
+		ProfileThreshold time.Duration `mapstructure:"profile_threshold"`
+	}
 }
 
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "start the configuration server",
-	Run: func(_ *cobra.Command, _ []string) {
+	Run: func(cmd *cobra.Command, _ []string) {
 		middlewares := []alice.Constructor{
 			wccs.Must1(wccs.AllowedMethodsMiddlewareFactory(cfg.Server.ConfigEndpointMethods...)),
 		}
@@ -63,8 +85,19 @@ var serverCmd = &cobra.Command{
 			providers = append(providers, wccs.Must1(wccs.NewFSProvider(cfg.Server.Provider.FS.Source, logger)))
 		}
 
+		options := starlarkOptions(cfg.Server.Starlark)
+		if cfg.Server.Debug.ProfileDir != "" {
+			logger.Warn("starlark profiling is enabled, conversions are no longer executed in parallel")
+			options = append(options, wccs.WithStarlarkProfiler(wccs.Must1(wccs.NewStarlarkProfiler(cfg.Server.Debug.ProfileDir, cfg.Server.Debug.ProfileThreshold, logger))))
+		}
+
 		converters := wccs.Converters{
-			wccs.Must1(wccs.NewStarlarkConverter(logger)),
+			wccs.Must1(wccs.NewStarlarkConverter(logger, options...)),
+			wccs.NewTemplateConverter(logger, templateOptions(cfg.Server.Template)...),
+			wccs.NewJavaScriptConverter(logger, javaScriptOptions(cfg.Server.JavaScript)...),
+			wccs.NewJsonnetConverter(logger, jsonnetOptions(cfg.Server.Jsonnet)...),
+			wccs.NewCueConverter(logger, cueOptions(cfg.Server.Cue)...),
+			wccs.NewDroneYAMLConverter(logger),
 		}
 
 		switch cfg.Server.PublicKey {
@@ -88,6 +121,39 @@ func init() {
 	viper.SetDefault("server.config_endpoint_methods", []string{http.MethodPost})
 	viper.SetDefault("server.providers", []wccs.ProviderType{wccs.ProviderTypeForge})
 	viper.SetDefault("server.provider.fs.source", "")
+	viper.SetDefault("server.starlark.load_root", "")
+	viper.SetDefault("server.starlark.modules", wccs.StarlarkModules)
+	viper.SetDefault("server.starlark.mode", wccs.StarlarkModeAuto)
+	viper.SetDefault("server.starlark.program_cache_size", defaultStarlarkProgramCacheSize)
+	viper.SetDefault("server.starlark.limits.max_execution_steps", defaultStarlarkMaxExecutionSteps)
+	viper.SetDefault("server.starlark.limits.timeout", defaultStarlarkTimeout)
+	viper.SetDefault("server.starlark.limits.max_output_size", defaultStarlarkMaxOutputSize)
+	viper.SetDefault("server.starlark.limits.max_workflows", defaultStarlarkMaxWorkflows)
+	viper.SetDefault("server.starlark.limits.max_file_reads", defaultStarlarkMaxFileReads)
+	viper.SetDefault("server.starlark.remote.url", "")
+	viper.SetDefault("server.starlark.remote.timeout", defaultStarlarkRemoteTimeout)
+	viper.SetDefault("server.starlark.remote.max_module_size", defaultStarlarkRemoteMaxModuleSize)
+	viper.SetDefault("server.starlark.remote.cache_dir", "")
+	viper.SetDefault("server.template.limits.timeout", defaultTemplateTimeout)
+	viper.SetDefault("server.template.limits.max_range", defaultTemplateMaxRange)
+	viper.SetDefault("server.template.limits.max_output_size", defaultTemplateMaxOutputSize)
+	viper.SetDefault("server.template.limits.max_workflows", defaultTemplateMaxWorkflows)
+	viper.SetDefault("server.javascript.limits.timeout", defaultJavaScriptTimeout)
+	viper.SetDefault("server.javascript.limits.max_call_stack_size", defaultJavaScriptMaxCallStackSize)
+	viper.SetDefault("server.javascript.limits.max_output_size", defaultJavaScriptMaxOutputSize)
+	viper.SetDefault("server.javascript.limits.max_workflows", defaultJavaScriptMaxWorkflows)
+	viper.SetDefault("server.jsonnet.limits.timeout", defaultJsonnetTimeout)
+	viper.SetDefault("server.jsonnet.limits.max_stack", defaultJsonnetMaxStack)
+	viper.SetDefault("server.jsonnet.limits.max_output_size", defaultJsonnetMaxOutputSize)
+	viper.SetDefault("server.jsonnet.limits.max_workflows", defaultJsonnetMaxWorkflows)
+	viper.SetDefault("server.cue.limits.timeout", defaultCueTimeout)
+	viper.SetDefault("server.cue.limits.max_output_size", defaultCueMaxOutputSize)
+	viper.SetDefault("server.cue.limits.max_workflows", defaultCueMaxWorkflows)
+	viper.SetDefault("server.debug.profile_dir", "")
+	viper.SetDefault("server.debug.profile_threshold", defaultProfileThreshold)
+
+	serverCmd.Flags().String("profile-dir", "", "write cpu profiles of slow starlark conversions to the given directory, conversions are serialized")
+	wccs.Must(viper.BindPFlag("server.debug.profile_dir", serverCmd.Flags().Lookup("profile-dir")))
 
 	rootCmd.AddCommand(serverCmd)
 }
@@ -0,0 +1,166 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+const (
+	// defaultStarlarkMaxExecutionSteps is the default number of steps all modules of a conversion may execute.
+	defaultStarlarkMaxExecutionSteps = 10_000_000
+	// defaultStarlarkTimeout is the default timeout of a conversion.
+	defaultStarlarkTimeout = 10 * time.Second
+	// defaultStarlarkMaxOutputSize is the default size of all generated workflows in bytes.
+	defaultStarlarkMaxOutputSize = 1 << 20
+	// defaultStarlarkMaxWorkflows is the default number of generated workflows.
+	defaultStarlarkMaxWorkflows = 100
+	// defaultStarlarkMaxFileReads is the default number of files and directories the files module may fetch.
+	defaultStarlarkMaxFileReads = 50
+	// defaultStarlarkProgramCacheSize is the default number of cached compiled programs.
+	defaultStarlarkProgramCacheSize = 256
+	// defaultStarlarkRemoteTimeout is the default timeout of a remote module fetch.
+	defaultStarlarkRemoteTimeout = 10 * time.Second
+	// defaultStarlarkRemoteMaxModuleSize is the default size of a remote module in bytes.
+	defaultStarlarkRemoteMaxModuleSize = 1 << 20
+)
+
+type starlarkConfiguration struct {
+	// the directory load statements are resolved against.
+	LoadRoot string `mapstructure:"load_root"`
+	// the predeclared modules which are available to scripts.
+	Modules []wccs.StarlarkModule
+	// the mode which selects the context and the returned documents.
+	Mode wccs.StarlarkMode
+	// the number of compiled programs which are cached, caching is disabled if 0.
+	ProgramCacheSize int `mapstructure:"program_cache_size"`
+	// resource limits of a conversion, 0 disables a limit.
+	Limits struct {
+		// the maximal number of steps all modules of a conversion may execute.
+		MaxExecutionSteps uint64 `mapstructure:"max_execution_steps"`
+		// the timeout of a conversion.
+		Timeout time.Duration
+		// the maximal size of all generated workflows in bytes.
+		MaxOutputSize int `mapstructure:"max_output_size"`
+		// the maximal number of generated workflows.
+		MaxWorkflows int `mapstructure:"max_workflows"`
+		// the maximal number of files and directories the files module may fetch.
+		MaxFileReads int `mapstructure:"max_file_reads"`
+	}
+	// remote module configuration.
+	Remote struct {
+		// the url template remote modules are fetched from, the forge is used if empty.
+		URL string
+		// the timeout of a fetch from the url template.
+		Timeout time.Duration
+		// the maximal size of a module fetched from the url template in bytes.
+		MaxModuleSize int `mapstructure:"max_module_size"`
+		// the directory fetched remote modules are cached in, caching is disabled if empty.
+		CacheDir string `mapstructure:"cache_dir"`
+	}
+	// operator defined values which are predeclared as a struct.
+	Globals struct {
+		// the name of the predeclared struct, org if empty.
+		Name string
+		// a yaml or json file the globals are read from, the values of the configuration are merged over it.
+		File string
+		// the fields of the struct.
+		Values map[string]any
+		// values by repository owner which are merged over the fields.
+		Overrides map[string]map[string]any
+	}
+}
+
+// starlarkOptions returns the converter options for the given configuration.
+func starlarkOptions(c starlarkConfiguration) []wccs.StarlarkOption {
+	options := []wccs.StarlarkOption{
+		wccs.WithStarlarkLoadRoot(c.LoadRoot),
+		wccs.WithStarlarkModules(c.Modules...),
+		wccs.WithStarlarkMode(c.Mode),
+		wccs.WithStarlarkLimits(wccs.StarlarkLimits(c.Limits)),
+	}
+
+	if c.ProgramCacheSize != 0 {
+		options = append(options, wccs.WithStarlarkProgramCache(wccs.Must1(wccs.NewProgramCache(c.ProgramCacheSize))))
+	}
+
+	if c.Remote.URL != "" {
+		options = append(options, wccs.WithStarlarkRepositories(wccs.NewURLRepositories(c.Remote.URL, c.Remote.Timeout, c.Remote.MaxModuleSize)))
+	}
+
+	if c.Remote.CacheDir != "" {
+		options = append(options, wccs.WithStarlarkModuleCache(wccs.Must1(wccs.NewModuleCache(c.Remote.CacheDir))))
+	}
+
+	globals := wccs.StarlarkGlobals{Name: c.Globals.Name, Values: c.Globals.Values, Overrides: c.Globals.Overrides}
+	if c.Globals.File != "" {
+		globals = wccs.Must1(wccs.ReadStarlarkGlobals(c.Globals.File)).Merge(globals)
+	}
+
+	if len(globals.Values) != 0 || len(globals.Overrides) != 0 {
+		options = append(options, wccs.WithStarlarkGlobals(globals))
+	}
+
+	return options
+}
+
+// addCoverageFlags adds the flags which request coverage reports of the executed starlark files.
+func addCoverageFlags(cmd *cobra.Command) {
+	cmd.Flags().String("coverage", "", "write an LCOV coverage report of the executed starlark files to the given file")
+	cmd.Flags().String("coverage-html", "", "write an HTML coverage report of the executed starlark files to the given file")
+}
+
+// starlarkCoverage returns the coverage the executions are recorded in, nil if no report is requested.
+func starlarkCoverage(cmd *cobra.Command) *wccs.Coverage {
+	if cmd.Flag("coverage").Value.String() == "" && cmd.Flag("coverage-html").Value.String() == "" {
+		return nil
+	}
+
+	return wccs.NewCoverage()
+}
+
+// writeCoverage writes the requested coverage reports.
+func writeCoverage(cmd *cobra.Command, coverage *wccs.Coverage) error {
+	if coverage == nil {
+		return nil
+	}
+
+	for flag, write := range map[string]func(f *os.File) error{
+		"coverage":      func(f *os.File) error { return coverage.LCOV(f) },
+		"coverage-html": func(f *os.File) error { return coverage.HTML(f) },
+	} {
+		p := cmd.Flag(flag).Value.String()
+		if p == "" {
+			continue
+		}
+
+		f, err := os.Create(p)
+		if err != nil {
+			return err
+		}
+
+		if err := errors.Join(write(f), f.Close()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+const (
+	// defaultTemplateTimeout is the default timeout of a conversion.
+	defaultTemplateTimeout = 10 * time.Second
+	// defaultTemplateMaxRange is the default integer a template may range over.
+	defaultTemplateMaxRange = 10_000
+	// defaultTemplateMaxOutputSize is the default size of the rendered output in bytes.
+	defaultTemplateMaxOutputSize = 1 << 20
+	// defaultTemplateMaxWorkflows is the default number of generated workflows.
+	defaultTemplateMaxWorkflows = 100
+)
+
+type templateConfiguration struct {
+	// resource limits of a conversion, 0 disables a limit.
+	Limits struct {
+		// the timeout of a conversion.
+		Timeout time.Duration
+		// the maximal integer a template may range over.
+		MaxRange int `mapstructure:"max_range"`
+		// the maximal size of the rendered output in bytes.
+		MaxOutputSize int `mapstructure:"max_output_size"`
+		// the maximal number of generated workflows.
+		MaxWorkflows int `mapstructure:"max_workflows"`
+	}
+}
+
+// templateOptions returns the converter options for the given configuration.
+func templateOptions(c templateConfiguration) []wccs.TemplateOption {
+	return []wccs.TemplateOption{
+		wccs.WithTemplateLimits(wccs.TemplateLimits(c.Limits)),
+	}
+}
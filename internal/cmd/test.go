@@ -0,0 +1,111 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test [pattern]",
+	Short: "run the test_ functions of starlark test files",
+	Long:  "run the test_ functions of all starlark files matching the pattern, by default all *" + wccs.StarlarkTestFileSuffix + " files below the root",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pattern := "**/*" + wccs.StarlarkTestFileSuffix
+		if len(args) != 0 {
+			pattern = args[0]
+		}
+
+		provider := wccs.Must1(wccs.NewFSProvider(filepath.Join(cmd.Flag("root").Value.String(), "**"), logger))
+		files := wccs.Must1(provider.Get(cmd.Context(), wccs.Environment{Repo: model.Repo{Config: pattern}}))
+		slices.SortFunc(files, func(a, b wccs.File) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+
+		options := starlarkOptions(cfg.Convert.Starlark)
+		coverage := starlarkCoverage(cmd)
+		if coverage != nil {
+			options = append(options, wccs.WithStarlarkCoverage(coverage))
+		}
+		converter := wccs.Must1(wccs.NewStarlarkConverter(logger, options...))
+
+		var results wccs.StarlarkTestResults
+		for _, f := range files {
+			fileResults, err := converter.Test(cmd.Context(), f)
+			if err != nil {
+				// a test file which cannot be executed is reported as a single failed test
+				failure := err.Error()
+				var diagnostic *wccs.Diagnostic
+				if errors.As(err, &diagnostic) {
+					failure = diagnostic.Report()
+				}
+
+				fileResults = wccs.StarlarkTestResults{{File: f.Name, Name: f.Name, Failure: failure}}
+			}
+
+			for _, result := range fileResults {
+				status := "PASS"
+				if result.Failure != "" {
+					status = "FAIL"
+				}
+
+				wccs.Must1(fmt.Fprintf(os.Stdout, "--- %s: %s %s (%.2fs)\n", status, result.File, result.Name, result.Duration.Seconds()))
+				if result.Failure != "" {
+					wccs.Must1(fmt.Fprintf(os.Stdout, "    %s\n", strings.ReplaceAll(strings.TrimSpace(result.Failure), "\n", "\n    ")))
+				}
+			}
+
+			results = append(results, fileResults...)
+		}
+
+		if junitP := cmd.Flag("junit").Value.String(); junitP != "" {
+			junit := wccs.Must1(os.Create(junitP))
+			wccs.Must(results.JUnit(junit))
+			wccs.Must(junit.Close())
+		}
+
+		wccs.Must(writeCoverage(cmd, coverage))
+
+		failed := results.Failed()
+		wccs.Must1(fmt.Fprintf(os.Stdout, "%d passed, %d failed\n", len(results)-failed, failed))
+		if coverage != nil {
+			if covered, total := coverage.Lines(); total != 0 {
+				wccs.Must1(fmt.Fprintf(os.Stdout, "coverage: %.1f%% of %d lines\n", 100*float64(covered)/float64(total), total)) //nolint: mnd
+			}
+		}
+		if failed != 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	testCmd.Flags().String("root", ".", "the directory test files are searched in and local modules are resolved against")
+	testCmd.Flags().String("junit", "", "write a JUnit XML report to the given file")
+	addCoverageFlags(testCmd)
+
+	rootCmd.AddCommand(testCmd)
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+var typesCmd = &cobra.Command{
+	Use:   "types",
+	Short: "print the typescript declarations of javascript and typescript files",
+	Long:  "print the declarations of the context main receives and the values it returns, e.g. wccs types > wccs.d.ts",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		wccs.Must1(fmt.Fprint(os.Stdout, wccs.JavaScriptDeclarations()))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(typesCmd)
+}
@@ -0,0 +1,507 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/big"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+	"go.starlark.net/starlark"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+)
+
+// javaScriptExtensions are the extensions of the files the JavaScriptConverter runs.
+var javaScriptExtensions = []string{".js", ".ts"}
+
+// javaScriptMaxCallStackSize restricts the depth of calls if no limit is configured,
+// deeper recursions fail instead of exhausting the memory.
+const javaScriptMaxCallStackSize = 1024
+
+// javaScriptStackPattern matches a frame of the stack property of errors, e.g. at main (build.ts:3:12(8)).
+var javaScriptStackPattern = regexp.MustCompile(`^\s*at (?:(.+) \()?(.+):(\d+):(\d+)\(\d+\)\)?$`)
+
+// JavaScriptLimits restricts the resources a conversion may use, a zero value disables the limit.
+type JavaScriptLimits struct {
+	// Timeout of the whole conversion.
+	Timeout time.Duration
+	// MaxCallStackSize is the maximal depth of calls, 1024 calls are allowed if 0.
+	MaxCallStackSize int
+	// MaxOutputSize is the maximal size of all generated workflows in bytes.
+	MaxOutputSize int
+	// MaxWorkflows is the maximal number of generated workflows.
+	MaxWorkflows int
+}
+
+// JavaScriptConverter runs JavaScript and TypeScript files in an embedded engine, the workflows are built by main.
+// TypeScript is not type checked, the type syntax is removed before the script runs.
+type JavaScriptConverter struct {
+	logger *slog.Logger
+	limits JavaScriptLimits
+}
+
+// JavaScriptOption configures the JavaScriptConverter.
+type JavaScriptOption func(*JavaScriptConverter)
+
+// WithJavaScriptLimits restricts the resources a conversion may use, conversions are unlimited by default
+// except for the depth of calls.
+func WithJavaScriptLimits(limits JavaScriptLimits) JavaScriptOption {
+	return func(c *JavaScriptConverter) {
+		c.limits = limits
+	}
+}
+
+// NewJavaScriptConverter returns a new JavaScriptConverter.
+func NewJavaScriptConverter(logger *slog.Logger, options ...JavaScriptOption) JavaScriptConverter {
+	c := JavaScriptConverter{logger: logger}
+	for _, option := range options {
+		option(&c)
+	}
+
+	return c
+}
+
+func (c JavaScriptConverter) Compatible(f File) bool {
+	// declaration files only contain types
+	return slices.Contains(javaScriptExtensions, path.Ext(f.Name)) && !strings.HasSuffix(f.Name, ".d.ts")
+}
+
+// Convert runs the script and calls the exported main function with the context of the pipeline,
+// main returns the same values as the main function of Starlark scripts.
+func (c JavaScriptConverter) Convert(ctx context.Context, f File, env Environment) ([]File, error) {
+	if f.Data == "" {
+		return nil, ErrNoContent
+	}
+
+	if c.limits.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, c.limits.Timeout, fmt.Errorf("%w: timeout %s", ErrLimitExceeded, c.limits.Timeout))
+		defer cancel()
+	}
+
+	src, err := stripScript(f.Data, path.Ext(f.Name) == ".ts")
+	if err != nil {
+		return nil, javaScriptDiagnostic(err, f)
+	}
+
+	ast, err := parser.ParseFile(nil, f.Name, src, 0)
+	if err != nil {
+		return nil, javaScriptDiagnostic(err, f)
+	}
+
+	program, err := goja.CompileAST(ast, true)
+	if err != nil {
+		return nil, javaScriptDiagnostic(err, f)
+	}
+
+	vm, stop := c.newRuntime(ctx, env)
+	defer stop()
+	if _, err := vm.RunProgram(program); err != nil {
+		return nil, fmt.Errorf("%w: error executing file", javaScriptLimitError(ctx, javaScriptDiagnostic(err, f), c.maxCallStackSize()))
+	}
+
+	entrypoint, ok := goja.AssertFunction(vm.Get("main"))
+	if !ok {
+		return nil, fmt.Errorf("%w: main", ErrNoEntrypoint)
+	}
+
+	v, err := entrypoint(goja.Undefined(), javaScriptValue(vm, contextData(env)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: error building conf", javaScriptLimitError(ctx, javaScriptDiagnostic(err, f), c.maxCallStackSize()))
+	}
+
+	// the jobs of promises run before the call returns, an async main is settled unless it waits for itself
+	if promise, ok := v.Export().(*goja.Promise); ok {
+		switch promise.State() {
+		case goja.PromiseStateFulfilled:
+			v = promise.Result()
+		case goja.PromiseStateRejected:
+			return nil, fmt.Errorf("%w: error building conf", javaScriptRejection(promise.Result(), f))
+		default:
+			return nil, fmt.Errorf("%w: main returned a promise which never settles", ErrUnsupportedType)
+		}
+	}
+
+	result, err := javaScriptToStarlark(v, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := starlarkFiles(result, f.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, checkOutput(files, c.limits.MaxWorkflows, c.limits.MaxOutputSize)
+}
+
+// newRuntime returns a runtime for the given environment, it has no access to the filesystem or the network.
+// The returned stop function unregisters the interruption of the runtime, it is called once the conversion returns.
+func (c JavaScriptConverter) newRuntime(ctx context.Context, env Environment) (*goja.Runtime, func() bool) {
+	vm := goja.New()
+	vm.SetMaxCallStackSize(c.maxCallStackSize())
+
+	// Date returns the pipeline timestamp, this keeps conversions reproducible
+	vm.SetTimeSource(func() time.Time {
+		return pipelineTime(env)
+	})
+
+	// the runtime is interrupted as soon as the conversion times out or the request is gone
+	stop := context.AfterFunc(ctx, func() {
+		vm.Interrupt(context.Cause(ctx))
+	})
+
+	console := vm.NewObject()
+	for _, name := range []string{"log", "info", "warn", "error", "debug"} {
+		_ = console.Set(name, func(call goja.FunctionCall) goja.Value {
+			args := make([]string, 0, len(call.Arguments))
+			for _, arg := range call.Arguments {
+				args = append(args, arg.String())
+			}
+			c.logger.Debug(strings.Join(args, " "))
+
+			return goja.Undefined()
+		})
+	}
+	_ = vm.Set("console", console)
+
+	return vm, stop
+}
+
+// maxCallStackSize returns the configured depth of calls or javaScriptMaxCallStackSize.
+func (c JavaScriptConverter) maxCallStackSize() int {
+	if c.limits.MaxCallStackSize != 0 {
+		return c.limits.MaxCallStackSize
+	}
+
+	return javaScriptMaxCallStackSize
+}
+
+// javaScriptLimitError returns a distinct error if the given error was caused by an exceeded limit or a canceled context.
+func javaScriptLimitError(ctx context.Context, err error, maxCallStackSize int) error {
+	// an interrupted runtime already reports the cause
+	if ctx.Err() != nil && !errors.Is(err, context.Cause(ctx)) {
+		return fmt.Errorf("%w: %w", context.Cause(ctx), err)
+	}
+
+	var stackOverflow *goja.StackOverflowError
+	if errors.As(err, &stackOverflow) {
+		return fmt.Errorf("%w: max call stack size %d: %w", ErrLimitExceeded, maxCallStackSize, err)
+	}
+
+	return err
+}
+
+// javaScriptValue converts plain Go values into JavaScript values, maps become objects with sorted keys.
+func javaScriptValue(vm *goja.Runtime, v any) goja.Value {
+	switch v := v.(type) {
+	case []any:
+		values := make([]any, 0, len(v))
+		for _, e := range v {
+			values = append(values, javaScriptValue(vm, e))
+		}
+
+		return vm.NewArray(values...)
+	case map[string]any:
+		obj := vm.NewObject()
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			_ = obj.Set(k, javaScriptValue(vm, v[k]))
+		}
+
+		return obj
+	default:
+		return vm.ToValue(v)
+	}
+}
+
+// javaScriptToStarlark converts the value returned by main into a Starlark value, objects keep the order of their keys.
+// Properties which are undefined are skipped like JSON.stringify does, the parents are used to detect cycles.
+func javaScriptToStarlark(v goja.Value, parents []*goja.Object) (starlark.Value, error) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return starlark.None, nil
+	}
+
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		switch e := v.Export().(type) {
+		case bool:
+			return starlark.Bool(e), nil
+		case int64:
+			return starlark.MakeInt64(e), nil
+		case float64:
+			return starlark.Float(e), nil
+		case string:
+			return starlark.String(e), nil
+		case *big.Int:
+			return starlark.MakeBigInt(e), nil
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, v.ExportType())
+		}
+	}
+
+	if slices.Contains(parents, obj) {
+		return nil, fmt.Errorf("%w: cyclic object", ErrUnsupportedType)
+	}
+	parents = append(parents, obj)
+
+	switch class := obj.ClassName(); class {
+	case "Array":
+		length := obj.Get("length").ToInteger()
+		list := make([]starlark.Value, 0, length)
+		for i := range length {
+			e, err := javaScriptToStarlark(obj.Get(strconv.FormatInt(i, 10)), parents)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			list = append(list, e)
+		}
+
+		return starlark.NewList(list), nil
+	case "Object":
+		keys := obj.Keys()
+		dict := starlark.NewDict(len(keys))
+		for _, k := range keys {
+			value := obj.Get(k)
+			if goja.IsUndefined(value) {
+				continue
+			}
+
+			e, err := javaScriptToStarlark(value, parents)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			_ = dict.SetKey(starlark.String(k), e)
+		}
+
+		return dict, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, class)
+	}
+}
+
+// javaScriptDiagnostic turns syntax errors and exceptions into a Diagnostic, other errors are returned unchanged.
+// Errors thrown with the plain Error constructor are intended by the author and reported like fail in Starlark.
+func javaScriptDiagnostic(err error, f File) error {
+	var d *Diagnostic
+
+	var scriptErr *scriptError
+	var parserErr parser.ErrorList
+	var compilerErr *goja.CompilerSyntaxError
+	var exception *goja.Exception
+	var interrupted *goja.InterruptedError
+	switch {
+	case errors.As(err, &scriptErr):
+		line, column := javaScriptPosition(f.Data, scriptErr.offset)
+		d = &Diagnostic{Kind: DiagnosticKindSyntax, File: f.Name, Line: line, Column: column, Message: scriptErr.message}
+	case errors.As(err, &parserErr) && len(parserErr) != 0:
+		pos := parserErr[0].Position
+		d = &Diagnostic{Kind: DiagnosticKindSyntax, File: f.Name, Line: int32(pos.Line), Column: int32(pos.Column), Message: parserErr[0].Message}
+	case errors.As(err, &compilerErr):
+		pos := compilerErr.File.Position(compilerErr.Offset)
+		d = &Diagnostic{Kind: DiagnosticKindSyntax, File: f.Name, Line: int32(pos.Line), Column: int32(pos.Column), Message: compilerErr.Message}
+	case errors.As(err, &interrupted):
+		// the runtime is interrupted with the cause of the context, the Diagnostic unwraps to it
+		d = &Diagnostic{Kind: DiagnosticKindRuntime, Message: fmt.Sprint(interrupted.Value())}
+		javaScriptBacktrace(d, javaScriptFrames(interrupted.Stack()))
+	case errors.As(err, &exception):
+		d = javaScriptException(exception.Value(), javaScriptFrames(exception.Stack()))
+	default:
+		return err
+	}
+
+	d.err = err
+
+	return javaScriptSource(d, f)
+}
+
+// javaScriptRejection turns the reason of a rejected promise into a Diagnostic,
+// the backtrace is read from the stack property of errors as the promise does not keep the exception.
+func javaScriptRejection(reason goja.Value, f File) error {
+	var backtrace []DiagnosticFrame
+	if obj, ok := reason.(*goja.Object); ok && obj.ClassName() == "Error" {
+		for _, line := range strings.Split(obj.Get("stack").String(), "\n") {
+			match := javaScriptStackPattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+
+			frame := DiagnosticFrame{Function: match[1], File: match[2]}
+			if frame.Function == "" {
+				frame.Function = "<anonymous>"
+			}
+			line, _ := strconv.Atoi(match[3])
+			column, _ := strconv.Atoi(match[4])
+			frame.Line, frame.Column = int32(line), int32(column)
+			backtrace = append(backtrace, frame)
+		}
+	}
+
+	d := javaScriptException(reason, backtrace)
+	d.err = errors.New(reason.String())
+
+	return javaScriptSource(d, f)
+}
+
+// javaScriptSource adds the failing line of the file to the Diagnostic.
+func javaScriptSource(d *Diagnostic, f File) *Diagnostic {
+	// the stripped TypeScript syntax is replaced by spaces, positions in the script and the file are the same
+	d.Column = max(d.Column, 1)
+	if lines := strings.Split(f.Data, "\n"); d.File == f.Name && d.Line > 0 && int(d.Line) <= len(lines) {
+		d.Source = strings.TrimRight(lines[d.Line-1], "\r")
+	}
+
+	return d
+}
+
+// javaScriptException builds the Diagnostic of the thrown value, the backtrace lists the innermost call first.
+func javaScriptException(thrown goja.Value, backtrace []DiagnosticFrame) *Diagnostic {
+	d := &Diagnostic{Kind: DiagnosticKindFail, Message: thrown.String()}
+	if obj, ok := thrown.(*goja.Object); ok && obj.ClassName() == "Error" {
+		name, message := obj.Get("name").String(), obj.Get("message").String()
+		d.Message = message
+		if name != "Error" {
+			d.Kind = DiagnosticKindRuntime
+			d.Message = name + ": " + message
+		}
+	}
+
+	javaScriptBacktrace(d, backtrace)
+
+	return d
+}
+
+// javaScriptFrames converts the stack of an exception, the innermost call comes first.
+func javaScriptFrames(stack []goja.StackFrame) []DiagnosticFrame {
+	var frames []DiagnosticFrame
+	for _, frame := range stack {
+		pos := frame.Position()
+		frames = append(frames, DiagnosticFrame{
+			Function: frame.FuncName(),
+			File:     frame.SrcName(),
+			Line:     int32(pos.Line),
+			Column:   int32(pos.Column),
+		})
+	}
+
+	return frames
+}
+
+// javaScriptBacktrace reverses the backtrace to match the Starlark backtraces and reports the Diagnostic
+// at its innermost position.
+func javaScriptBacktrace(d *Diagnostic, backtrace []DiagnosticFrame) {
+	slices.Reverse(backtrace)
+	d.Backtrace = backtrace
+
+	// native functions have no position, the error is reported at their caller
+	for i := len(backtrace) - 1; i >= 0; i-- {
+		if frame := backtrace[i]; frame.Line != 0 {
+			d.File, d.Line, d.Column = frame.File, frame.Line, frame.Column
+			break
+		}
+	}
+}
+
+// javaScriptPosition returns the line and the column of the given byte offset, both start at 1.
+func javaScriptPosition(src string, offset int) (int32, int32) {
+	offset = min(offset, len(src))
+	line := strings.Count(src[:offset], "\n") + 1
+	column := offset - strings.LastIndex(src[:offset], "\n")
+
+	return int32(line), int32(column)
+}
+
+// JavaScriptDeclarations returns the TypeScript declarations of the values main receives and returns,
+// the context is generated from the StarlarkContextSchema.
+func JavaScriptDeclarations() string {
+	var b strings.Builder
+	b.WriteString("// Code generated by wccs types. DO NOT EDIT.\n\n")
+	b.WriteString("/** The types of the JavaScript and TypeScript configuration files. */\n")
+	b.WriteString("declare namespace wccs {\n")
+	fmt.Fprintf(&b, "  /** %s */\n", StarlarkContextSchema.Doc)
+	b.WriteString("  interface Context ")
+	javaScriptDeclaration(&b, StarlarkContextSchema, reflect.TypeFor[struct {
+		Repo     model.Repo
+		Pipeline model.Pipeline
+	}](), "  ")
+	b.WriteString(`
+
+  /** A woodpecker workflow, the name is used as file name and is not part of the workflow. */
+  interface Workflow {
+    name?: string;
+    [key: string]: unknown;
+  }
+
+  /** The values main may return, named workflows, a workflow, workflows or YAML strings by file name, or a YAML string. */
+  type Result = Workflow[] | Workflow | Record<string, Workflow | string> | string;
+
+  /** The main function, it is exported as main or as named default function and may be async. */
+  type Main = (ctx: Context) => Result | Promise<Result>;
+}
+`)
+
+	return b.String()
+}
+
+// javaScriptDeclaration writes the TypeScript type of the given model type, structs are written as the attributes.
+func javaScriptDeclaration(b *strings.Builder, attribute ContextAttribute, t reflect.Type, indent string) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		javaScriptDeclaration(b, attribute, t.Elem(), indent)
+		b.WriteString(" | null")
+	case reflect.String:
+		b.WriteString("string")
+	case reflect.Bool:
+		b.WriteString("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		b.WriteString("number")
+	case reflect.Slice, reflect.Array:
+		b.WriteString("Array<")
+		javaScriptDeclaration(b, ContextAttribute{}, t.Elem(), indent)
+		b.WriteString(">")
+	case reflect.Map:
+		b.WriteString("Record<string, ")
+		javaScriptDeclaration(b, ContextAttribute{}, t.Elem(), indent)
+		b.WriteString(">")
+	case reflect.Struct:
+		b.WriteString("{\n")
+		for _, child := range attribute.Attributes {
+			if doc := child.Doc; strings.HasPrefix(doc, "Deprecated ") {
+				fmt.Fprintf(b, "%s  /** @deprecated %s */\n", indent, doc)
+			} else if doc != "" {
+				fmt.Fprintf(b, "%s  /** %s */\n", indent, doc)
+			}
+			fmt.Fprintf(b, "%s  %s: ", indent, child.Name)
+			javaScriptDeclaration(b, child, t.FieldByIndex(child.index).Type, indent+"  ")
+			b.WriteString(";\n")
+		}
+		fmt.Fprintf(b, "%s}", indent)
+	default:
+		b.WriteString("unknown")
+	}
+}
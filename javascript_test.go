@@ -0,0 +1,229 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestJavaScriptConverter_Compatible(t *testing.T) {
+	c := wccs.NewJavaScriptConverter(noopLogger)
+	for name, compatible := range map[string]bool{
+		".woodpecker/build.js": true,
+		".woodpecker/build.ts": true,
+		"types/wccs.d.ts":      false,
+		"build.json":           false,
+		"build.star":           false,
+		"build.tsx":            false,
+	} {
+		assert.Equal(t, compatible, c.Compatible(wccs.File{Name: name}), name)
+	}
+}
+
+func TestJavaScriptConverter_Convert(t *testing.T) {
+	env := wccs.Environment{
+		Repo:     model.Repo{FullName: "opencloud-eu/web", Owner: "opencloud-eu", Name: "web"},
+		Pipeline: model.Pipeline{Event: model.EventPush, Timestamp: 1700000000, ChangedFiles: []string{"web/src/app.ts", "README.md"}},
+		Netrc:    model.Netrc{Login: "secret", Password: "secret"},
+	}
+	c := wccs.NewJavaScriptConverter(noopLogger)
+
+	t.Run("calls main with the context", func(t *testing.T) {
+		files, err := c.Convert(t.Context(), wccs.File{Name: ".woodpecker/build.js", Data: `
+const images = { go: "golang:1.26", node: "node:24" };
+
+export function main(ctx) {
+  console.log("building", ctx.repo.full_name);
+  return [
+    {
+      name: "build",
+      when: { event: ctx.build.event },
+      steps: [{ name: "test", image: images.go, commands: ["make test"], skip: undefined }],
+    },
+    {
+      name: "frontend",
+      steps: ctx.build.changed_files.filter((f) => f.startsWith("web/")).map((f) => ({ name: f, image: images.node })),
+      labels: { year: new Date().getUTCFullYear(), netrc: typeof ctx.netrc },
+    },
+  ];
+}
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{
+			{Name: "build.yaml", Data: "when:\n  event: push\nsteps:\n  - name: test\n    image: golang:1.26\n    commands:\n      - make test\n"},
+			{Name: "frontend.yaml", Data: "steps:\n  - name: web/src/app.ts\n    image: node:24\nlabels:\n  year: 2023\n  netrc: undefined\n"},
+		}, files)
+	})
+
+	t.Run("strips TypeScript syntax", func(t *testing.T) {
+		files, err := c.Convert(t.Context(), wccs.File{Name: ".woodpecker/build.ts", Data: `
+import type { Options } from "./options";
+
+type Step = { name: string; image: string; commands?: string[] };
+interface Workflow<T = Step> {
+  steps: T[];
+}
+declare const process: { env: Record<string, string> };
+
+class Pipeline<T extends Step> implements Workflow<T> {
+  private readonly steps: T[] = [];
+  declare name?: string;
+
+  add<U extends T>(step: U): this {
+    this.steps.push(step as T);
+    return this;
+  }
+}
+
+export const image = (name: string, tag?: string): string => tag ? name + ":" + tag : name;
+
+export async function main(ctx: wccs.Context): Promise<wccs.Result> {
+  const pipeline = new Pipeline<Step>();
+  const name = ctx.repo.name!;
+  const tags = ["1.26"] as const;
+  pipeline.add({ name, image: image("golang", tags[0]) } satisfies Step);
+
+  return await Promise.resolve({ [name + ".yaml"]: { steps: pipeline["steps"] } });
+}
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{{Name: "web.yaml", Data: "steps:\n  - name: web\n    image: golang:1.26\n"}}, files)
+	})
+
+	t.Run("accepts default exports and export lists", func(t *testing.T) {
+		for _, src := range []string{
+			"export default function main(ctx) { return { steps: [] } }",
+			"export default async function build(ctx: wccs.Context): Promise<wccs.Result> { return { steps: [] } }",
+			"function main(ctx) { return { steps: [] } }\nexport { main };",
+			"type Step = { name: string };\nconst build = (ctx) => ({ steps: [] as Step[] });\nexport { type Step, build as main }",
+		} {
+			files, err := c.Convert(t.Context(), wccs.File{Name: "build.ts", Data: src}, env)
+			assert.NoError(t, err, src)
+			assert.Equal(t, []wccs.File{{Name: "build.yaml", Data: "steps: []\n"}}, files, src)
+		}
+	})
+
+	t.Run("reports errors with their position", func(t *testing.T) {
+		for src, expected := range map[string]struct {
+			kind    wccs.DiagnosticKind
+			message string
+		}{
+			"export function main(ctx) {\n  return [\n}\n": {
+				kind: wccs.DiagnosticKindSyntax, message: "build.ts:3:1: unexpected }",
+			},
+			"export function main(ctx) {\n  return {steps: [}\n}\n": {
+				kind: wccs.DiagnosticKindSyntax, message: "build.ts:2:19: unexpected }",
+			},
+			"export function main(ctx) {\n  let = 1\n}\n": {
+				kind: wccs.DiagnosticKindSyntax, message: "build.ts:2:",
+			},
+			"enum Event { Push }\nexport function main(ctx) {}\n": {
+				kind: wccs.DiagnosticKindSyntax, message: "build.ts:1:1: enums are not supported",
+			},
+			"import { steps } from \"./steps\";\n": {
+				kind: wccs.DiagnosticKindSyntax, message: "build.ts:1:1: import declarations are not supported",
+			},
+			"export default { steps: [] };\n": {
+				kind: wccs.DiagnosticKindSyntax, message: "build.ts:1:1: only functions can be exported as default",
+			},
+			"export default function (ctx) {}\n": {
+				kind: wccs.DiagnosticKindSyntax, message: "build.ts:1:25: default exported functions must be named",
+			},
+			"function check(name: string) {\n  throw new Error(`invalid name ${name}`);\n}\n\nexport function main(ctx) {\n  check(ctx.repo.name);\n}\n": {
+				kind: wccs.DiagnosticKindFail, message: "build.ts:2:9: invalid name web",
+			},
+			"export function main(ctx) {\n  return ctx.build.missing.name;\n}\n": {
+				kind: wccs.DiagnosticKindRuntime, message: "build.ts:2:28: TypeError: Cannot read property 'name' of undefined",
+			},
+			"export async function main(ctx) {\n  await null;\n  throw new Error(\"async\");\n}\n": {
+				kind: wccs.DiagnosticKindFail, message: "build.ts:3:9: async",
+			},
+		} {
+			_, err := c.Convert(t.Context(), wccs.File{Name: "build.ts", Data: src}, env)
+
+			var diagnostic *wccs.Diagnostic
+			if assert.True(t, errors.As(err, &diagnostic), src) {
+				assert.Equal(t, expected.kind, diagnostic.Kind, src)
+				assert.NotEmpty(t, diagnostic.Report(), src)
+			}
+			assert.ErrorContains(t, err, expected.message, src)
+		}
+	})
+
+	t.Run("rejects unsupported results", func(t *testing.T) {
+		for _, src := range []string{
+			`export function main(ctx) { return { steps: [() => {}] } }`,
+			`export function main(ctx) { const w = { steps: [] }; w.self = w; return w }`,
+			`export function main(ctx) { return new Promise(() => {}) }`,
+		} {
+			_, err := c.Convert(t.Context(), wccs.File{Name: "build.js", Data: src}, env)
+			assert.ErrorIs(t, err, wccs.ErrUnsupportedType, src)
+		}
+
+		_, err := c.Convert(t.Context(), wccs.File{Name: "build.js", Data: `const steps = []`}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoEntrypoint)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "build.js"}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoContent)
+	})
+}
+
+func TestJavaScriptConverter_Limits(t *testing.T) {
+	convert := func(t *testing.T, limits wccs.JavaScriptLimits, src string) error {
+		_, err := wccs.NewJavaScriptConverter(noopLogger, wccs.WithJavaScriptLimits(limits)).Convert(t.Context(), wccs.File{Name: "build.js", Data: src}, wccs.Environment{})
+		return err
+	}
+
+	assert.ErrorIs(t, convert(t, wccs.JavaScriptLimits{Timeout: 100 * time.Millisecond}, `while (true) {}`), wccs.ErrLimitExceeded)
+
+	err := convert(t, wccs.JavaScriptLimits{Timeout: 100 * time.Millisecond}, "export function main(ctx) {\n  for (;;) {}\n}")
+	assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+	assert.Equal(t, 1, strings.Count(err.Error(), "timeout 100ms"), err.Error())
+	var diagnostic *wccs.Diagnostic
+	assert.True(t, errors.As(err, &diagnostic))
+	assert.Equal(t, "main", diagnostic.Backtrace[0].Function)
+
+	assert.ErrorIs(t, convert(t, wccs.JavaScriptLimits{}, `const f = () => f(); f()`), wccs.ErrLimitExceeded)
+	assert.ErrorContains(t, convert(t, wccs.JavaScriptLimits{MaxCallStackSize: 10}, `const f = () => f(); f()`), "max call stack size 10")
+	assert.ErrorIs(t, convert(t, wccs.JavaScriptLimits{MaxWorkflows: 1}, `export function main(ctx) { return [{ name: "a" }, { name: "b" }] }`), wccs.ErrLimitExceeded)
+	assert.ErrorIs(t, convert(t, wccs.JavaScriptLimits{MaxOutputSize: 1024}, `export function main(ctx) { return { steps: "x".repeat(2048) } }`), wccs.ErrLimitExceeded)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = wccs.NewJavaScriptConverter(noopLogger).Convert(ctx, wccs.File{Name: "build.js", Data: `while (true) {}`}, wccs.Environment{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	afterFuncCtx := newAfterFuncContext()
+	_, err = wccs.NewJavaScriptConverter(noopLogger).Convert(afterFuncCtx, wccs.File{Name: "build.js", Data: `export function main(ctx) { return [{ name: "a" }] }`}, wccs.Environment{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, afterFuncCtx.registered)
+	assert.Equal(t, 0, afterFuncCtx.pending)
+}
+
+func TestJavaScriptDeclarations(t *testing.T) {
+	data, err := os.ReadFile("types/wccs.d.ts")
+	assert.NoError(t, err)
+	assert.Equal(t, wccs.JavaScriptDeclarations(), string(data), "types/wccs.d.ts is outdated, run wccs types > types/wccs.d.ts")
+}
@@ -0,0 +1,263 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// jsonnetExtensions are the extensions of the files the JsonnetConverter evaluates.
+var jsonnetExtensions = []string{".jsonnet", ".libsonnet"}
+
+// jsonnetContextName is the name of the external variable and the top-level argument which hold the context.
+const jsonnetContextName = "ctx"
+
+// JsonnetLimits restricts the resources a conversion may use, a zero value disables the limit.
+type JsonnetLimits struct {
+	// Timeout of the whole conversion.
+	Timeout time.Duration
+	// MaxStack is the maximal depth of the evaluation, the default of jsonnet is used if 0.
+	MaxStack int
+	// MaxOutputSize is the maximal size of all generated workflows in bytes.
+	MaxOutputSize int
+	// MaxWorkflows is the maximal number of generated workflows.
+	MaxWorkflows int
+}
+
+// JsonnetConverter evaluates Jsonnet files, the context Starlark scripts receive is passed as ctx.
+type JsonnetConverter struct {
+	logger *slog.Logger
+	limits JsonnetLimits
+}
+
+// JsonnetOption configures the JsonnetConverter.
+type JsonnetOption func(*JsonnetConverter)
+
+// WithJsonnetLimits restricts the resources a conversion may use, conversions are unlimited by default.
+func WithJsonnetLimits(limits JsonnetLimits) JsonnetOption {
+	return func(c *JsonnetConverter) {
+		c.limits = limits
+	}
+}
+
+// NewJsonnetConverter returns a new JsonnetConverter.
+func NewJsonnetConverter(logger *slog.Logger, options ...JsonnetOption) JsonnetConverter {
+	c := JsonnetConverter{logger: logger}
+	for _, option := range options {
+		option(&c)
+	}
+
+	return c
+}
+
+func (c JsonnetConverter) Compatible(f File) bool {
+	return slices.Contains(jsonnetExtensions, path.Ext(f.Name))
+}
+
+// Convert evaluates the file, the context is available as the external variable ctx and as the top-level argument ctx
+// if the file is a function. The result is turned into files like the value main returns in Starlark scripts.
+// Imports are read from the source of the file relative to the importing file.
+func (c JsonnetConverter) Convert(ctx context.Context, f File, env Environment) ([]File, error) {
+	if f.Data == "" {
+		return nil, ErrNoContent
+	}
+
+	if c.limits.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, c.limits.Timeout, fmt.Errorf("%w: timeout %s", ErrLimitExceeded, c.limits.Timeout))
+		defer cancel()
+	}
+
+	importer := &jsonnetImporter{source: f.Source, sources: map[string]string{f.Name: f.Data}, contents: map[string]jsonnet.Contents{}}
+
+	node, err := jsonnet.SnippetToAST(f.Name, f.Data)
+	if err != nil {
+		return nil, jsonnetDiagnostic(err, importer.sources)
+	}
+
+	data, err := json.Marshal(contextData(env))
+	if err != nil {
+		return nil, err
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.Importer(importer)
+	vm.ExtCode(jsonnetContextName, string(data))
+	vm.TLACode(jsonnetContextName, string(data))
+	if c.limits.MaxStack != 0 {
+		vm.MaxStack = c.limits.MaxStack
+	}
+
+	type evaluation struct {
+		out string
+		err error
+	}
+	done := make(chan evaluation, 1)
+	go func() {
+		out, err := vm.Evaluate(node)
+		done <- evaluation{out: out, err: err}
+	}()
+
+	var out string
+	select {
+	case <-ctx.Done():
+		// jsonnet cannot be interrupted, the evaluation ends in the background and its result is dropped
+		return nil, context.Cause(ctx)
+	case result := <-done:
+		if result.err != nil {
+			return nil, fmt.Errorf("%w: error evaluating file", jsonnetLimitError(jsonnetDiagnostic(result.err, importer.sources), vm.MaxStack))
+		}
+		out = result.out
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(out), &document); err != nil {
+		return nil, err
+	}
+
+	result, err := YAMLToStarlark(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := starlarkFiles(result, f.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, checkOutput(files, c.limits.MaxWorkflows, c.limits.MaxOutputSize)
+}
+
+// jsonnetLimitError returns a distinct error if the evaluation exceeded the maximal stack depth.
+func jsonnetLimitError(err error, maxStack int) error {
+	var d *Diagnostic
+	if errors.As(err, &d) && strings.HasPrefix(d.Message, "max stack frames exceeded") {
+		return fmt.Errorf("%w: max stack %d: %w", ErrLimitExceeded, maxStack, err)
+	}
+
+	return err
+}
+
+// jsonnetImporter reads imports from the source of the entry file, paths are relative to the importing file
+// and must not leave the repository. It is only used by the evaluation of a single file.
+type jsonnetImporter struct {
+	source Source
+	// sources of the evaluated files by name, used to report errors.
+	sources map[string]string
+	// contents of the imported files by name, jsonnet expects the same contents for every import of a file.
+	contents map[string]jsonnet.Contents
+}
+
+// Import implements the jsonnet.Importer interface.
+func (i *jsonnetImporter) Import(importedFrom, importedPath string) (jsonnet.Contents, string, error) {
+	name, err := resolveModulePath("", path.Join(path.Dir(importedFrom), importedPath))
+	if err != nil {
+		return jsonnet.Contents{}, "", err
+	}
+
+	if contents, ok := i.contents[name]; ok {
+		return contents, name, nil
+	}
+
+	if i.source == nil {
+		return jsonnet.Contents{}, "", fmt.Errorf("%w: %s", ErrNoSource, name)
+	}
+
+	f, err := i.source.Read(name)
+	if err != nil {
+		return jsonnet.Contents{}, "", err
+	}
+
+	contents := jsonnet.MakeContents(f.Data)
+	i.contents[name] = contents
+	i.sources[name] = f.Data
+
+	return contents, name, nil
+}
+
+// jsonnetDiagnostic turns syntax and runtime errors of jsonnet into a Diagnostic, other errors are returned unchanged.
+// The sources are used to add the failing line.
+func jsonnetDiagnostic(err error, sources map[string]string) error {
+	var d *Diagnostic
+
+	var runtimeErr jsonnet.RuntimeError
+	// the static errors of the parser are internal to jsonnet, only their location is accessible
+	var staticErr interface {
+		error
+		Loc() ast.LocationRange
+	}
+	switch {
+	case errors.As(err, &runtimeErr):
+		d = &Diagnostic{Kind: DiagnosticKindRuntime, Message: runtimeErr.Msg}
+		for _, frame := range runtimeErr.StackTrace {
+			// frames of the manifestation have no position
+			if !frame.Loc.Begin.IsSet() {
+				continue
+			}
+
+			d.Backtrace = append(d.Backtrace, DiagnosticFrame{
+				Function: frame.Name,
+				File:     frame.Loc.FileName,
+				Line:     int32(frame.Loc.Begin.Line),
+				Column:   int32(frame.Loc.Begin.Column),
+			})
+		}
+
+		// the innermost frame comes last
+		if len(d.Backtrace) != 0 {
+			frame := d.Backtrace[len(d.Backtrace)-1]
+			d.File, d.Line, d.Column = frame.File, frame.Line, frame.Column
+		}
+	case errors.As(err, &staticErr):
+		loc := staticErr.Loc()
+		d = &Diagnostic{
+			Kind:    DiagnosticKindSyntax,
+			File:    loc.FileName,
+			Line:    int32(loc.Begin.Line),
+			Column:  int32(loc.Begin.Column),
+			Message: strings.TrimPrefix(staticErr.Error(), jsonnetLocation(loc)+" "),
+		}
+	default:
+		return err
+	}
+
+	d.err = err
+	if lines := strings.Split(sources[d.File], "\n"); d.Line > 0 && int(d.Line) <= len(lines) {
+		d.Source = strings.TrimRight(lines[d.Line-1], "\r")
+	}
+
+	return d
+}
+
+// jsonnetLocation returns the location as jsonnet prefixes it to the messages of static errors.
+func jsonnetLocation(loc ast.LocationRange) string {
+	if !loc.IsSet() {
+		return ""
+	}
+
+	return loc.String()
+}
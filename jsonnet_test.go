@@ -0,0 +1,135 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestJsonnetConverter_Compatible(t *testing.T) {
+	c := wccs.NewJsonnetConverter(noopLogger)
+	for name, compatible := range map[string]bool{
+		".woodpecker/build.jsonnet":   true,
+		".woodpecker/lib.libsonnet":   true,
+		".woodpecker/build.json":      false,
+		".woodpecker/build.star":      false,
+		".woodpecker/build.jsonnet.x": false,
+	} {
+		assert.Equal(t, compatible, c.Compatible(wccs.File{Name: name}), name)
+	}
+}
+
+func TestJsonnetConverter_Convert(t *testing.T) {
+	env := wccs.Environment{
+		Repo:     model.Repo{FullName: "opencloud-eu/web", Owner: "opencloud-eu", Name: "web"},
+		Pipeline: model.Pipeline{Event: model.EventPush, Branch: "main"},
+		Netrc:    model.Netrc{Login: "secret", Password: "secret"},
+	}
+	c := wccs.NewJsonnetConverter(noopLogger)
+
+	t.Run("passes the context as top-level argument", func(t *testing.T) {
+		files, err := c.Convert(t.Context(), wccs.File{Name: ".woodpecker/build.jsonnet", Data: `
+function(ctx) [
+  {
+    name: "build",
+    when: { event: ctx.build.event },
+    steps: [{ name: "test", image: "golang", commands: ["make test"] }],
+  },
+  {
+    name: ctx.repo.name,
+    labels: { netrc: std.objectHas(ctx, "netrc") },
+  },
+]
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{
+			{Name: "build.yaml", Data: "steps:\n  - commands:\n      - make test\n    image: golang\n    name: test\nwhen:\n  event: push\n"},
+			{Name: "web.yaml", Data: "labels:\n  netrc: false\n"},
+		}, files)
+	})
+
+	t.Run("passes the context as external variable", func(t *testing.T) {
+		files, err := c.Convert(t.Context(), wccs.File{Name: "ci.jsonnet", Data: `
+local ctx = std.extVar("ctx");
+{ ["release-" + ctx.build.branch + ".yaml"]: { steps: [] } }
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{{Name: "release-main.yaml", Data: "steps: []\n"}}, files)
+	})
+
+	t.Run("imports files relative to the importing file", func(t *testing.T) {
+		source := newMapSource(map[string]string{
+			"lib/steps.libsonnet":    `local images = import "images.json"; { test(image): { name: "test", image: images[image] } }`,
+			"lib/images.json":        `{"go": "golang:1.26"}`,
+			".woodpecker/ci.jsonnet": `local steps = import "../lib/steps.libsonnet"; { steps: [steps.test("go")] }`,
+		})
+
+		f, err := source.Read(".woodpecker/ci.jsonnet")
+		assert.NoError(t, err)
+
+		files, err := c.Convert(t.Context(), f, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{{Name: "ci.yaml", Data: "steps:\n  - image: golang:1.26\n    name: test\n"}}, files)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "ci.jsonnet", Data: `import "../secret.libsonnet"`, Source: source}, env)
+		assert.ErrorContains(t, err, wccs.ErrPathNotAllowed.Error())
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "ci.jsonnet", Data: `import "lib.libsonnet"`}, env)
+		assert.ErrorContains(t, err, wccs.ErrNoSource.Error())
+	})
+
+	t.Run("reports the position of errors", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Name: "ci.jsonnet", Data: "{\n  steps: [,\n}\n"}, env)
+		var diagnostic *wccs.Diagnostic
+		assert.True(t, errors.As(err, &diagnostic))
+		assert.Equal(t, wccs.DiagnosticKindSyntax, diagnostic.Kind)
+		assert.Equal(t, int32(2), diagnostic.Line)
+		assert.Equal(t, "  steps: [,", diagnostic.Source)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "ci.jsonnet", Data: "local check(x) =\n  error \"invalid \" + x;\n{ steps: check(\"image\") }\n"}, env)
+		assert.True(t, errors.As(err, &diagnostic))
+		assert.Equal(t, wccs.DiagnosticKindRuntime, diagnostic.Kind)
+		assert.Equal(t, "invalid image", diagnostic.Message)
+		assert.Equal(t, "ci.jsonnet:2:3", diagnostic.Error()[:len("ci.jsonnet:2:3")])
+		assert.NotEmpty(t, diagnostic.Backtrace)
+	})
+
+	t.Run("fails on unsupported results", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Name: "ci.jsonnet", Data: "42"}, env)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "ci.jsonnet"}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoContent)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		for data, limits := range map[string]wccs.JsonnetLimits{
+			"local loop(n) = if n == 0 then {} else loop(n - 1) tailstrict; loop(1000000000)": {Timeout: 100 * time.Millisecond},
+			"local deep(n) = deep(n + 1) + 1; deep(0)":                                        {MaxStack: 100},
+			"[{ name: std.toString(i) } for i in std.range(1, 3)]":                            {MaxWorkflows: 2},
+			`{ steps: [std.repeat("x", 100)] }`:                                               {MaxOutputSize: 50},
+		} {
+			_, err := wccs.NewJsonnetConverter(noopLogger, wccs.WithJsonnetLimits(limits)).Convert(t.Context(), wccs.File{Name: "ci.jsonnet", Data: data}, env)
+			assert.ErrorIs(t, err, wccs.ErrLimitExceeded, data)
+		}
+	})
+}
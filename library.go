@@ -0,0 +1,30 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// StarlarkLibraryPrefix is the prefix of the modules of the Starlark library which is embedded in the binary,
+// e.g. @wccs//steps.star. The library is versioned together with wccs and needs no lock file entry.
+const StarlarkLibraryPrefix = "@wccs//"
+
+//go:embed library/*.star
+var starlarkLibraryFS embed.FS
+
+// starlarkLibrary is the source of the embedded Starlark library.
+var starlarkLibrary = fsSource{fs: Must1(fs.Sub(starlarkLibraryFS, "library"))}
@@ -0,0 +1,81 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkLibrary(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	t.Run("passes its tests", func(t *testing.T) {
+		paths, err := filepath.Glob("library/*" + wccs.StarlarkTestFileSuffix)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, paths)
+
+		for _, p := range paths {
+			data, err := os.ReadFile(p)
+			assert.NoError(t, err)
+
+			results, err := c.Test(t.Context(), wccs.File{Name: filepath.Base(p), Data: string(data)})
+			assert.NoError(t, err)
+			assert.NotEmpty(t, results)
+			for _, result := range results {
+				assert.Empty(t, result.Failure, result.Name)
+			}
+		}
+	})
+
+	main := wccs.File{Name: "main.star", Data: `
+load("@wccs//steps.star", "step")
+load("@wccs//workflows.star", "matrix", "workflow")
+
+def main(ctx):
+  return matrix({"go": ["1.25", "1.26"]}, lambda m: workflow("test", [step("test", "golang:" + m["go"], "go test ./...")]))
+`}
+
+	t.Run("is loaded without a source", func(t *testing.T) {
+		files, err := c.Convert(t.Context(), main, wccs.Environment{})
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{
+			{Name: "test-1.25.yaml", Data: "steps:\n  - name: test\n    image: golang:1.25\n    commands:\n      - go test ./...\n"},
+			{Name: "test-1.26.yaml", Data: "steps:\n  - name: test\n    image: golang:1.26\n    commands:\n      - go test ./...\n"},
+		}, files)
+	})
+
+	t.Run("is not locked", func(t *testing.T) {
+		lock, err := wccs.LockStarlark(t.Context(), main, "", nil)
+		assert.NoError(t, err)
+		assert.Empty(t, lock)
+	})
+
+	t.Run("does not resolve paths outside of the library", func(t *testing.T) {
+		source := newMapSource(map[string]string{"main.star": "x = 1"})
+		_, _, err := c.Exec(t.Context(), wccs.File{Name: "main.star", Data: `load("@wccs//../main.star", "x")`, Source: source}, wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrPathNotAllowed)
+
+		_, _, err = c.Exec(t.Context(), wccs.File{Name: "main.star", Data: `load("@wccs//missing.star", "x")`, Source: source}, wccs.Environment{})
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.Empty(t, source.reads)
+	})
+}
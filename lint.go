@@ -0,0 +1,577 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// LintSeverity classifies a LintFinding.
+type LintSeverity string
+
+const (
+	// LintSeverityError is used for findings which make the conversion fail.
+	LintSeverityError LintSeverity = "error"
+	// LintSeverityWarning is used for findings which are most likely mistakes.
+	LintSeverityWarning LintSeverity = "warning"
+)
+
+// LintRules describes the rules Lint checks by their id.
+var LintRules = map[string]string{
+	"syntax":           "The file cannot be parsed or resolved.",
+	"load":             "A local module cannot be read.",
+	"undefined":        "A name is neither defined, loaded nor predeclared.",
+	"main":             "An entry file must define main with exactly one parameter, the context.",
+	"context":          "An attribute of the context does not exist.",
+	"unused-load":      "A loaded name is never used.",
+	"unused-variable":  "A local variable is assigned but never used.",
+	"shadowed-builtin": "A builtin or predeclared module is shadowed by a definition.",
+	"unreachable":      "A statement or branch is never executed.",
+}
+
+// LintFinding is a problem Lint found in a file.
+type LintFinding struct {
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	File     string       `json:"file"`
+	Line     int32        `json:"line"`
+	Column   int32        `json:"column"`
+	Message  string       `json:"message"`
+}
+
+// LintFindings are the findings of one or more files.
+type LintFindings []LintFinding
+
+// Errors returns the number of findings with the error severity.
+func (findings LintFindings) Errors() int {
+	var errs int
+	for _, finding := range findings {
+		if finding.Severity == LintSeverityError {
+			errs++
+		}
+	}
+
+	return errs
+}
+
+// Text writes the findings in the file:line:column format of compilers, one finding per line.
+func (findings LintFindings) Text(w io.Writer) error {
+	for _, finding := range findings {
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s (%s)\n", finding.File, finding.Line, finding.Column, finding.Severity, finding.Message, finding.Rule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// JSON writes the findings as JSON array.
+func (findings LintFindings) JSON(w io.Writer) error {
+	if findings == nil {
+		findings = LintFindings{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(findings)
+}
+
+// SARIF writes the findings as SARIF 2.1.0 log, the format code scanning tools of forges import.
+func (findings LintFindings) SARIF(w io.Writer) error {
+	driver := sarifDriver{Name: "wccs", InformationURI: "https://github.com/opencloud-eu/woodpecker-ci-config-service"}
+	for _, id := range slices.Sorted(maps.Keys(LintRules)) {
+		driver.Rules = append(driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: LintRules[id]}})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, finding := range findings {
+		results = append(results, sarifResult{
+			RuleID:  finding.Rule,
+			Level:   string(finding.Severity),
+			Message: sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: finding.File},
+				Region:           sarifRegion{StartLine: finding.Line, StartColumn: finding.Column},
+			}}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int32 `json:"startLine"`
+	StartColumn int32 `json:"startColumn"`
+}
+
+// lintFile is a file Lint checks.
+type lintFile struct {
+	name   string
+	syntax *syntax.File
+	// drone is set for files which are checked against the drone context.
+	drone bool
+	// entry is set for files which must define main.
+	entry bool
+}
+
+// Lint statically checks the given entry files and the local modules they load, nothing is executed.
+// Files which are loaded by another file and test files are modules which do not need a main function,
+// remote modules are not checked.
+func (p StarlarkConverter) Lint(files ...File) LintFindings {
+	l := &linter{}
+
+	// modules inherit the mode of the first file which loads them
+	drone := map[string]bool{}
+	for _, f := range files {
+		drone[f.Name] = p.fileMode(f) == StarlarkModeDrone
+	}
+
+	var linted []*lintFile
+	parsed := map[string]bool{}
+	loaded := map[string]bool{}
+	queue := slices.Clone(files)
+	for len(queue) != 0 {
+		f := queue[0]
+		queue = queue[1:]
+		if parsed[f.Name] {
+			continue
+		}
+		parsed[f.Name] = true
+
+		file, err := syntax.LegacyFileOptions().Parse(f.Name, f.Data, 0)
+		if err != nil {
+			var syntaxErr syntax.Error
+			if errors.As(err, &syntaxErr) {
+				l.report("syntax", LintSeverityError, syntaxErr.Pos, "%s", syntaxErr.Msg)
+			}
+
+			continue
+		}
+		linted = append(linted, &lintFile{name: f.Name, syntax: file, drone: drone[f.Name]})
+
+		for _, stmt := range file.Stmts {
+			load, ok := stmt.(*syntax.LoadStmt)
+			if !ok || strings.HasPrefix(load.ModuleName(), "@") {
+				continue
+			}
+
+			name, err := resolveModulePath(p.loadRoot, load.ModuleName())
+			if err == nil {
+				loaded[name] = true
+				if _, ok := drone[name]; !ok {
+					drone[name] = drone[f.Name]
+				}
+				if parsed[name] {
+					continue
+				}
+			}
+
+			var module File
+			switch {
+			case err != nil:
+			case f.Source == nil:
+				err = fmt.Errorf("%w: %s", ErrNoSource, name)
+			default:
+				module, err = f.Source.Read(name)
+			}
+			if err != nil {
+				l.report("load", LintSeverityError, load.Module.TokenPos, "cannot load %s: %s", load.ModuleName(), err)
+				continue
+			}
+
+			module.Name = name
+			module.Source = f.Source
+			queue = append(queue, module)
+		}
+	}
+
+	for _, f := range linted {
+		f.entry = !loaded[f.name] && !strings.HasSuffix(f.name, StarlarkTestFileSuffix)
+		p.lintFile(l, f)
+	}
+
+	findings := l.findings
+	slices.SortStableFunc(findings, func(a, b LintFinding) int {
+		return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+
+	return findings
+}
+
+// lintFile resolves the file and runs all checks on it.
+func (p StarlarkConverter) lintFile(l *linter, f *lintFile) {
+	l.file = f
+
+	predeclared := maps.Clone(p.predeclared)
+	if strings.HasSuffix(f.name, StarlarkTestFileSuffix) {
+		predeclared["assert"] = starlarkAssertModule
+		predeclared["testing"] = p.starlarkTestingModule()
+	}
+
+	if err := resolve.File(f.syntax, predeclared.Has, starlark.Universe.Has); err != nil {
+		var errs resolve.ErrorList
+		if errors.As(err, &errs) {
+			for _, err := range errs {
+				rule := "syntax"
+				if strings.HasPrefix(err.Msg, "undefined: ") {
+					rule = "undefined"
+				}
+
+				l.report(rule, LintSeverityError, err.Pos, "%s", err.Msg)
+			}
+		}
+	}
+
+	l.main()
+	l.bindings(predeclared)
+	l.unreachable(f.syntax.Stmts)
+}
+
+// linter collects the findings of Lint, the checks run on the current file.
+type linter struct {
+	file     *lintFile
+	findings LintFindings
+}
+
+func (l *linter) report(rule string, severity LintSeverity, pos syntax.Position, format string, args ...any) {
+	l.findings = append(l.findings, LintFinding{
+		Rule:     rule,
+		Severity: severity,
+		File:     pos.Filename(),
+		Line:     pos.Line,
+		Column:   pos.Col,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// main checks that entry files define main with a single parameter.
+func (l *linter) main() {
+	if !l.file.entry {
+		return
+	}
+
+	for _, stmt := range l.file.syntax.Stmts {
+		def, ok := stmt.(*syntax.DefStmt)
+		if !ok || def.Name.Name != "main" {
+			continue
+		}
+
+		if len(def.Params) != 1 {
+			l.report("main", LintSeverityError, def.Name.NamePos, "main must take exactly one parameter, the context, got %d", len(def.Params))
+		}
+
+		return
+	}
+
+	// main may be loaded or assigned as well
+	for _, stmt := range l.file.syntax.Stmts {
+		switch stmt := stmt.(type) {
+		case *syntax.LoadStmt:
+			if slices.ContainsFunc(stmt.To, func(id *syntax.Ident) bool { return id.Name == "main" }) {
+				return
+			}
+		case *syntax.AssignStmt:
+			if id, ok := stmt.LHS.(*syntax.Ident); ok && id.Name == "main" {
+				return
+			}
+		}
+	}
+
+	l.report("main", LintSeverityError, syntax.MakePosition(&l.file.syntax.Path, 1, 1), "main is not defined")
+}
+
+// bindings checks the context attributes, unused loads and variables and shadowed builtins.
+func (l *linter) bindings(predeclared starlark.StringDict) {
+	// definitions are the identifiers which bind a name, all other identifiers use it
+	definitions := map[*syntax.Ident]string{}
+	contextParams := map[*syntax.Ident]bool{}
+	define := func(kind string, exprs ...syntax.Expr) {
+		for _, expr := range exprs {
+			walkSyntax(expr, func(n syntax.Node) bool {
+				switch n := n.(type) {
+				case *syntax.Ident:
+					definitions[n] = kind
+				case *syntax.BinaryExpr:
+					// the default value of a parameter is a use
+					walkSyntax(n.X, func(n syntax.Node) bool {
+						if id, ok := n.(*syntax.Ident); ok {
+							definitions[id] = kind
+						}
+
+						return true
+					})
+
+					return false
+				case *syntax.IndexExpr, *syntax.DotExpr:
+					// assignments to elements and attributes use the value
+					return false
+				}
+
+				return true
+			})
+		}
+	}
+	params := func(def *syntax.DefStmt, params []syntax.Expr) {
+		define("parameter", params...)
+		for i, param := range params {
+			id, ok := param.(*syntax.Ident)
+			if !ok || l.file.drone {
+				continue
+			}
+
+			if id.Name == "ctx" || (def != nil && def.Name.Name == "main" && i == 0) {
+				contextParams[id] = true
+			}
+		}
+	}
+
+	walkSyntax(l.file.syntax, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.AssignStmt:
+			if n.Op == syntax.EQ {
+				define("variable", n.LHS)
+			}
+		case *syntax.ForStmt:
+			define("variable", n.Vars)
+		case *syntax.ForClause:
+			define("variable", n.Vars)
+		case *syntax.DefStmt:
+			define("function", n.Name)
+			params(n, n.Params)
+		case *syntax.LambdaExpr:
+			params(nil, n.Params)
+		case *syntax.LoadStmt:
+			for _, id := range n.To {
+				definitions[id] = "load"
+			}
+		}
+
+		return true
+	})
+
+	used := map[*resolve.Binding]bool{}
+	walkSyntax(l.file.syntax, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.DotExpr:
+			if l.context(n, contextParams) {
+				return false
+			}
+		case *syntax.Ident:
+			binding, ok := n.Binding.(*resolve.Binding)
+			if _, isDefinition := definitions[n]; !ok || isDefinition {
+				break
+			}
+
+			used[binding] = true
+			// free variables of nested functions use the binding of the enclosing function
+			if binding.First != nil {
+				if first, ok := binding.First.Binding.(*resolve.Binding); ok {
+					used[first] = true
+				}
+			}
+		}
+
+		return true
+	})
+
+	for _, id := range slices.SortedFunc(maps.Keys(definitions), func(a, b *syntax.Ident) int {
+		return cmp.Or(cmp.Compare(a.NamePos.Line, b.NamePos.Line), cmp.Compare(a.NamePos.Col, b.NamePos.Col))
+	}) {
+		// every name is only reported at its first definition
+		binding, ok := id.Binding.(*resolve.Binding)
+		if !ok || binding.First != id {
+			continue
+		}
+
+		kind := definitions[id]
+		if _, isBuiltin := starlark.Universe[id.Name]; isBuiltin || predeclared.Has(id.Name) {
+			l.report("shadowed-builtin", LintSeverityWarning, id.NamePos, "%s %s shadows the builtin %s", kind, id.Name, id.Name)
+		}
+
+		// main is used by the converter
+		if used[binding] || strings.HasPrefix(id.Name, "_") || id.Name == "main" {
+			continue
+		}
+
+		switch {
+		case kind == "load":
+			l.report("unused-load", LintSeverityWarning, id.NamePos, "%s is loaded but never used", id.Name)
+		case kind == "variable" && binding.Scope == resolve.Local:
+			l.report("unused-variable", LintSeverityWarning, id.NamePos, "%s is assigned but never used", id.Name)
+		}
+	}
+}
+
+// context checks the attribute path of the given expression if it starts at a context parameter,
+// it reports whether the expression was checked.
+func (l *linter) context(dot *syntax.DotExpr, contextParams map[*syntax.Ident]bool) bool {
+	var path []*syntax.DotExpr
+	var x syntax.Expr = dot
+	for {
+		d, ok := x.(*syntax.DotExpr)
+		if !ok {
+			break
+		}
+
+		path = append(path, d)
+		x = d.X
+	}
+
+	id, ok := x.(*syntax.Ident)
+	if !ok {
+		return false
+	}
+
+	binding, ok := id.Binding.(*resolve.Binding)
+	if !ok || binding.First == nil || !contextParams[binding.First] {
+		return false
+	}
+
+	attribute := StarlarkContextSchema
+	name := id.Name
+	for i := len(path) - 1; i >= 0; i-- {
+		// methods of values are not part of the schema
+		if attribute.Type != "struct" {
+			break
+		}
+
+		next, ok := attribute.Attribute(path[i].Name.Name)
+		name += "." + path[i].Name.Name
+		if !ok {
+			l.report("context", LintSeverityError, path[i].Name.NamePos, "%s is not an attribute of the context", name)
+			break
+		}
+
+		attribute = next
+	}
+
+	return true
+}
+
+// unreachable reports statements after a return, break, continue or fail and branches of constant conditions.
+func (l *linter) unreachable(stmts []syntax.Stmt) bool {
+	terminated := false
+	for _, stmt := range stmts {
+		if terminated {
+			l.report("unreachable", LintSeverityWarning, syntax.Start(stmt), "statement is unreachable")
+			return true
+		}
+
+		switch stmt := stmt.(type) {
+		case *syntax.ReturnStmt:
+			terminated = true
+		case *syntax.BranchStmt:
+			terminated = stmt.Token != syntax.PASS
+		case *syntax.ExprStmt:
+			call, ok := stmt.X.(*syntax.CallExpr)
+			if !ok {
+				break
+			}
+
+			if id, ok := call.Fn.(*syntax.Ident); ok && id.Name == "fail" {
+				binding, ok := id.Binding.(*resolve.Binding)
+				terminated = ok && binding.Scope == resolve.Universal
+			}
+		case *syntax.DefStmt:
+			l.unreachable(stmt.Body)
+		case *syntax.ForStmt:
+			l.unreachable(stmt.Body)
+		case *syntax.WhileStmt:
+			l.unreachable(stmt.Body)
+		case *syntax.IfStmt:
+			if id, ok := stmt.Cond.(*syntax.Ident); ok && (id.Name == "True" || id.Name == "False") {
+				if binding, ok := id.Binding.(*resolve.Binding); ok && binding.Scope == resolve.Universal {
+					l.report("unreachable", LintSeverityWarning, id.NamePos, "condition is always %s", strings.ToLower(id.Name))
+				}
+			}
+
+			// a statement after an if is unreachable if both branches end the block
+			trueTerminated := l.unreachable(stmt.True)
+			falseTerminated := l.unreachable(stmt.False)
+			terminated = trueTerminated && falseTerminated && stmt.False != nil
+		}
+	}
+
+	return terminated
+}
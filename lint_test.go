@@ -0,0 +1,168 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkConverter_Lint(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	source := newMapSource(map[string]string{
+		"lib.star": `
+load("other.star", "unused")
+
+def image(ctx, event):
+  name = "golang"
+  if ctx.build.evnet == event:
+    return "alpine"
+  return ctx.repo.full_name.upper()
+`,
+		"other.star":  `unused = 1`,
+		"syntax.star": `def broken(:`,
+	})
+
+	lint := func(files ...wccs.File) []string {
+		t.Helper()
+
+		var findings []string
+		for _, finding := range c.Lint(files...) {
+			findings = append(findings, fmt.Sprintf("%s:%d %s: %s", finding.File, finding.Line, finding.Rule, finding.Message))
+		}
+
+		return findings
+	}
+
+	assert.Equal(t, []string{
+		"lib.star:2 unused-load: unused is loaded but never used",
+		"lib.star:5 unused-variable: name is assigned but never used",
+		"lib.star:6 context: ctx.build.evnet is not an attribute of the context",
+		"main.star:2 load: cannot load missing.star: file does not exist",
+		"main.star:5 shadowed-builtin: parameter str shadows the builtin str",
+		"main.star:6 undefined: undefined: helper",
+		"main.star:7 unreachable: condition is always true",
+		"main.star:8 context: ctx.repo.nmae is not an attribute of the context",
+		"main.star:9 unreachable: statement is unreachable",
+	}, lint(wccs.File{Name: "main.star", Source: source, Data: `load("lib.star", "image")
+load("missing.star", "x")
+
+def main(ctx):
+  upper = lambda str: str.upper()
+  helper(upper(image(ctx, "tag")), x)
+  if True:
+    return [ctx.repo.nmae, ctx.build.event.lower(), [ctx for ctx in range(2)]]
+    fail("unreachable")
+`}))
+
+	t.Run("main", func(t *testing.T) {
+		for data, expected := range map[string][]string{
+			"def main(ctx):\n  return []\n":          nil,
+			"def main(ctx):\n  return []\n\nx = 1\n": nil,
+			"load(\"other.star\", \"main\")\n":       nil,
+			"def main(a, b):\n  return []\n":         {"entry.star:1 main: main must take exactly one parameter, the context, got 2"},
+			"x = 1\n":                                {"entry.star:1 main: main is not defined"},
+		} {
+			assert.Equal(t, expected, lint(wccs.File{Name: "entry.star", Source: source, Data: data}), data)
+		}
+
+		// modules and test files do not need main
+		assert.Nil(t, lint(wccs.File{Name: "entry.star", Source: source, Data: "load(\"other.star\", \"unused\")\nmain = unused\n"}))
+		assert.Nil(t, lint(wccs.File{Name: "entry_test.star", Source: source, Data: "def test_a():\n  assert.eq(testing.ctx().repo.name, \"\")\n"}))
+	})
+
+	t.Run("reports syntax errors", func(t *testing.T) {
+		assert.Equal(t, []string{"syntax.star:1 syntax: got ':', want ')'"}, lint(wccs.File{Name: "entry.star", Source: source, Data: "load(\"syntax.star\", \"broken\")\nmain = broken\n"}))
+	})
+
+	t.Run("reports while loops", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"entry.star:3 syntax: this Starlark dialect does not support while loops",
+			"entry.star:4 context: ctx.repo.nmae is not an attribute of the context",
+		}, lint(wccs.File{Name: "entry.star", Data: "def main(ctx):\n  n = 1\n  while n > 0:\n    n -= len(ctx.repo.nmae)\n  return []\n"}))
+	})
+
+	t.Run("does not check the drone context", func(t *testing.T) {
+		assert.Nil(t, lint(wccs.File{Name: ".drone.star", Data: "def main(ctx):\n  return [ctx.build.source]\n"}))
+	})
+}
+
+func TestLintFindings(t *testing.T) {
+	findings := wccs.LintFindings{
+		{Rule: "main", Severity: wccs.LintSeverityError, File: "a.star", Line: 1, Column: 1, Message: "main is not defined"},
+		{Rule: "unused-load", Severity: wccs.LintSeverityWarning, File: "b.star", Line: 2, Column: 5, Message: "x is loaded but never used"},
+	}
+	assert.Equal(t, 1, findings.Errors())
+
+	text := new(bytes.Buffer)
+	assert.NoError(t, findings.Text(text))
+	assert.Equal(t, "a.star:1:1: error: main is not defined (main)\nb.star:2:5: warning: x is loaded but never used (unused-load)\n", text.String())
+
+	jsonOut := new(bytes.Buffer)
+	assert.NoError(t, findings.JSON(jsonOut))
+	var decoded wccs.LintFindings
+	assert.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, findings, decoded)
+
+	empty := new(bytes.Buffer)
+	assert.NoError(t, wccs.LintFindings(nil).JSON(empty))
+	assert.Equal(t, "[]\n", empty.String())
+
+	sarif := new(bytes.Buffer)
+	assert.NoError(t, findings.SARIF(sarif))
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	assert.NoError(t, json.Unmarshal(sarif.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(wccs.LintRules))
+	assert.Equal(t, "unused-load", log.Runs[0].Results[1].RuleID)
+	assert.Equal(t, "warning", log.Runs[0].Results[1].Level)
+	assert.Equal(t, "b.star", log.Runs[0].Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 2, log.Runs[0].Results[1].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, 5, log.Runs[0].Results[1].Locations[0].PhysicalLocation.Region.StartColumn)
+}
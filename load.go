@@ -0,0 +1,295 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// starlarkOriginKey is the thread local key which holds the origin of the executing module.
+const starlarkOriginKey = "wccs.origin"
+
+// moduleOrigin describes where a module comes from, relative loads are resolved against it.
+type moduleOrigin struct {
+	source Source
+	root   string
+	// repo and ref are only set for remote modules.
+	repo, ref string
+	// library is set for the modules of the embedded library.
+	library bool
+}
+
+// resolve returns the origin, the cache key and the path of the given module.
+func (o moduleOrigin) resolve(module string) (moduleOrigin, string, string, error) {
+	if strings.HasPrefix(module, StarlarkLibraryPrefix) {
+		name, err := resolveModulePath("", strings.TrimPrefix(module, StarlarkLibraryPrefix))
+		if err != nil {
+			return moduleOrigin{}, "", "", err
+		}
+
+		return moduleOrigin{source: starlarkLibrary, library: true}, StarlarkLibraryPrefix + name, name, nil
+	}
+
+	if strings.HasPrefix(module, "@") {
+		label, err := ParseModuleLabel(module)
+		if err != nil {
+			return moduleOrigin{}, "", "", err
+		}
+
+		return moduleOrigin{repo: label.Repo, ref: label.Ref}, label.String(), label.Path, nil
+	}
+
+	name, err := resolveModulePath(o.root, module)
+	if err != nil {
+		return moduleOrigin{}, "", "", err
+	}
+
+	if o.library {
+		return o, StarlarkLibraryPrefix + name, name, nil
+	}
+
+	if o.repo == "" {
+		return o, name, name, nil
+	}
+
+	return o, ModuleLabel{Repo: o.repo, Ref: o.ref, Path: name}.String(), name, nil
+}
+
+// starlarkLoader resolves Starlark load statements through the source of the entry file.
+// Every module is executed at most once, the loader must not be shared between conversions.
+type starlarkLoader struct {
+	// ctx of the conversion, remote modules are fetched within it.
+	ctx          context.Context
+	entry        File
+	root         string
+	repositories RepositorySource
+	cache        *ModuleCache
+	programs     *ProgramCache
+	coverage     *Coverage
+	options      *syntax.FileOptions
+	predeclared  starlark.StringDict
+	// thread returns a new thread for a module.
+	thread func() *starlark.Thread
+	// threads created for the conversion, used to report exceeded limits.
+	threads []*starlark.Thread
+	// stops unregister the cancellation of the threads.
+	stops []func() bool
+	// the lock file of the entry file, read on the first remote module.
+	lock Lock
+	// sources of the executed files by name, used to report errors.
+	sources map[string]string
+	// loaded modules, a nil entry marks a module that is currently loading.
+	modules map[string]*starlarkModule
+	// files of the repository of the entry file, shared by all modules.
+	files *repoFiles
+	// maxSteps all threads may execute together, 0 disables the limit.
+	maxSteps uint64
+}
+
+// release unregisters the cancellation of the threads from the context of the conversion,
+// it is called once the conversion returns.
+func (l *starlarkLoader) release() {
+	for _, stop := range l.stops {
+		stop()
+	}
+	l.stops = nil
+}
+
+// limitSteps limits the thread to its steps and those the threads of the conversion have left,
+// it is called for new threads and for threads which continue after a module was loaded.
+func (l *starlarkLoader) limitSteps(thread *starlark.Thread) {
+	if l.maxSteps == 0 {
+		return
+	}
+
+	var left uint64
+	if steps := executionSteps(l.threads); steps < l.maxSteps {
+		left = l.maxSteps - steps
+	}
+
+	// a limit of 0 would disable the limit of a new thread
+	thread.SetMaxExecutionSteps(max(thread.ExecutionSteps()+left, 1))
+}
+
+type starlarkModule struct {
+	globals starlark.StringDict
+	err     error
+}
+
+// Load implements the starlark.Thread Load hook.
+func (l *starlarkLoader) Load(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+	origin, ok := thread.Local(starlarkOriginKey).(moduleOrigin)
+	if !ok {
+		origin = moduleOrigin{source: l.entry.Source, root: l.root}
+	}
+
+	next, key, name, err := origin.resolve(module)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := l.modules[key]; ok {
+		if m == nil {
+			return nil, fmt.Errorf("%w: %s", ErrLoadCycle, key)
+		}
+
+		return m.globals, m.err
+	}
+
+	l.modules[key] = nil
+	globals, err := l.exec(next, key, name)
+	l.modules[key] = &starlarkModule{globals: globals, err: err}
+	l.limitSteps(thread)
+
+	return globals, err
+}
+
+func (l *starlarkLoader) exec(origin moduleOrigin, key, name string) (starlark.StringDict, error) {
+	var data string
+	var err error
+	switch {
+	case origin.repo != "":
+		data, origin.source, err = l.readRemote(origin, key, name)
+	case origin.source == nil:
+		err = fmt.Errorf("%w: %s", ErrNoSource, key)
+	default:
+		var f File
+		f, err = origin.source.Read(name)
+		data = f.Data
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	l.sources[key] = data
+	child := l.thread()
+	child.SetLocal(starlarkOriginKey, origin)
+
+	return l.execFile(child, key, data)
+}
+
+// execFile executes the given file in the thread, with a program cache the file is only compiled once.
+// Test files are never instrumented for coverage.
+func (l *starlarkLoader) execFile(thread *starlark.Thread, name, data string) (starlark.StringDict, error) {
+	predeclared := l.predeclared
+	var program *starlark.Program
+	var err error
+	switch {
+	case l.coverage != nil && !strings.HasSuffix(name, StarlarkTestFileSuffix):
+		predeclared = l.coverage.predeclared(predeclared)
+		program, err = l.coverage.program(l.options, name, data, predeclared)
+	case l.programs != nil:
+		program, err = l.programs.Program(l.options, name, data, predeclared)
+	default:
+		return starlark.ExecFileOptions(l.options, thread, name, data, predeclared)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	globals, err := program.Init(thread, predeclared)
+	globals.Freeze()
+
+	return globals, err
+}
+
+// readRemote reads a remote module, its digest must match the lock file entry.
+// The returned source is used to resolve relative loads inside the remote module.
+func (l *starlarkLoader) readRemote(origin moduleOrigin, key, name string) (string, Source, error) {
+	if err := l.readLock(); err != nil {
+		return "", nil, err
+	}
+
+	digest, ok := l.lock[key]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %s, run wccs lock update", ErrModuleNotLocked, key)
+	}
+
+	var source Source
+	var err error
+	switch {
+	case origin.source != nil:
+		source = origin.source
+	case l.repositories != nil:
+		source, err = l.repositories.Repository(l.ctx, origin.repo, origin.ref)
+	default:
+		repositories, ok := l.entry.Source.(RepositorySource)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s", ErrNoSource, key)
+		}
+		source, err = repositories.Repository(l.ctx, origin.repo, origin.ref)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if l.cache != nil {
+		if data, ok := l.cache.Get(digest); ok {
+			return data, source, nil
+		}
+	}
+
+	f, err := source.Read(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if Digest(f.Data) != digest {
+		return "", nil, fmt.Errorf("%w: %s", ErrDigestMismatch, key)
+	}
+
+	if l.cache != nil {
+		if err := l.cache.Put(f.Data); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return f.Data, source, nil
+}
+
+func (l *starlarkLoader) readLock() error {
+	if l.lock != nil {
+		return nil
+	}
+
+	if l.entry.Source == nil {
+		return fmt.Errorf("%w: %s", ErrNoSource, lockFilePath(l.entry.Name))
+	}
+
+	f, err := l.entry.Source.Read(lockFilePath(l.entry.Name))
+	if err != nil {
+		return errors.Join(fmt.Errorf("%w: %s", ErrModuleNotLocked, lockFilePath(l.entry.Name)), err)
+	}
+
+	l.lock, err = ParseLock(f.Data)
+	return err
+}
+
+// resolveModulePath joins the module with the root and makes sure the result does not leave it.
+func resolveModulePath(root, module string) (string, error) {
+	module = path.Clean(module)
+	if module == "." || module == ".." || path.IsAbs(module) || strings.HasPrefix(module, "../") {
+		return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, module)
+	}
+
+	return path.Join(root, module), nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+// mapSource is an in memory source, it counts how often each file was read.
+type mapSource struct {
+	files map[string]string
+	reads map[string]int
+}
+
+func newMapSource(files map[string]string) *mapSource {
+	return &mapSource{files: files, reads: map[string]int{}}
+}
+
+func (s *mapSource) Read(name string) (wccs.File, error) {
+	data, ok := s.files[name]
+	if !ok {
+		return wccs.File{}, fs.ErrNotExist
+	}
+	s.reads[name]++
+
+	return wccs.File{Name: name, Data: data, Source: s}, nil
+}
+
+func TestStarlarkConverter_Load(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	entrypoint := func(source wccs.Source, data string) wccs.File {
+		return wccs.File{Name: "main.star", Data: data, Source: source}
+	}
+
+	t.Run("loads modules from the source", func(t *testing.T) {
+		source := newMapSource(map[string]string{
+			"lib/docker.star": `
+load("lib/names.star", "name")
+def docker_step():
+  return {"name": name, "image": "docker"}
+`,
+			"lib/names.star": `name = "build"`,
+		})
+
+		files, err := c.Convert(t.Context(), entrypoint(source, `
+load("lib/docker.star", "docker_step")
+load("lib/names.star", "name")
+def main(ctx):
+  return [{"name": name, "steps": [docker_step()]}]
+`), wccs.Environment{})
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "build.yaml", files[0].Name)
+		assert.Equal(t, "steps:\n  - name: build\n    image: docker\n", files[0].Data)
+
+		t.Run("caches modules per conversion", func(t *testing.T) {
+			assert.Equal(t, 1, source.reads["lib/names.star"])
+		})
+	})
+
+	t.Run("fails on cycles", func(t *testing.T) {
+		source := newMapSource(map[string]string{
+			"a.star": `load("b.star", "b")` + "\na = 1",
+			"b.star": `load("a.star", "a")` + "\nb = 1",
+		})
+
+		_, err := c.Convert(t.Context(), entrypoint(source, `load("a.star", "a")`), wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrLoadCycle)
+	})
+
+	t.Run("fails if the module leaves the root", func(t *testing.T) {
+		for _, module := range []string{"../secret.star", "lib/../../secret.star", "/etc/secret.star"} {
+			_, err := c.Convert(t.Context(), entrypoint(newMapSource(nil), `load("`+module+`", "secret")`), wccs.Environment{})
+			assert.ErrorIs(t, err, wccs.ErrPathNotAllowed, module)
+		}
+	})
+
+	t.Run("fails without a source", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Data: `load("lib.star", "lib")`}, wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrNoSource)
+	})
+
+	t.Run("fails if the module does not exist", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), entrypoint(newMapSource(nil), `load("lib.star", "lib")`), wccs.Environment{})
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("resolves modules relative to the configured root", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkLoadRoot(".woodpecker/lib"))
+		assert.NoError(t, err)
+
+		source := newMapSource(map[string]string{".woodpecker/lib/names.star": `name = "rooted"`})
+		files, err := c.Convert(t.Context(), entrypoint(source, `
+load("names.star", "name")
+def main(ctx):
+  return [{"name": name}]
+`), wccs.Environment{})
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "rooted.yaml", files[0].Name)
+	})
+}
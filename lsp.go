@@ -0,0 +1,791 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// JSON-RPC error codes which are used by the language server.
+const (
+	lspParseError     = -32700
+	lspInvalidRequest = -32600
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+	lspInternalError  = -32603
+)
+
+// LSP constants which are used by the language server.
+const (
+	lspTextDocumentSyncFull   = 1
+	lspSeverityError          = 1
+	lspSeverityWarning        = 2
+	lspCompletionItemFunction = 3
+	lspCompletionItemField    = 5
+	lspCompletionItemModule   = 9
+	lspCompletionItemConstant = 21
+)
+
+// lspMaxContentLength is the maximal size of a message the language server reads.
+const lspMaxContentLength = 16 << 20
+
+// lspMainParam finds the context parameter of main, the file often does not parse while it is edited.
+var lspMainParam = regexp.MustCompile(`(?m)^def\s+main\s*\(\s*([A-Za-z_][A-Za-z0-9_]*)`)
+
+// LanguageServer speaks the language server protocol for Starlark configuration files.
+// It reports the findings of the linter and completes and documents the context and the predeclared modules,
+// the documents which are open in the client take precedence over the files of the workspace.
+type LanguageServer struct {
+	converter StarlarkConverter
+	logger    *slog.Logger
+	// root of the workspace, documents are named relative to it.
+	root      string
+	documents map[string]string
+	out       io.Writer
+	shutdown  bool
+}
+
+// NewLanguageServer returns a language server which checks files like the given converter executes them.
+func NewLanguageServer(converter StarlarkConverter, logger *slog.Logger) *LanguageServer {
+	return &LanguageServer{converter: converter, logger: logger}
+}
+
+// lspMessage is a request, a notification or a response.
+type lspMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *lspError       `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *lspError) Error() string {
+	return e.Message
+}
+
+// lspPosition is a zero-based position, its character counts UTF-16 code units.
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type lspPositionParams struct {
+	TextDocument lspTextDocument `json:"textDocument"`
+	Position     lspPosition     `json:"position"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspCompletionItem struct {
+	Label         string `json:"label"`
+	Kind          int    `json:"kind"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+type lspMarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspHover struct {
+	Contents lspMarkupContent `json:"contents"`
+	Range    lspRange         `json:"range"`
+}
+
+// Serve reads messages from r and writes the responses and notifications to w,
+// it returns when the client exits, r ends or the context is done.
+func (s *LanguageServer) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.out = w
+	s.documents = map[string]string{}
+	s.shutdown = false
+
+	in := textproto.NewReader(bufio.NewReader(r))
+	for ctx.Err() == nil {
+		header, err := in.ReadMIMEHeader()
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return fmt.Errorf("%w: content length: %w", ErrUnsupportedType, err)
+		}
+
+		switch {
+		case length < 0:
+			return fmt.Errorf("%w: negative content length %d", ErrUnsupportedType, length)
+		case length > lspMaxContentLength:
+			return fmt.Errorf("%w: content length %d exceeds %d", ErrLimitExceeded, length, lspMaxContentLength)
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(in.R, data); err != nil {
+			return err
+		}
+
+		var msg lspMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			if err := s.write(lspMessage{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &lspError{Code: lspParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.dispatch(msg)
+		if msg.ID == nil {
+			if err != nil {
+				s.logger.Debug("notification failed", "method", msg.Method, "error", err)
+			}
+
+			continue
+		}
+
+		response := lspMessage{JSONRPC: "2.0", ID: msg.ID}
+		var rpcErr *lspError
+		switch {
+		case errors.As(err, &rpcErr):
+			response.Error = rpcErr
+		case err != nil:
+			response.Error = &lspError{Code: lspInvalidParams, Message: err.Error()}
+		default:
+			if response.Result, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+
+		if err := s.write(response); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+// write sends the message with its header.
+func (s *LanguageServer) write(msg lspMessage) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// notify sends a notification to the client.
+func (s *LanguageServer) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return s.write(lspMessage{Method: method, Params: data})
+}
+
+// dispatch handles the message, a panic fails the message instead of ending the session.
+func (s *LanguageServer) dispatch(msg lspMessage) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("message failed", "method", msg.Method, "panic", r)
+			result, err = nil, &lspError{Code: lspInternalError, Message: fmt.Sprintf("internal error: %v", r)}
+		}
+	}()
+
+	return s.handle(msg)
+}
+
+// handle dispatches the message and returns the result of requests.
+func (s *LanguageServer) handle(msg lspMessage) (any, error) {
+	if s.shutdown && msg.ID != nil {
+		return nil, &lspError{Code: lspInvalidRequest, Message: "the server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(msg.Params)
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   lspTextDocument   `json:"textDocument"`
+			ContentChanges []lspTextDocument `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+
+		// the full content is synchronized, the last change holds the current text
+		return nil, s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		name, err := s.name(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		delete(s.documents, name)
+
+		return nil, s.notify("textDocument/publishDiagnostics", map[string]any{"uri": params.TextDocument.URI, "diagnostics": []lspDiagnostic{}})
+	case "textDocument/completion", "textDocument/hover", "textDocument/definition":
+		var params lspPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		name, err := s.name(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		text, ok := s.documents[name]
+		if !ok {
+			return nil, nil
+		}
+
+		switch msg.Method {
+		case "textDocument/completion":
+			return s.completion(name, text, params.Position), nil
+		case "textDocument/hover":
+			return s.hover(name, text, params.Position), nil
+		default:
+			return s.definition(name, text, params.Position), nil
+		}
+	case "initialized", "$/cancelRequest", "$/setTrace", "textDocument/didSave", "workspace/didChangeConfiguration":
+		return nil, nil
+	default:
+		return nil, &lspError{Code: lspMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// initialize keeps the workspace root and returns the capabilities of the server.
+func (s *LanguageServer) initialize(data json.RawMessage) (any, error) {
+	var params struct {
+		RootURI          string `json:"rootUri"`
+		WorkspaceFolders []struct {
+			URI string `json:"uri"`
+		} `json:"workspaceFolders"`
+	}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+
+	root := params.RootURI
+	if len(params.WorkspaceFolders) != 0 {
+		root = params.WorkspaceFolders[0].URI
+	}
+
+	switch u, err := url.Parse(root); {
+	case root == "":
+		s.root = "."
+	case err != nil:
+		return nil, err
+	default:
+		s.root = filepath.FromSlash(u.Path)
+	}
+
+	var err error
+	if s.root, err = filepath.Abs(s.root); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync":   lspTextDocumentSyncFull,
+			"completionProvider": map[string]any{"triggerCharacters": []string{"."}},
+			"hoverProvider":      true,
+			"definitionProvider": true,
+		},
+		"serverInfo": map[string]any{"name": "wccs"},
+	}, nil
+}
+
+// name returns the name of the document relative to the workspace root.
+func (s *LanguageServer) name(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedType, uri)
+	}
+
+	name, err := filepath.Rel(s.root, filepath.FromSlash(u.Path))
+	if err != nil {
+		return "", err
+	}
+
+	name = filepath.ToSlash(name)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("%w: %s is outside of the workspace", ErrPathNotAllowed, uri)
+	}
+
+	return name, nil
+}
+
+// uri returns the URI of the document with the given name.
+func (s *LanguageServer) uri(name string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(s.root, filepath.FromSlash(name)))}).String()
+}
+
+// Read returns open documents with their current content, other files are read from the workspace.
+func (s *LanguageServer) Read(name string) (File, error) {
+	if text, ok := s.documents[name]; ok {
+		return File{Name: name, Data: text, Source: s}, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.root, filepath.FromSlash(name)))
+	if err != nil {
+		return File{}, err
+	}
+
+	return File{Name: name, Data: string(data), Source: s}, nil
+}
+
+// update keeps the content of the document and publishes its diagnostics.
+func (s *LanguageServer) update(uri, text string) error {
+	name, err := s.name(uri)
+	if err != nil {
+		return err
+	}
+	s.documents[name] = text
+
+	// a module which is opened on its own is not known to be loaded, it is not required to define main
+	definesMain := lspMainParam.MatchString(text)
+
+	diagnostics := []lspDiagnostic{}
+	for _, finding := range s.converter.Lint(File{Name: name, Data: text, Source: s}) {
+		if finding.File != name || (finding.Rule == "main" && !definesMain) {
+			continue
+		}
+
+		severity := lspSeverityWarning
+		if finding.Severity == LintSeverityError {
+			severity = lspSeverityError
+		}
+
+		start := lspPositionOf(text, int(finding.Line), int(finding.Column))
+		end := start
+		if line, col := lineColumn(text, start); col < len(line) {
+			end = lspPositionOf(text, int(finding.Line), wordEnd(line, col)+2) //nolint:mnd
+		}
+
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range:    lspRange{Start: start, End: end},
+			Severity: severity,
+			Code:     finding.Rule,
+			Source:   "wccs",
+			Message:  finding.Message,
+		})
+	}
+
+	return s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diagnostics})
+}
+
+// completion returns the attributes of the context or a predeclared module in front of the position,
+// or the predeclared names and builtins if no attribute is accessed.
+func (s *LanguageServer) completion(name, text string, pos lspPosition) []lspCompletionItem {
+	line, col := lineColumn(text, pos)
+	start := chainStart(line, col)
+	if start < 0 {
+		return []lspCompletionItem{}
+	}
+
+	path := strings.Split(string(line[start:col]), ".")
+	prefix := path[len(path)-1]
+
+	items := []lspCompletionItem{}
+	add := func(item lspCompletionItem) {
+		if strings.HasPrefix(item.Label, prefix) {
+			items = append(items, item)
+		}
+	}
+
+	if len(path) == 1 {
+		for _, predeclared := range []starlark.StringDict{s.converter.predeclared, starlark.Universe} {
+			for _, name := range predeclared.Keys() {
+				add(valueCompletion(name, predeclared[name]))
+			}
+		}
+
+		return items
+	}
+
+	if attribute, ok := s.contextAttribute(name, text, path[:len(path)-1]); ok {
+		for _, attribute := range attribute.Attributes {
+			add(lspCompletionItem{Label: attribute.Name, Kind: lspCompletionItemField, Detail: attribute.Type, Documentation: attribute.Doc})
+		}
+
+		return items
+	}
+
+	if v, ok := s.predeclaredValue(path[:len(path)-1]); ok {
+		if v, ok := v.(starlark.HasAttrs); ok {
+			for _, name := range v.AttrNames() {
+				if attr, err := v.Attr(name); err == nil && attr != nil {
+					add(valueCompletion(name, attr))
+				}
+			}
+		}
+	}
+
+	return items
+}
+
+// valueCompletion returns the completion item of a predeclared value.
+func valueCompletion(name string, v starlark.Value) lspCompletionItem {
+	kind := lspCompletionItemConstant
+	switch v.(type) {
+	case starlark.Callable:
+		kind = lspCompletionItemFunction
+	case starlark.HasAttrs:
+		kind = lspCompletionItemModule
+	}
+
+	return lspCompletionItem{Label: name, Kind: kind, Detail: v.Type()}
+}
+
+// hover documents the context attribute or the predeclared value under the position.
+func (s *LanguageServer) hover(name, text string, pos lspPosition) *lspHover {
+	line, col := lineColumn(text, pos)
+	if col >= len(line) || !isIdentRune(line[col]) {
+		return nil
+	}
+
+	start, end := chainStart(line, col), wordEnd(line, col)+1
+	if start < 0 {
+		return nil
+	}
+
+	path := strings.Split(string(line[start:end]), ".")
+	r := lspRange{
+		Start: lspPosition{Line: pos.Line, Character: utf16Len(line[:start])},
+		End:   lspPosition{Line: pos.Line, Character: utf16Len(line[:end])},
+	}
+
+	if attribute, ok := s.contextAttribute(name, text, path); ok {
+		return &lspHover{
+			Contents: lspMarkupContent{Kind: "markdown", Value: fmt.Sprintf("```\n%s: %s\n```\n%s", strings.Join(path, "."), attribute.Type, attribute.Doc)},
+			Range:    r,
+		}
+	}
+
+	if v, ok := s.predeclaredValue(path); ok {
+		return &lspHover{
+			Contents: lspMarkupContent{Kind: "markdown", Value: fmt.Sprintf("```\n%s: %s\n```", strings.Join(path, "."), v.Type())},
+			Range:    r,
+		}
+	}
+
+	return nil
+}
+
+// contextAttribute returns the schema of the context attribute with the given path,
+// its first element is the parameter of main or ctx. The drone context has no schema.
+func (s *LanguageServer) contextAttribute(name, text string, path []string) (ContextAttribute, bool) {
+	if s.converter.fileMode(File{Name: name}) == StarlarkModeDrone {
+		return ContextAttribute{}, false
+	}
+
+	param := "ctx"
+	if match := lspMainParam.FindStringSubmatch(text); match != nil {
+		param = match[1]
+	}
+	if path[0] != "ctx" && path[0] != param {
+		return ContextAttribute{}, false
+	}
+
+	attribute := StarlarkContextSchema
+	for _, name := range path[1:] {
+		next, ok := attribute.Attribute(name)
+		if !ok {
+			return ContextAttribute{}, false
+		}
+
+		attribute = next
+	}
+
+	return attribute, true
+}
+
+// predeclaredValue returns the predeclared value or module member with the given path.
+func (s *LanguageServer) predeclaredValue(path []string) (starlark.Value, bool) {
+	v, ok := s.converter.predeclared[path[0]]
+	if !ok {
+		return nil, false
+	}
+
+	for _, name := range path[1:] {
+		attrs, ok := v.(starlark.HasAttrs)
+		if !ok {
+			return nil, false
+		}
+
+		if v, _ = attrs.Attr(name); v == nil {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// definition returns the location the name under the position is defined at, loaded names are defined by their module.
+// The module of a load statement is located at its beginning.
+func (s *LanguageServer) definition(name, text string, pos lspPosition) *lspLocation {
+	f, err := syntax.LegacyFileOptions().Parse(name, text, 0)
+	if err != nil {
+		return nil
+	}
+
+	// the bindings are kept even if some names cannot be resolved
+	_ = resolve.File(f, s.converter.predeclared.Has, starlark.Universe.Has)
+
+	_, col := lineColumn(text, pos)
+	target := syntax.MakePosition(&name, int32(pos.Line+1), int32(col+1))
+
+	var id *syntax.Ident
+	var module *syntax.LoadStmt
+	walkSyntax(f, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.Ident:
+			if n.NamePos.Line == target.Line && n.NamePos.Col <= target.Col && target.Col <= n.NamePos.Col+int32(len([]rune(n.Name))) {
+				id = n
+			}
+		case *syntax.LoadStmt:
+			if start, end := n.Module.Span(); contains(start, end, target) {
+				module = n
+			}
+		}
+
+		return true
+	})
+
+	switch {
+	case module != nil:
+		return s.moduleDefinition(module.ModuleName(), "")
+	case id == nil:
+		return nil
+	}
+
+	binding, ok := id.Binding.(*resolve.Binding)
+	if !ok || binding.First == nil {
+		return nil
+	}
+
+	// names loaded from another module are defined by it
+	for _, stmt := range f.Stmts {
+		if load, ok := stmt.(*syntax.LoadStmt); ok {
+			if i := slices.Index(load.To, binding.First); i >= 0 {
+				return s.moduleDefinition(load.ModuleName(), load.From[i].Name)
+			}
+		}
+	}
+
+	return &lspLocation{URI: s.uri(name), Range: identRange(text, binding.First)}
+}
+
+// moduleDefinition returns the location of the global of the local module, or its beginning without a name.
+// Names which the module loads itself are not exported by it.
+func (s *LanguageServer) moduleDefinition(module, global string) *lspLocation {
+	if strings.HasPrefix(module, "@") {
+		return nil
+	}
+
+	name, err := resolveModulePath(s.converter.loadRoot, module)
+	if err != nil {
+		return nil
+	}
+
+	m, err := s.Read(name)
+	if err != nil {
+		return nil
+	}
+
+	if global == "" {
+		return &lspLocation{URI: s.uri(name)}
+	}
+
+	f, err := syntax.LegacyFileOptions().Parse(name, m.Data, 0)
+	if err != nil {
+		return nil
+	}
+
+	for _, stmt := range f.Stmts {
+		switch stmt := stmt.(type) {
+		case *syntax.DefStmt:
+			if stmt.Name.Name == global {
+				return &lspLocation{URI: s.uri(name), Range: identRange(m.Data, stmt.Name)}
+			}
+		case *syntax.AssignStmt:
+			if id, ok := stmt.LHS.(*syntax.Ident); ok && id.Name == global {
+				return &lspLocation{URI: s.uri(name), Range: identRange(m.Data, id)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// contains reports whether the position is within the span.
+func contains(start, end, pos syntax.Position) bool {
+	before := func(a, b syntax.Position) bool {
+		return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+	}
+
+	return !before(pos, start) && !before(end, pos)
+}
+
+// identRange returns the range of the identifier.
+func identRange(text string, id *syntax.Ident) lspRange {
+	start := lspPositionOf(text, int(id.NamePos.Line), int(id.NamePos.Col))
+
+	return lspRange{Start: start, End: lspPositionOf(text, int(id.NamePos.Line), int(id.NamePos.Col)+len([]rune(id.Name)))}
+}
+
+// lspPositionOf converts the one-based line and rune column of the text into a LSP position.
+func lspPositionOf(text string, line, col int) lspPosition {
+	runes := []rune(textLine(text, line-1))
+
+	return lspPosition{Line: line - 1, Character: utf16Len(runes[:min(max(col-1, 0), len(runes))])}
+}
+
+// lineColumn returns the line of the position and its rune column.
+func lineColumn(text string, pos lspPosition) ([]rune, int) {
+	line := []rune(textLine(text, pos.Line))
+
+	col, units := 0, 0
+	for col < len(line) && units < pos.Character {
+		units += utf16.RuneLen(line[col])
+		col++
+	}
+
+	return line, col
+}
+
+// textLine returns the zero-based line of the text.
+func textLine(text string, line int) string {
+	lines := strings.Split(text, "\n")
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+
+	return strings.TrimSuffix(lines[line], "\r")
+}
+
+func utf16Len(runes []rune) int {
+	return len(utf16.Encode(runes))
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// chainStart returns the start of the dotted names which end at the column,
+// it is negative for attributes of other expressions like calls, whose values are unknown.
+func chainStart(line []rune, col int) int {
+	start := col
+	for start > 0 && (isIdentRune(line[start-1]) || line[start-1] == '.') {
+		start--
+	}
+
+	if start < col && line[start] == '.' {
+		return -1
+	}
+
+	return start
+}
+
+// wordEnd returns the column of the last rune of the name at the column.
+func wordEnd(line []rune, col int) int {
+	end := col
+	for end+1 < len(line) && isIdentRune(line[end+1]) {
+		end++
+	}
+
+	return end
+}
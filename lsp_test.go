@@ -0,0 +1,307 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+// lspTestMessage is a message which is written by the language server.
+type lspTestMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+type lspTestRange struct {
+	Start struct{ Line, Character int } `json:"start"`
+	End   struct{ Line, Character int } `json:"end"`
+}
+
+// lspSession runs the language server with the given messages and returns all messages it wrote.
+func lspSession(t *testing.T, messages ...map[string]any) []lspTestMessage {
+	t.Helper()
+
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	in := new(bytes.Buffer)
+	for _, msg := range messages {
+		msg["jsonrpc"] = "2.0"
+		data, err := json.Marshal(msg)
+		assert.NoError(t, err)
+		_, _ = fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+
+	out := new(bytes.Buffer)
+	assert.NoError(t, wccs.NewLanguageServer(c, noopLogger).Serve(t.Context(), in, out))
+
+	var written []lspTestMessage
+	r := textproto.NewReader(bufio.NewReader(out))
+	for {
+		header, err := r.ReadMIMEHeader()
+		if errors.Is(err, io.EOF) {
+			return written
+		}
+		assert.NoError(t, err)
+
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		assert.NoError(t, err)
+
+		data := make([]byte, length)
+		_, err = io.ReadFull(r.R, data)
+		assert.NoError(t, err)
+
+		var msg lspTestMessage
+		assert.NoError(t, json.Unmarshal(data, &msg))
+		written = append(written, msg)
+	}
+}
+
+// lspResult decodes the result of the response with the given id.
+func lspResult(t *testing.T, messages []lspTestMessage, id int, result any) {
+	t.Helper()
+
+	for _, msg := range messages {
+		if msg.ID != nil && *msg.ID == id {
+			assert.Nil(t, msg.Error)
+			assert.NoError(t, json.Unmarshal(msg.Result, result))
+
+			return
+		}
+	}
+
+	t.Fatalf("no response %d", id)
+}
+
+func TestLanguageServer(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "lib.star"), []byte("load(\"images.star\", \"alpine\")\n\ndef image(event):\n  return alpine\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "images.star"), []byte("alpine = \"alpine\"\n"), 0o600))
+
+	rootURI := (&url.URL{Scheme: "file", Path: filepath.ToSlash(root)}).String()
+	mainURI := rootURI + "/main.star"
+	libURI := rootURI + "/lib.star"
+	main := `load("lib.star", "image")
+
+def main(ctx):
+  return [{"name": ctx.build.evnet, "image": image(ctx.repo.full_name), "x": json.encode(1)}]
+`
+
+	position := func(id int, method string, line, character int) map[string]any {
+		return map[string]any{"id": id, "method": "textDocument/" + method, "params": map[string]any{
+			"textDocument": map[string]any{"uri": mainURI},
+			"position":     map[string]any{"line": line, "character": character},
+		}}
+	}
+
+	messages := lspSession(t,
+		map[string]any{"id": 1, "method": "initialize", "params": map[string]any{"rootUri": rootURI}},
+		map[string]any{"method": "initialized", "params": map[string]any{}},
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{"textDocument": map[string]any{"uri": mainURI, "text": main}}},
+		position(2, "hover", 3, 62),
+		position(3, "definition", 3, 48),
+		position(4, "definition", 0, 8),
+		map[string]any{"method": "textDocument/didChange", "params": map[string]any{
+			"textDocument":   map[string]any{"uri": mainURI},
+			"contentChanges": []any{map[string]any{"text": main + "  ctx.repo.\n  ctx.build.ev\n  json.\n"}},
+		}},
+		position(5, "completion", 4, 11),
+		position(6, "completion", 5, 14),
+		position(7, "completion", 6, 7),
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{"textDocument": map[string]any{"uri": libURI, "text": "def image(event):\n  return alpine\n"}}},
+		map[string]any{"id": 8, "method": "unknown"},
+		map[string]any{"id": 9, "method": "shutdown"},
+		map[string]any{"method": "exit"},
+	)
+
+	var initialize struct {
+		Capabilities struct {
+			HoverProvider      bool `json:"hoverProvider"`
+			DefinitionProvider bool `json:"definitionProvider"`
+		} `json:"capabilities"`
+	}
+	lspResult(t, messages, 1, &initialize)
+	assert.True(t, initialize.Capabilities.HoverProvider)
+	assert.True(t, initialize.Capabilities.DefinitionProvider)
+
+	t.Run("diagnostics", func(t *testing.T) {
+		var diagnostics []string
+		for _, msg := range messages {
+			if msg.Method != "textDocument/publishDiagnostics" {
+				continue
+			}
+
+			var params struct {
+				URI         string `json:"uri"`
+				Diagnostics []struct {
+					Range    lspTestRange `json:"range"`
+					Severity int          `json:"severity"`
+					Code     string       `json:"code"`
+					Message  string       `json:"message"`
+				} `json:"diagnostics"`
+			}
+			assert.NoError(t, json.Unmarshal(msg.Params, &params))
+
+			for _, d := range params.Diagnostics {
+				diagnostics = append(diagnostics, fmt.Sprintf("%s %d:%d-%d:%d %d %s: %s", filepath.Base(params.URI),
+					d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Line, d.Range.End.Character, d.Severity, d.Code, d.Message))
+			}
+		}
+
+		assert.Equal(t, []string{
+			"main.star 3:29-3:34 1 context: ctx.build.evnet is not an attribute of the context",
+			"main.star 5:0-5:1 1 syntax: not an identifier",
+			// modules are not required to define main
+			"lib.star 1:9-1:15 1 undefined: undefined: alpine",
+		}, diagnostics)
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		repo, _ := wccs.StarlarkContextSchema.Attribute("repo")
+		fullName, _ := repo.Attribute("full_name")
+
+		var hover struct {
+			Contents struct {
+				Value string `json:"value"`
+			} `json:"contents"`
+			Range lspTestRange `json:"range"`
+		}
+		lspResult(t, messages, 2, &hover)
+		assert.Equal(t, "```\nctx.repo.full_name: string\n```\n"+fullName.Doc, hover.Contents.Value)
+		assert.Equal(t, [4]int{3, 51, 3, 69}, [4]int{hover.Range.Start.Line, hover.Range.Start.Character, hover.Range.End.Line, hover.Range.End.Character})
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var location struct {
+			URI   string       `json:"uri"`
+			Range lspTestRange `json:"range"`
+		}
+		lspResult(t, messages, 3, &location)
+		assert.Equal(t, libURI, location.URI)
+		assert.Equal(t, [4]int{2, 4, 2, 9}, [4]int{location.Range.Start.Line, location.Range.Start.Character, location.Range.End.Line, location.Range.End.Character})
+
+		lspResult(t, messages, 4, &location)
+		assert.Equal(t, libURI, location.URI)
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		labels := func(id int) []string {
+			var items []struct {
+				Label string `json:"label"`
+			}
+			lspResult(t, messages, id, &items)
+
+			var labels []string
+			for _, item := range items {
+				labels = append(labels, item.Label)
+			}
+
+			return labels
+		}
+
+		repo, _ := wccs.StarlarkContextSchema.Attribute("repo")
+		assert.Contains(t, labels(5), "full_name")
+		assert.Len(t, labels(5), len(repo.Attributes))
+		assert.Equal(t, []string{"event", "event_reason"}, labels(6))
+		assert.Contains(t, labels(7), "encode")
+	})
+
+	unknown := messages[len(messages)-2]
+	assert.Equal(t, -32601, unknown.Error.Code)
+
+	var shutdown any
+	lspResult(t, messages, 9, &shutdown)
+	assert.Nil(t, shutdown)
+}
+
+func TestLanguageServer_ContentLength(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	for length, expected := range map[string]error{
+		"abc":        wccs.ErrUnsupportedType,
+		"-1":         wccs.ErrUnsupportedType,
+		"1073741824": wccs.ErrLimitExceeded,
+	} {
+		in := strings.NewReader("Content-Length: " + length + "\r\n\r\n{}")
+		err := wccs.NewLanguageServer(c, noopLogger).Serve(t.Context(), in, io.Discard)
+		assert.ErrorIs(t, err, expected, length)
+	}
+}
+
+func TestLanguageServer_WhileLoops(t *testing.T) {
+	rootURI := (&url.URL{Scheme: "file", Path: filepath.ToSlash(t.TempDir())}).String()
+	mainURI := rootURI + "/main.star"
+
+	messages := lspSession(t,
+		map[string]any{"id": 1, "method": "initialize", "params": map[string]any{"rootUri": rootURI}},
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{"textDocument": map[string]any{
+			"uri":  mainURI,
+			"text": "def main(ctx):\n  n = 1\n  while n > 0:\n    n -= 1\n  return []\n",
+		}}},
+		map[string]any{"id": 2, "method": "textDocument/definition", "params": map[string]any{
+			"textDocument": map[string]any{"uri": mainURI},
+			"position":     map[string]any{"line": 3, "character": 4},
+		}},
+		map[string]any{"method": "exit"},
+	)
+
+	var codes []string
+	for _, msg := range messages {
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+
+		var params struct {
+			Diagnostics []struct {
+				Code string `json:"code"`
+			} `json:"diagnostics"`
+		}
+		assert.NoError(t, json.Unmarshal(msg.Params, &params))
+		for _, d := range params.Diagnostics {
+			codes = append(codes, d.Code)
+		}
+	}
+	assert.Equal(t, []string{"syntax"}, codes)
+
+	var location struct {
+		URI   string       `json:"uri"`
+		Range lspTestRange `json:"range"`
+	}
+	lspResult(t, messages, 2, &location)
+	assert.Equal(t, mainURI, location.URI)
+	assert.Equal(t, [4]int{1, 2, 1, 3}, [4]int{location.Range.Start.Line, location.Range.Start.Character, location.Range.End.Line, location.Range.End.Character})
+}
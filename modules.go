@@ -0,0 +1,393 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"strings"
+
+	starlarkjson "go.starlark.net/lib/json"
+	starlarkmath "go.starlark.net/lib/math"
+	starlarktime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"gopkg.in/yaml.v3"
+)
+
+// StarlarkModule is the name of a predeclared Starlark module.
+type StarlarkModule string
+
+const (
+	// StarlarkModuleJSON provides json.encode, json.decode and json.indent.
+	StarlarkModuleJSON StarlarkModule = "json"
+	// StarlarkModuleYAML provides yaml.encode and yaml.decode.
+	StarlarkModuleYAML StarlarkModule = "yaml"
+	// StarlarkModuleRE provides regular expressions in RE2 syntax.
+	StarlarkModuleRE StarlarkModule = "re"
+	// StarlarkModuleBase64 provides base64.encode and base64.decode.
+	StarlarkModuleBase64 StarlarkModule = "base64"
+	// StarlarkModuleHashlib provides hex encoded sha256, sha1 and md5 digests.
+	StarlarkModuleHashlib StarlarkModule = "hashlib"
+	// StarlarkModuleMath provides the math functions and constants.
+	StarlarkModuleMath StarlarkModule = "math"
+	// StarlarkModuleTime provides time parsing and formatting, time.now returns the pipeline timestamp.
+	StarlarkModuleTime StarlarkModule = "time"
+	// StarlarkModuleSemver provides semver.parse and semver.compare.
+	StarlarkModuleSemver StarlarkModule = "semver"
+	// StarlarkModuleStruct provides the struct and module constructors.
+	StarlarkModuleStruct StarlarkModule = "struct"
+	// StarlarkModuleFiles provides read_file, list_dir and exists for the repository of the entry file.
+	StarlarkModuleFiles StarlarkModule = "files"
+)
+
+// StarlarkModules contains all available predeclared modules.
+var StarlarkModules = []StarlarkModule{
+	StarlarkModuleJSON,
+	StarlarkModuleYAML,
+	StarlarkModuleRE,
+	StarlarkModuleBase64,
+	StarlarkModuleHashlib,
+	StarlarkModuleMath,
+	StarlarkModuleTime,
+	StarlarkModuleSemver,
+	StarlarkModuleStruct,
+	StarlarkModuleFiles,
+}
+
+// starlarkModules returns the predeclared values for the given modules.
+func starlarkModules(modules []StarlarkModule) (starlark.StringDict, error) {
+	predeclared := starlark.StringDict{}
+	for _, module := range modules {
+		switch module {
+		case StarlarkModuleJSON:
+			predeclared["json"] = starlarkjson.Module
+		case StarlarkModuleYAML:
+			predeclared["yaml"] = yamlModule
+		case StarlarkModuleRE:
+			predeclared["re"] = reModule
+		case StarlarkModuleBase64:
+			predeclared["base64"] = base64Module
+		case StarlarkModuleHashlib:
+			predeclared["hashlib"] = hashlibModule
+		case StarlarkModuleMath:
+			predeclared["math"] = starlarkmath.Module
+		case StarlarkModuleTime:
+			predeclared["time"] = starlarktime.Module
+		case StarlarkModuleSemver:
+			predeclared["semver"] = semverModule
+		case StarlarkModuleStruct:
+			predeclared["struct"] = starlark.NewBuiltin("struct", starlarkstruct.Make)
+			predeclared["module"] = starlark.NewBuiltin("module", starlarkstruct.MakeModule)
+		case StarlarkModuleFiles:
+			predeclared["read_file"] = readFileBuiltin
+			predeclared["list_dir"] = listDirBuiltin
+			predeclared["exists"] = existsBuiltin
+		default:
+			return nil, fmt.Errorf("%w: starlark module %s", ErrUnknownType, module)
+		}
+	}
+
+	return predeclared, nil
+}
+
+var yamlModule = &starlarkstruct.Module{
+	Name: "yaml",
+	Members: starlark.StringDict{
+		"encode": starlark.NewBuiltin("yaml.encode", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var v starlark.Value
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &v); err != nil {
+				return nil, err
+			}
+
+			node, err := StarlarkToYAML(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			out, err := encodeYAML(node)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			return starlark.String(out), nil
+		}),
+		"decode": starlark.NewBuiltin("yaml.decode", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+				return nil, err
+			}
+
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(s), &node); err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			v, err := YAMLToStarlark(&node)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			return v, nil
+		}),
+		"decode_all": starlark.NewBuiltin("yaml.decode_all", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+				return nil, err
+			}
+
+			documents, err := decodeYAMLDocuments(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			return starlark.NewList(documents), nil
+		}),
+	},
+}
+
+// decodeYAMLDocuments decodes all documents of a multi document YAML stream.
+func decodeYAMLDocuments(s string) ([]starlark.Value, error) {
+	var documents []starlark.Value
+	dec := yaml.NewDecoder(strings.NewReader(s))
+	for {
+		var node yaml.Node
+		switch err := dec.Decode(&node); {
+		case errors.Is(err, io.EOF):
+			return documents, nil
+		case err != nil:
+			return nil, err
+		}
+
+		v, err := YAMLToStarlark(&node)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, v)
+	}
+}
+
+var reModule = &starlarkstruct.Module{
+	Name: "re",
+	Members: starlark.StringDict{
+		"match":   starlark.NewBuiltin("re.match", reFind),
+		"search":  starlark.NewBuiltin("re.search", reFind),
+		"findall": starlark.NewBuiltin("re.findall", reFindAll),
+		"sub":     starlark.NewBuiltin("re.sub", reSub),
+		"split":   starlark.NewBuiltin("re.split", reSplit),
+		"escape": starlark.NewBuiltin("re.escape", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+				return nil, err
+			}
+
+			return starlark.String(regexp.QuoteMeta(s)), nil
+		}),
+	},
+}
+
+func compileRE(b *starlark.Builtin, pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	return re, nil
+}
+
+// reFind returns a tuple of the match and its groups, or None.
+// re.match only matches at the beginning of the string, re.search anywhere.
+func reFind(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "string", &s); err != nil {
+		return nil, err
+	}
+
+	if b.Name() == "re.match" {
+		pattern = `\A(?:` + pattern + `)`
+	}
+
+	re, err := compileRE(b, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	match := re.FindStringSubmatch(s)
+	if match == nil {
+		return starlark.None, nil
+	}
+
+	groups := make(starlark.Tuple, len(match))
+	for i, group := range match {
+		groups[i] = starlark.String(group)
+	}
+
+	return groups, nil
+}
+
+// reFindAll returns all matches, for patterns with groups each match is a tuple of its groups.
+func reFindAll(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "string", &s); err != nil {
+		return nil, err
+	}
+
+	re, err := compileRE(b, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []starlark.Value
+	for _, match := range re.FindAllStringSubmatch(s, -1) {
+		switch len(match) {
+		case 1:
+			matches = append(matches, starlark.String(match[0]))
+		case 2: //nolint:mnd
+			matches = append(matches, starlark.String(match[1]))
+		default:
+			groups := make(starlark.Tuple, len(match)-1)
+			for i, group := range match[1:] {
+				groups[i] = starlark.String(group)
+			}
+			matches = append(matches, groups)
+		}
+	}
+
+	return starlark.NewList(matches), nil
+}
+
+// reSub replaces the matches of the pattern, the replacement may reference groups with $1 or ${name}.
+func reSub(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, repl, s string
+	var count int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "repl", &repl, "string", &s, "count?", &count); err != nil {
+		return nil, err
+	}
+
+	re, err := compileRE(b, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := -1
+	if count > 0 {
+		limit = count
+	}
+
+	var out []byte
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(s, limit) {
+		out = append(out, s[last:match[0]]...)
+		out = re.ExpandString(out, repl, s, match)
+		last = match[1]
+	}
+	out = append(out, s[last:]...)
+
+	return starlark.String(out), nil
+}
+
+func reSplit(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	var maxsplit int
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "string", &s, "maxsplit?", &maxsplit); err != nil {
+		return nil, err
+	}
+
+	re, err := compileRE(b, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	n := -1
+	if maxsplit > 0 {
+		n = maxsplit + 1
+	}
+
+	var parts []starlark.Value
+	for _, part := range re.Split(s, n) {
+		parts = append(parts, starlark.String(part))
+	}
+
+	return starlark.NewList(parts), nil
+}
+
+var base64Module = &starlarkstruct.Module{
+	Name: "base64",
+	Members: starlark.StringDict{
+		"encode": starlark.NewBuiltin("base64.encode", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			var urlsafe bool
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "s", &s, "urlsafe?", &urlsafe); err != nil {
+				return nil, err
+			}
+
+			return starlark.String(base64Encoding(urlsafe).EncodeToString([]byte(s))), nil
+		}),
+		"decode": starlark.NewBuiltin("base64.decode", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			var urlsafe bool
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "s", &s, "urlsafe?", &urlsafe); err != nil {
+				return nil, err
+			}
+
+			data, err := base64Encoding(urlsafe).DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			return starlark.String(data), nil
+		}),
+	},
+}
+
+func base64Encoding(urlsafe bool) *base64.Encoding {
+	if urlsafe {
+		return base64.URLEncoding
+	}
+
+	return base64.StdEncoding
+}
+
+var hashlibModule = &starlarkstruct.Module{
+	Name: "hashlib",
+	Members: starlark.StringDict{
+		"sha256": hashBuiltin("hashlib.sha256", sha256.New),
+		"sha1":   hashBuiltin("hashlib.sha1", sha1.New),
+		"md5":    hashBuiltin("hashlib.md5", md5.New),
+	},
+}
+
+// hashBuiltin returns a builtin which returns the hex encoded digest of a string.
+func hashBuiltin(name string, h func() hash.Hash) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var s string
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+			return nil, err
+		}
+
+		digest := h()
+		_, _ = digest.Write([]byte(s))
+
+		return starlark.String(hex.EncodeToString(digest.Sum(nil))), nil
+	})
+}
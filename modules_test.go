@@ -0,0 +1,121 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+// execModules executes the given statements and returns the string representation of the result global.
+func execModules(t *testing.T, c wccs.StarlarkConverter, env wccs.Environment, src string) (string, error) {
+	t.Helper()
+
+	globals, _, err := c.Exec(t.Context(), wccs.File{Name: "modules.star", Data: src}, env)
+	if err != nil {
+		return "", err
+	}
+
+	return globals["result"].String(), nil
+}
+
+func TestStarlarkModules(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	check := func(t *testing.T, src, expected string) {
+		t.Helper()
+
+		result, err := execModules(t, c, wccs.Environment{Pipeline: model.Pipeline{Timestamp: 1700000000}}, src)
+		assert.NoError(t, err, src)
+		assert.Equal(t, expected, result, src)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		check(t, `result = json.encode({"a": [1, True, None]})`, `"{\"a\":[1,true,null]}"`)
+		check(t, `result = json.decode('{"b": 2, "a": 1}')`, `{"b": 2, "a": 1}`)
+		check(t, `result = json.indent('{"a":1}', indent="  ")`, `"{\n  \"a\": 1\n}"`)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		check(t, `result = yaml.encode({"b": [1, "True"], "a": None})`, `"b:\n  - 1\n  - \"True\"\na: null\n"`)
+		check(t, `result = yaml.decode("b: 1\na: [x, 2.5, true, ~]\n")`, `{"b": 1, "a": ["x", 2.5, True, None]}`)
+		check(t, `result = yaml.decode("base: &base {image: golang, pull: true}\nstep:\n  <<: *base\n  image: alpine\n")["step"]`, `{"image": "alpine", "pull": True}`)
+		check(t, `result = yaml.decode_all("a: 1\n---\nb: 2\n")`, `[{"a": 1}, {"b": 2}]`)
+	})
+
+	t.Run("re", func(t *testing.T) {
+		check(t, `result = re.match("v(\\d+)", "v12.3")`, `("v12", "12")`)
+		check(t, `result = re.match("\\d+", "v12")`, `None`)
+		check(t, `result = re.search("\\d+", "v12")`, `("12",)`)
+		check(t, `result = re.findall("(\\w)=(\\d)", "a=1 b=2")`, `[("a", "1"), ("b", "2")]`)
+		check(t, `result = re.sub("(\\d)", "<$1>", "a1b2c3", count=2)`, `"a<1>b<2>c3"`)
+		check(t, `result = re.split(",\\s*", "a, b,c", maxsplit=1)`, `["a", "b,c"]`)
+		check(t, `result = re.escape("a.b")`, `"a\\.b"`)
+
+		_, err := execModules(t, c, wccs.Environment{}, `re.match("(", "")`)
+		assert.ErrorContains(t, err, "re.match")
+	})
+
+	t.Run("base64", func(t *testing.T) {
+		check(t, `result = base64.encode("wccs?")`, `"d2Njcz8="`)
+		check(t, `result = base64.encode("??>", urlsafe=True)`, `"Pz8-"`)
+		check(t, `result = base64.decode("d2Njcz8=")`, `"wccs?"`)
+	})
+
+	t.Run("hashlib", func(t *testing.T) {
+		check(t, `result = hashlib.sha256("wccs")`, `"c724ce52c1b8e6344488360ce2830b296e2983f15d82beb12e6430e9f2a45980"`)
+		check(t, `result = hashlib.md5("wccs")`, `"430f3b9733d7209f1356f9d05609fd5f"`)
+	})
+
+	t.Run("math", func(t *testing.T) {
+		check(t, `result = math.ceil(1.2)`, `2`)
+	})
+
+	t.Run("time", func(t *testing.T) {
+		check(t, `result = time.now().unix`, `1700000000`)
+		check(t, `result = time.parse_time("2024-01-02T03:04:05Z").format("2006-01-02")`, `"2024-01-02"`)
+	})
+
+	t.Run("semver", func(t *testing.T) {
+		check(t, `result = semver.parse("v1.2.3-rc.1+build.5")`, `semver(build = "build.5", major = 1, minor = 2, patch = 3, prerelease = "rc.1")`)
+		check(t, `result = [semver.compare("1.2.3", "1.10.0"), semver.compare("1.0.0", "1.0.0-rc.1"), semver.compare("1.0.0-rc.2", "1.0.0-rc.10"), semver.compare("1.0.0+a", "1.0.0+b")]`, `[-1, 1, -1, 0]`)
+		check(t, `result = [semver.is_valid("1.2"), semver.is_valid("01.2.3"), semver.is_valid("v1.2.3")]`, `[False, False, True]`)
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		check(t, `result = struct(a = 1).a + module("m", b = 2).b`, `3`)
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkModules(wccs.StarlarkModuleJSON))
+		assert.NoError(t, err)
+
+		_, err = execModules(t, c, wccs.Environment{}, `result = json.encode(1)`)
+		assert.NoError(t, err)
+
+		_, err = execModules(t, c, wccs.Environment{}, `result = yaml.encode(1)`)
+		assert.ErrorContains(t, err, "undefined: yaml")
+	})
+
+	t.Run("fails on unknown modules", func(t *testing.T) {
+		_, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkModules("unknown"))
+		assert.ErrorIs(t, err, wccs.ErrUnknownType)
+	})
+}
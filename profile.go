@@ -0,0 +1,97 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// profileNameReplacer matches the characters which are replaced in profile file names.
+var profileNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// StarlarkProfiler writes CPU profiles of slow Starlark conversions to a directory, they can be read by go tool pprof.
+// The Starlark profiler is process wide and must not be started while Starlark is executed,
+// conversions of a converter with a profiler are therefore executed one after another.
+type StarlarkProfiler struct {
+	logger    *slog.Logger
+	dir       string
+	threshold time.Duration
+	// sem is held by the profiled conversion.
+	sem chan struct{}
+}
+
+// NewStarlarkProfiler returns a profiler which keeps the profiles of conversions which take at least the threshold.
+func NewStarlarkProfiler(dir string, threshold time.Duration, logger *slog.Logger) (*StarlarkProfiler, error) {
+	if err := os.MkdirAll(dir, 0o770); err != nil { //nolint: mnd
+		return nil, err
+	}
+
+	return &StarlarkProfiler{logger: logger, dir: dir, threshold: threshold, sem: make(chan struct{}, 1)}, nil
+}
+
+// profile runs f with the Starlark profiler and writes the profile if it took at least the threshold.
+// Failing to profile does not fail f, the failure is logged.
+func (p *StarlarkProfiler) profile(ctx context.Context, name string, f func() error) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	select {
+	case p.sem <- struct{}{}:
+		defer func() {
+			<-p.sem
+		}()
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := starlark.StartProfile(buf); err != nil {
+		p.logger.Warn("failed to start the starlark profiler", "error", err)
+		return f()
+	}
+
+	start := time.Now()
+	err := f()
+	elapsed := time.Since(start)
+
+	if stopErr := starlark.StopProfile(); stopErr != nil {
+		p.logger.Warn("failed to stop the starlark profiler", "error", stopErr)
+		return err
+	}
+
+	if elapsed < p.threshold {
+		return err
+	}
+
+	fp := filepath.Join(p.dir, fmt.Sprintf("%s-%s.pprof", start.UTC().Format("20060102T150405.000000000"), profileNameReplacer.ReplaceAllString(name, "_")))
+	if writeErr := os.WriteFile(fp, buf.Bytes(), 0o660); writeErr != nil { //nolint: mnd
+		p.logger.Warn("failed to write the starlark profile", "path", fp, "error", writeErr)
+		return err
+	}
+
+	p.logger.Info("wrote starlark profile", "path", fp, "duration", elapsed)
+
+	return err
+}
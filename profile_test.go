@@ -0,0 +1,98 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkProfiler(t *testing.T) {
+	f := wccs.File{Name: ".woodpecker/build.star", Data: `
+def main(ctx):
+  return {"steps": [{"name": "step-%d" % i, "image": "alpine"} for i in range(1000)]}
+`}
+	env := wccs.Environment{Repo: model.Repo{FullName: "opencloud-eu/wccs"}}
+
+	t.Run("writes profiles of slow conversions", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "profiles")
+		profiler, err := wccs.NewStarlarkProfiler(dir, 0, noopLogger)
+		assert.NoError(t, err)
+
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkProfiler(profiler))
+		assert.NoError(t, err)
+
+		// conversions are serialized, the profiler is never started twice
+		var wg sync.WaitGroup
+		for range 3 {
+			wg.Go(func() {
+				files, err := c.Convert(t.Context(), f, env)
+				assert.NoError(t, err)
+				assert.Len(t, files, 1)
+			})
+		}
+		wg.Wait()
+
+		profiles, err := filepath.Glob(filepath.Join(dir, "*-opencloud-eu_wccs-.woodpecker_build.star.pprof"))
+		assert.NoError(t, err)
+		assert.Len(t, profiles, 3)
+
+		data, err := os.ReadFile(profiles[0])
+		assert.NoError(t, err)
+		// profiles are gzip compressed protocol buffers
+		assert.Equal(t, []byte{0x1f, 0x8b}, data[:2])
+	})
+
+	t.Run("discards profiles of fast conversions", func(t *testing.T) {
+		dir := t.TempDir()
+		profiler, err := wccs.NewStarlarkProfiler(dir, time.Hour, noopLogger)
+		assert.NoError(t, err)
+
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkProfiler(profiler))
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), f, env)
+		assert.NoError(t, err)
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("returns errors of the conversion", func(t *testing.T) {
+		profiler, err := wccs.NewStarlarkProfiler(t.TempDir(), 0, noopLogger)
+		assert.NoError(t, err)
+
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkProfiler(profiler))
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "broken.star", Data: "def main(ctx):\n  fail(\"broken\")\n"}, env)
+		assert.ErrorContains(t, err, "broken")
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		_, err = c.Convert(ctx, f, env)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
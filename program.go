@@ -0,0 +1,123 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"container/list"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// ProgramCacheStats are the counters of a ProgramCache.
+type ProgramCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+// LogValue implements slog.LogValuer, the counters are logged as group.
+func (s ProgramCacheStats) LogValue() slog.Value {
+	return slog.GroupValue(slog.Uint64("hits", s.Hits), slog.Uint64("misses", s.Misses), slog.Int("entries", s.Entries))
+}
+
+// ProgramCache is a least recently used cache of compiled Starlark programs,
+// it is safe for concurrent use and can be shared between converters.
+type ProgramCache struct {
+	size    int
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	hits    uint64
+	misses  uint64
+}
+
+type programCacheEntry struct {
+	key     string
+	program *starlark.Program
+}
+
+// NewProgramCache returns a ProgramCache which keeps at most size programs.
+func NewProgramCache(size int) (*ProgramCache, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: program cache size must be positive, got %d", ErrMissingParam, size)
+	}
+
+	return &ProgramCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}, nil
+}
+
+// Stats returns the current counters of the cache.
+func (c *ProgramCache) Stats() ProgramCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return ProgramCacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len()}
+}
+
+// Program returns the compiled program of the given file, it is compiled on a miss.
+// The file name is part of the key because positions of the program refer to it.
+func (c *ProgramCache) Program(options *syntax.FileOptions, name, data string, predeclared starlark.StringDict) (*starlark.Program, error) {
+	names := predeclared.Keys()
+	slices.Sort(names)
+	key := Digest(strings.Join([]string{fmt.Sprintf("%+v", *options), strings.Join(names, ","), name, data}, "\x00"))
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.hits++
+		c.order.MoveToFront(element)
+		c.mu.Unlock()
+
+		return element.Value.(*programCacheEntry).program, nil //nolint:forcetypeassert
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// compile without holding the lock, concurrent misses of the same file compile twice which is harmless
+	program, err := compileProgram(options, name, data, predeclared)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(&programCacheEntry{key: key, program: program})
+	}
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*programCacheEntry).key) //nolint:forcetypeassert
+	}
+
+	return program, nil
+}
+
+// compileProgram parses and resolves the given file.
+func compileProgram(options *syntax.FileOptions, name, data string, predeclared starlark.StringDict) (*starlark.Program, error) {
+	_, program, err := starlark.SourceProgramOptions(options, name, data, predeclared.Has)
+
+	return program, err
+}
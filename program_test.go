@@ -0,0 +1,95 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestProgramCache(t *testing.T) {
+	_, err := wccs.NewProgramCache(0)
+	assert.ErrorIs(t, err, wccs.ErrMissingParam)
+
+	cache, err := wccs.NewProgramCache(2)
+	assert.NoError(t, err)
+
+	options := syntax.LegacyFileOptions()
+	program := func(name string) *starlark.Program {
+		t.Helper()
+
+		p, err := cache.Program(options, name, "x = 1", starlark.StringDict{})
+		assert.NoError(t, err)
+
+		return p
+	}
+
+	a := program("a.star")
+	assert.Same(t, a, program("a.star"))
+	program("b.star")
+	program("c.star")
+	assert.Equal(t, wccs.ProgramCacheStats{Hits: 1, Misses: 3, Entries: 2}, cache.Stats())
+
+	// a is the least recently used program and was evicted
+	assert.NotSame(t, a, program("a.star"))
+
+	_, err = cache.Program(options, "broken.star", "x = ", starlark.StringDict{})
+	assert.Error(t, err)
+	assert.Equal(t, 2, cache.Stats().Entries)
+}
+
+func TestStarlarkConverter_ProgramCache(t *testing.T) {
+	cache, err := wccs.NewProgramCache(10)
+	assert.NoError(t, err)
+
+	c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkProgramCache(cache))
+	assert.NoError(t, err)
+
+	source := newMapSource(map[string]string{"lib.star": `image = "golang"`})
+	for range 3 {
+		files, err := c.Convert(t.Context(), wccs.File{Name: "main.star", Data: `
+load("lib.star", "image")
+def main(ctx):
+  return {"steps": [{"name": "test", "image": image}]}
+`, Source: source}, wccs.Environment{})
+		assert.NoError(t, err)
+		assert.Equal(t, "steps:\n  - name: test\n    image: golang\n", files[0].Data)
+	}
+	assert.Equal(t, wccs.ProgramCacheStats{Hits: 4, Misses: 2, Entries: 2}, cache.Stats())
+
+	t.Run("logs the counters", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		c, err := wccs.NewStarlarkConverter(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})), wccs.WithStarlarkProgramCache(cache))
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "main.star", Data: "def main(ctx):\n  return []\n"}, wccs.Environment{})
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "file=main.star program_cache.hits=4 program_cache.misses=3 program_cache.entries=3")
+	})
+
+	t.Run("reports errors with the file name", func(t *testing.T) {
+		for _, name := range []string{"a.star", "b.star"} {
+			_, err := c.Convert(t.Context(), wccs.File{Name: name, Data: "def main(ctx):\n  fail(\"broken\")\n"}, wccs.Environment{})
+			assert.ErrorContains(t, err, name+":2:7")
+		}
+	})
+}
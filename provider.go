@@ -22,6 +22,7 @@ import (
 	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"sync"
 
 	"github.com/bmatcuk/doublestar/v4"
@@ -113,11 +114,41 @@ func (p ForgeProvider) Get(ctx context.Context, env Environment) ([]File, error)
 	}
 
 	return []File{{
-		Name: env.Repo.Config,
-		Data: string(data),
+		Name:   env.Repo.Config,
+		Data:   string(data),
+		Source: forgeSource{ctx: ctx, forge: f, env: env},
 	}}, nil
 }
 
+// Repositories returns a source for the repositories of the forge of the given environment.
+func (p ForgeProvider) Repositories(ctx context.Context, env Environment) (RepositorySource, error) {
+	f, ok := p.forges[env.Netrc.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, env.Netrc.Type)
+	}
+
+	return forgeSource{ctx: ctx, forge: f, env: env}, nil
+}
+
+// forgeSource reads files from the forge repository at the pipeline commit.
+type forgeSource struct {
+	ctx   context.Context
+	forge forge.Forge
+	env   Environment
+}
+
+// Read returns the file with the given name from the forge repository.
+func (s forgeSource) Read(name string) (File, error) {
+	data, err := s.forge.File(s.ctx, &model.User{
+		AccessToken: s.env.Netrc.Login,
+	}, &s.env.Repo, &s.env.Pipeline, name)
+	if err != nil {
+		return File{}, forgeError(err)
+	}
+
+	return File{Name: name, Data: string(data), Source: s}, nil
+}
+
 // FSProvider provides configuration files from the filesystem.
 type FSProvider struct {
 	logger  *slog.Logger
@@ -128,15 +159,20 @@ type FSProvider struct {
 // NewFSProvider returns a new FSProvider.
 func NewFSProvider(p string, logger *slog.Logger) (FSProvider, error) {
 	base, pattern := doublestar.SplitPattern(p)
-	dirFS := os.DirFS(base)
-	if _, err := fs.Stat(dirFS, "."); err != nil {
+	if _, err := fs.Stat(os.DirFS(base), "."); err != nil {
+		return FSProvider{}, err
+	}
+
+	// the root refuses paths and symlinks which leave the base directory
+	root, err := os.OpenRoot(base)
+	if err != nil {
 		return FSProvider{}, err
 	}
 
 	return FSProvider{
 		logger:  logger,
 		pattern: pattern,
-		fs:      dirFS,
+		fs:      root.FS(),
 	}, nil
 }
 
@@ -175,8 +211,9 @@ func (p FSProvider) Get(_ context.Context, env Environment) ([]File, error) {
 
 			mutex.Lock()
 			files = append(files, File{
-				Name: fp,
-				Data: buf.String(),
+				Name:   fp,
+				Data:   buf.String(),
+				Source: p.Source(),
 			})
 			mutex.Unlock()
 
@@ -187,3 +224,24 @@ func (p FSProvider) Get(_ context.Context, env Environment) ([]File, error) {
 	err = eg.Wait()
 	return files, err
 }
+
+// Source returns a source for the base directory of the provider.
+func (p FSProvider) Source() Source {
+	return fsSource{fs: p.fs}
+}
+
+// fsSource reads files from the base directory of the FSProvider.
+type fsSource struct {
+	fs fs.FS
+}
+
+// Read returns the file with the given name from the filesystem, the name is cleaned, so ./main.star reads main.star.
+func (s fsSource) Read(name string) (File, error) {
+	name = path.Clean(name)
+	data, err := fs.ReadFile(s.fs, name)
+	if err != nil {
+		return File{}, err
+	}
+
+	return File{Name: name, Data: string(data), Source: s}, nil
+}
@@ -89,5 +89,20 @@ func TestFSProvider_Get(t *testing.T) {
 		assert.Len(t, matches, 2)
 		assert.Equal(t, matches[wccs.Must1(filepath.Rel(tempdir, tempfileStar.Name()))].Data, tempfileStar.Name())
 		assert.Equal(t, matches[wccs.Must1(filepath.Rel(tempdir, tempfileYaml.Name()))].Data, tempfileYaml.Name())
+
+		t.Run("reads further files from the same source", func(t *testing.T) {
+			star := matches[wccs.Must1(filepath.Rel(tempdir, tempfileStar.Name()))]
+			file, err := star.Source.Read(wccs.Must1(filepath.Rel(tempdir, tempfileYaml.Name())))
+			assert.NoError(t, err)
+			assert.Equal(t, tempfileYaml.Name(), file.Data)
+		})
+
+		t.Run("reads files relative to the base directory", func(t *testing.T) {
+			name := wccs.Must1(filepath.Rel(tempdir, tempfileYaml.Name()))
+			file, err := provider.Source().Read("./" + name)
+			assert.NoError(t, err)
+			assert.Equal(t, name, file.Name)
+			assert.Equal(t, tempfileYaml.Name(), file.Data)
+		})
 	})
 }
@@ -0,0 +1,365 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.starlark.net/syntax"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+)
+
+// LockFileName is the name of the lock file which pins remote Starlark modules, it is located next to the entry file.
+const LockFileName = "wccs.lock"
+
+// ModuleLabel references a module of another repository, e.g. @org/ci-lib@v2.3.0//go.star.
+type ModuleLabel struct {
+	// Repo is the full name of the repository.
+	Repo string
+	// Ref is the tag or commit sha the module is pinned to.
+	Ref string
+	// Path is the path of the module inside the repository.
+	Path string
+}
+
+// ParseModuleLabel parses remote module references of the form @<repo>@<ref>//<path>.
+func ParseModuleLabel(module string) (ModuleLabel, error) {
+	head, p, ok := strings.Cut(strings.TrimPrefix(module, "@"), "//")
+	if !ok || !strings.HasPrefix(module, "@") {
+		return ModuleLabel{}, fmt.Errorf("%w: invalid module label %s", ErrPathNotAllowed, module)
+	}
+
+	i := strings.LastIndex(head, "@")
+	if i <= 0 || i == len(head)-1 {
+		return ModuleLabel{}, fmt.Errorf("%w: invalid module label %s", ErrPathNotAllowed, module)
+	}
+	label := ModuleLabel{Repo: head[:i], Ref: head[i+1:]}
+
+	name, err := resolveModulePath("", p)
+	if err != nil {
+		return ModuleLabel{}, err
+	}
+	label.Path = name
+
+	return label, nil
+}
+
+// String returns the canonical representation of the label.
+func (l ModuleLabel) String() string {
+	return "@" + l.Repo + "@" + l.Ref + "//" + l.Path
+}
+
+var digestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// Digest returns the content digest which is recorded in the lock file.
+func Digest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Lock maps remote module labels to the digest of their content.
+type Lock map[string]string
+
+// ParseLock parses the content of a lock file, each line contains a module label and its digest.
+func ParseLock(data string) (Lock, error) {
+	lock := Lock{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !digestPattern.MatchString(fields[1]) { //nolint:mnd
+			return nil, fmt.Errorf("%w: invalid lock entry on line %d", ErrMissingParam, n)
+		}
+		lock[fields[0]] = fields[1]
+	}
+
+	return lock, scanner.Err()
+}
+
+// String renders the lock file content sorted by module label.
+func (l Lock) String() string {
+	labels := make([]string, 0, len(l))
+	for label := range l {
+		labels = append(labels, label)
+	}
+	slices.Sort(labels)
+
+	var b strings.Builder
+	b.WriteString("# generated by wccs lock update, do not edit\n")
+	for _, label := range labels {
+		fmt.Fprintf(&b, "%s %s\n", label, l[label])
+	}
+
+	return b.String()
+}
+
+// RepositorySource is a Source which also gives access to other repositories of the same origin,
+// the files of a returned source are fetched within the given context.
+type RepositorySource interface {
+	Source
+	Repository(ctx context.Context, repo, ref string) (Source, error)
+}
+
+// Repository returns a source for the given repository at the given ref of the same forge.
+func (s forgeSource) Repository(ctx context.Context, repo, ref string) (Source, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("%w: repository %s must be of the form owner/name", ErrPathNotAllowed, repo)
+	}
+
+	env := s.env
+	env.Repo = model.Repo{Owner: owner, Name: name, FullName: repo}
+	env.Pipeline = model.Pipeline{Commit: ref}
+
+	return forgeSource{ctx: ctx, forge: s.forge, env: env}, nil
+}
+
+// URLRepositories fetches remote modules over http, the URL template may contain
+// the {repo}, {ref} and {path} placeholders, e.g. https://raw.githubusercontent.com/{repo}/{ref}/{path}.
+type URLRepositories struct {
+	client        *http.Client
+	template      string
+	maxModuleSize int
+}
+
+// NewURLRepositories returns a new URLRepositories, a fetch fails after the given timeout
+// or if the module is larger than maxModuleSize bytes, 0 disables the limit.
+func NewURLRepositories(template string, timeout time.Duration, maxModuleSize int) URLRepositories {
+	return URLRepositories{client: &http.Client{Timeout: timeout}, template: template, maxModuleSize: maxModuleSize}
+}
+
+// Read is not supported, files are always read from a specific repository.
+func (r URLRepositories) Read(name string) (File, error) {
+	return File{}, fmt.Errorf("%w: %s", ErrNoSource, name)
+}
+
+// Repository returns a source for the given repository at the given ref.
+func (r URLRepositories) Repository(ctx context.Context, repo, ref string) (Source, error) {
+	escaped, err := escapeURLPath(repo)
+	if err != nil {
+		return nil, fmt.Errorf("%w: repository %s", err, repo)
+	}
+
+	return urlSource{ctx: ctx, repositories: r, repo: escaped, ref: url.PathEscape(ref)}, nil
+}
+
+type urlSource struct {
+	ctx          context.Context
+	repositories URLRepositories
+	// repo and ref are escaped for the use in the URL.
+	repo, ref string
+}
+
+// Read fetches the file with the given name.
+func (s urlSource) Read(name string) (File, error) {
+	p, err := escapeURLPath(name)
+	if err != nil {
+		return File{}, err
+	}
+
+	u := strings.NewReplacer(
+		"{repo}", s.repo,
+		"{ref}", s.ref,
+		"{path}", p,
+	).Replace(s.repositories.template)
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return File{}, err
+	}
+
+	res, err := s.repositories.client.Do(req)
+	if err != nil {
+		return File{}, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return File{}, fmt.Errorf("%w: %s", fs.ErrNotExist, u)
+	case res.StatusCode != http.StatusOK:
+		return File{}, fmt.Errorf("unexpected status %d fetching %s", res.StatusCode, u)
+	}
+
+	body := io.Reader(res.Body)
+	// one more byte tells a module which exceeds the limit from one which has the maximal size
+	maxModuleSize := s.repositories.maxModuleSize
+	if maxModuleSize != 0 {
+		body = io.LimitReader(res.Body, int64(maxModuleSize)+1)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return File{}, err
+	}
+
+	if maxModuleSize != 0 && len(data) > maxModuleSize {
+		return File{}, fmt.Errorf("%w: max module size %d bytes fetching %s", ErrLimitExceeded, maxModuleSize, u)
+	}
+
+	return File{Name: name, Data: string(data), Source: s}, nil
+}
+
+// escapeURLPath escapes every segment of the given slash separated path,
+// empty segments and segments which change the directory are rejected.
+func escapeURLPath(p string) (string, error) {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, p)
+		}
+
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// ModuleCache stores fetched remote modules on disk, addressed by their digest.
+type ModuleCache struct {
+	dir string
+}
+
+// NewModuleCache returns a new ModuleCache, the directory is created if it does not exist.
+func NewModuleCache(dir string) (ModuleCache, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil { //nolint:mnd
+		return ModuleCache{}, err
+	}
+
+	return ModuleCache{dir: dir}, nil
+}
+
+// Get returns the cached module for the given digest, the content is verified before it is returned.
+func (c ModuleCache) Get(digest string) (string, bool) {
+	data, err := os.ReadFile(c.path(digest))
+	if err != nil || Digest(string(data)) != digest {
+		return "", false
+	}
+
+	return string(data), true
+}
+
+// Put stores the module content.
+func (c ModuleCache) Put(data string) error {
+	fp := c.path(Digest(data))
+	tmp, err := os.CreateTemp(c.dir, ".module-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.WriteString(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fp)
+}
+
+func (c ModuleCache) path(digest string) string {
+	return filepath.Join(c.dir, strings.ReplaceAll(digest, ":", "-"))
+}
+
+// LockStarlark walks the load statements of the given file and records the digests
+// of all remote modules which are reachable from it.
+func LockStarlark(ctx context.Context, f File, root string, repositories RepositorySource) (Lock, error) {
+	lock := Lock{}
+	visited := map[string]bool{}
+
+	var walk func(origin moduleOrigin, name, data string) error
+	walk = func(origin moduleOrigin, name, data string) error {
+		file, err := syntax.LegacyFileOptions().Parse(name, data, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, stmt := range file.Stmts {
+			load, ok := stmt.(*syntax.LoadStmt)
+			if !ok {
+				continue
+			}
+
+			module, _ := load.Module.Value.(string)
+			next, key, p, err := origin.resolve(module)
+			if err != nil {
+				return err
+			}
+
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+
+			if next.repo != "" && next.source == nil {
+				if next.source, err = repositories.Repository(ctx, next.repo, next.ref); err != nil {
+					return err
+				}
+			}
+
+			if next.source == nil {
+				return fmt.Errorf("%w: %s", ErrNoSource, module)
+			}
+
+			m, err := next.source.Read(p)
+			if err != nil {
+				return err
+			}
+
+			if next.repo != "" {
+				lock[key] = Digest(m.Data)
+			}
+
+			if err := walk(next, key, m.Data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return lock, walk(moduleOrigin{source: f.Source, root: root}, f.Name, f.Data)
+}
+
+// lockFilePath returns the path of the lock file which belongs to the given entry file.
+func lockFilePath(entry string) string {
+	return path.Join(path.Dir(entry), LockFileName)
+}
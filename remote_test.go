@@ -0,0 +1,280 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+// mapRepositories serves in memory repositories, keyed by <repo>@<ref>.
+type mapRepositories map[string]*mapSource
+
+func (r mapRepositories) Read(name string) (wccs.File, error) {
+	return wccs.File{}, fs.ErrNotExist
+}
+
+func (r mapRepositories) Repository(_ context.Context, repo, ref string) (wccs.Source, error) {
+	source, ok := r[repo+"@"+ref]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	return source, nil
+}
+
+const (
+	remoteGoStar = `
+load("helpers.star", "image")
+def go_pipeline(name):
+  return {"name": name, "steps": {"test": {"image": image}}}
+`
+	remoteHelpersStar = `image = "golang"`
+	remoteEntryStar   = `
+load("@org/ci-lib@v2.3.0//go.star", "go_pipeline")
+def main(ctx):
+  return [go_pipeline("test")]
+`
+)
+
+func TestParseModuleLabel(t *testing.T) {
+	label, err := wccs.ParseModuleLabel("@org/ci-lib@v2.3.0//lib/go.star")
+	assert.NoError(t, err)
+	assert.Equal(t, wccs.ModuleLabel{Repo: "org/ci-lib", Ref: "v2.3.0", Path: "lib/go.star"}, label)
+	assert.Equal(t, "@org/ci-lib@v2.3.0//lib/go.star", label.String())
+
+	for _, module := range []string{
+		"@org/ci-lib//go.star",
+		"@org/ci-lib@//go.star",
+		"@@v1//go.star",
+		"@org/ci-lib@v1/go.star",
+		"@org/ci-lib@v1//../go.star",
+		"org/ci-lib@v1//go.star",
+	} {
+		_, err := wccs.ParseModuleLabel(module)
+		assert.ErrorIs(t, err, wccs.ErrPathNotAllowed, module)
+	}
+}
+
+func TestLock(t *testing.T) {
+	lock := wccs.Lock{
+		"@org/b@v1//b.star": wccs.Digest("b"),
+		"@org/a@v1//a.star": wccs.Digest("a"),
+	}
+
+	parsed, err := wccs.ParseLock(lock.String())
+	assert.NoError(t, err)
+	assert.Equal(t, lock, parsed)
+
+	_, err = wccs.ParseLock("@org/a@v1//a.star sha256:../../etc/passwd")
+	assert.ErrorIs(t, err, wccs.ErrMissingParam)
+}
+
+func TestModuleCache(t *testing.T) {
+	cache, err := wccs.NewModuleCache(t.TempDir())
+	assert.NoError(t, err)
+
+	_, ok := cache.Get(wccs.Digest("module"))
+	assert.False(t, ok)
+
+	assert.NoError(t, cache.Put("module"))
+	data, ok := cache.Get(wccs.Digest("module"))
+	assert.True(t, ok)
+	assert.Equal(t, "module", data)
+}
+
+func TestLockStarlark(t *testing.T) {
+	repositories := mapRepositories{
+		"org/ci-lib@v2.3.0": newMapSource(map[string]string{"go.star": remoteGoStar, "helpers.star": remoteHelpersStar}),
+	}
+	source := newMapSource(map[string]string{"lib/local.star": `load("@org/ci-lib@v2.3.0//go.star", "go_pipeline")`})
+
+	lock, err := wccs.LockStarlark(t.Context(), wccs.File{Name: "main.star", Data: `load("lib/local.star", "go_pipeline")`, Source: source}, "", repositories)
+	assert.NoError(t, err)
+	assert.Equal(t, wccs.Lock{
+		"@org/ci-lib@v2.3.0//go.star":      wccs.Digest(remoteGoStar),
+		"@org/ci-lib@v2.3.0//helpers.star": wccs.Digest(remoteHelpersStar),
+	}, lock)
+}
+
+func TestStarlarkConverter_LoadRemote(t *testing.T) {
+	newRepositories := func() (mapRepositories, *mapSource) {
+		library := newMapSource(map[string]string{"go.star": remoteGoStar, "helpers.star": remoteHelpersStar})
+		return mapRepositories{"org/ci-lib@v2.3.0": library}, library
+	}
+
+	lock := wccs.Lock{
+		"@org/ci-lib@v2.3.0//go.star":      wccs.Digest(remoteGoStar),
+		"@org/ci-lib@v2.3.0//helpers.star": wccs.Digest(remoteHelpersStar),
+	}
+
+	entrypoint := func(lock wccs.Lock) wccs.File {
+		files := map[string]string{}
+		if lock != nil {
+			files[".woodpecker/"+wccs.LockFileName] = lock.String()
+		}
+
+		return wccs.File{Name: ".woodpecker/main.star", Data: remoteEntryStar, Source: newMapSource(files)}
+	}
+
+	t.Run("loads locked modules", func(t *testing.T) {
+		repositories, _ := newRepositories()
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkRepositories(repositories))
+		assert.NoError(t, err)
+
+		files, err := c.Convert(t.Context(), entrypoint(lock), wccs.Environment{})
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "steps:\n  test:\n    image: golang\n", files[0].Data)
+	})
+
+	t.Run("fails without a lock file", func(t *testing.T) {
+		repositories, _ := newRepositories()
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkRepositories(repositories))
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), entrypoint(nil), wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrModuleNotLocked)
+	})
+
+	t.Run("fails if the module is not locked", func(t *testing.T) {
+		repositories, _ := newRepositories()
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkRepositories(repositories))
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), entrypoint(wccs.Lock{"@org/ci-lib@v2.3.0//go.star": wccs.Digest(remoteGoStar)}), wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrModuleNotLocked)
+		assert.Contains(t, err.Error(), "helpers.star")
+	})
+
+	t.Run("fails if the digest does not match", func(t *testing.T) {
+		repositories, library := newRepositories()
+		library.files["go.star"] += "\n# changed"
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkRepositories(repositories))
+		assert.NoError(t, err)
+
+		_, err = c.Convert(t.Context(), entrypoint(lock), wccs.Environment{})
+		assert.ErrorIs(t, err, wccs.ErrDigestMismatch)
+	})
+
+	t.Run("uses the module cache", func(t *testing.T) {
+		cache, err := wccs.NewModuleCache(t.TempDir())
+		assert.NoError(t, err)
+
+		repositories, library := newRepositories()
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkRepositories(repositories), wccs.WithStarlarkModuleCache(cache))
+		assert.NoError(t, err)
+
+		for range 3 {
+			_, err = c.Convert(t.Context(), entrypoint(lock), wccs.Environment{})
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 1, library.reads["go.star"])
+		assert.Equal(t, 1, library.reads["helpers.star"])
+	})
+}
+
+func TestURLRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/org/ci-lib/raw/v2.3.0/go.star" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write([]byte(remoteGoStar))
+	}))
+	defer server.Close()
+
+	repositories := wccs.NewURLRepositories(server.URL+"/{repo}/raw/{ref}/{path}", time.Second, 0)
+	source, err := repositories.Repository(t.Context(), "org/ci-lib", "v2.3.0")
+	assert.NoError(t, err)
+
+	file, err := source.Read("go.star")
+	assert.NoError(t, err)
+	assert.Equal(t, remoteGoStar, file.Data)
+
+	_, err = source.Read("unknown.star")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	t.Run("escapes the repository", func(t *testing.T) {
+		source, err := repositories.Repository(t.Context(), "org/ci-lib?x=1", "v2.3.0")
+		assert.NoError(t, err)
+
+		_, err = source.Read("go.star")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+
+		for _, repo := range []string{"org/../ci-lib", "org//ci-lib", "/org/ci-lib"} {
+			_, err = repositories.Repository(t.Context(), repo, "v2.3.0")
+			assert.ErrorIs(t, err, wccs.ErrPathNotAllowed, repo)
+		}
+	})
+
+	t.Run("fetches within the context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		source, err := repositories.Repository(ctx, "org/ci-lib", "v2.3.0")
+		assert.NoError(t, err)
+
+		_, err = source.Read("go.star")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("limits the module size", func(t *testing.T) {
+		for size, expected := range map[int]error{
+			len(remoteGoStar):     nil,
+			len(remoteGoStar) - 1: wccs.ErrLimitExceeded,
+		} {
+			source, err := wccs.NewURLRepositories(server.URL+"/{repo}/raw/{ref}/{path}", time.Second, size).Repository(t.Context(), "org/ci-lib", "v2.3.0")
+			assert.NoError(t, err)
+
+			_, err = source.Read("go.star")
+			if expected == nil {
+				assert.NoError(t, err, size)
+				continue
+			}
+			assert.ErrorIs(t, err, expected, size)
+		}
+	})
+}
+
+func TestURLRepositoriesTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	source, err := wccs.NewURLRepositories(server.URL+"/{repo}/raw/{ref}/{path}", 50*time.Millisecond, 0).Repository(t.Context(), "org/ci-lib", "v2.3.0")
+	assert.NoError(t, err)
+
+	_, err = source.Read("go.star")
+	var urlErr *url.Error
+	assert.ErrorAs(t, err, &urlErr)
+	assert.True(t, urlErr.Timeout())
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// StarlarkREPL evaluates statements and expressions with the globals of an executed Starlark file.
+type StarlarkREPL struct {
+	converter StarlarkConverter
+	globals   starlark.StringDict
+	thread    *starlark.Thread
+	loader    *starlarkLoader
+	// inputs counts the evaluated inputs, every input gets its own file name.
+	inputs int
+}
+
+// REPL executes the given file the same way Convert does and returns a REPL with its globals and the predeclared modules,
+// the context which main is called with is available as ctx.
+// The output of print is written to out, the execution is canceled when the context is done.
+func (p StarlarkConverter) REPL(ctx context.Context, f File, env Environment, out io.Writer) (*StarlarkREPL, error) {
+	globals, thread, loader, err := p.exec(ctx, f, env)
+	// every input registers its own cancellation, the thread outlives the context of the file
+	loader.release()
+	if err != nil {
+		return nil, p.limitError(ctx, loader, newDiagnostic(err, loader.sources))
+	}
+
+	thread.Print = func(_ *starlark.Thread, msg string) {
+		_, _ = fmt.Fprintln(out, msg)
+	}
+
+	// the predeclared modules are globals of the inputs, which may be reassigned
+	replGlobals := maps.Clone(loader.predeclared)
+	maps.Copy(replGlobals, globals)
+	replGlobals["ctx"] = p.mainContext(f, env)
+
+	return &StarlarkREPL{converter: p, globals: replGlobals, thread: thread, loader: loader}, nil
+}
+
+// Format returns the YAML representation of the value, values which have none are formatted as Starlark.
+func (r *StarlarkREPL) Format(v starlark.Value) string {
+	node, err := StarlarkToYAML(v)
+	if err != nil {
+		return v.String() + "\n"
+	}
+
+	out, err := encodeYAML(node)
+	if err != nil {
+		return v.String() + "\n"
+	}
+
+	return out
+}
+
+// Names returns the sorted names of the globals.
+func (r *StarlarkREPL) Names() []string {
+	return slices.Sorted(maps.Keys(r.globals))
+}
+
+// Eval reads a single statement or expression with readline, which returns one line of input per call,
+// lines are read until the input is complete. The value of an expression is returned and kept as _,
+// statements return None. Errors of readline are returned as they are, the limits of the converter apply to every input.
+func (r *StarlarkREPL) Eval(ctx context.Context, readline func() ([]byte, error)) (starlark.Value, error) {
+	r.inputs++
+	name := fmt.Sprintf("<input-%d>", r.inputs)
+
+	var input strings.Builder
+	var readErr error
+	f, err := r.options().ParseCompoundStmt(name, func() ([]byte, error) {
+		line, err := readline()
+		if err != nil && readErr == nil {
+			readErr = err
+		}
+		input.Write(line)
+
+		return line, err
+	})
+	if readErr != nil {
+		return nil, readErr
+	}
+	r.loader.sources[name] = input.String()
+	if err != nil {
+		return nil, newDiagnostic(err, r.loader.sources)
+	}
+
+	if limits := r.converter.limits; limits.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, limits.Timeout, fmt.Errorf("%w: timeout %s", ErrLimitExceeded, limits.Timeout))
+		defer cancel()
+	}
+
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+
+	// the thread outlives the input, a canceled input or an exceeded limit must not cancel the next one
+	stop := context.AfterFunc(ctx, func() {
+		r.thread.Cancel(context.Cause(ctx).Error())
+	})
+	defer func() {
+		stop()
+		r.thread.Uncancel()
+	}()
+
+	// the execution steps of the thread add up, every input may take the configured number of steps
+	limit, steps := r.converter.limits.MaxExecutionSteps, r.thread.ExecutionSteps()
+	if limit != 0 {
+		r.thread.SetMaxExecutionSteps(steps + limit)
+	}
+
+	v := starlark.Value(starlark.None)
+	if stmt, ok := soleExprStmt(f); ok {
+		v, err = starlark.EvalExprOptions(f.Options, r.thread, stmt.X, r.globals)
+		if err == nil {
+			r.globals["_"] = v
+		}
+	} else {
+		err = starlark.ExecREPLChunk(f, r.thread, r.globals)
+	}
+	switch {
+	case err == nil:
+		return v, nil
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("%w: %w", context.Cause(ctx), newDiagnostic(err, r.loader.sources))
+	case limit != 0 && r.thread.ExecutionSteps()-steps >= limit:
+		return nil, fmt.Errorf("%w: max execution steps %d: %w", ErrLimitExceeded, limit, newDiagnostic(err, r.loader.sources))
+	default:
+		return nil, newDiagnostic(err, r.loader.sources)
+	}
+}
+
+// options returns the file options of the inputs, loads bind globally so loaded names stay available.
+func (r *StarlarkREPL) options() *syntax.FileOptions {
+	options := *r.loader.options
+	options.LoadBindsGlobally = true
+
+	return &options
+}
+
+// soleExprStmt returns the statement of the file if it consists of a single expression.
+func soleExprStmt(f *syntax.File) (*syntax.ExprStmt, bool) {
+	if len(f.Stmts) != 1 {
+		return nil, false
+	}
+
+	stmt, ok := f.Stmts[0].(*syntax.ExprStmt)
+
+	return stmt, ok
+}
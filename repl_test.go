@@ -0,0 +1,121 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkConverter_REPL(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkLimits(wccs.StarlarkLimits{MaxExecutionSteps: 10000}))
+	assert.NoError(t, err)
+
+	out := new(bytes.Buffer)
+	repl, err := c.REPL(t.Context(), wccs.File{Name: "main.star", Data: `load("lib.star", "image")
+
+def main(ctx):
+  return {"steps": [{"name": ctx.repo.name, "image": image}]}
+`, Source: newMapSource(map[string]string{"lib.star": `image = "golang"`, "other.star": `other = 1`})}, wccs.Environment{Repo: model.Repo{Name: "wccs"}}, out)
+	assert.NoError(t, err)
+	assert.Subset(t, repl.Names(), []string{"ctx", "json", "main"})
+
+	eval := func(input string) string {
+		t.Helper()
+
+		v, err := repl.Eval(t.Context(), lines(input))
+		if err != nil {
+			return err.Error()
+		}
+
+		return v.String()
+	}
+
+	assert.Equal(t, `"wccs"`, eval("ctx.repo.name\n"))
+	assert.Equal(t, `{"steps": [{"name": "wccs", "image": "golang"}]}`, eval("main(ctx)\n"))
+	assert.Equal(t, `"golang"`, eval("_[\"steps\"][0][\"image\"]\n"))
+	assert.Equal(t, "None", eval("def twice(x):\n  return 2 * x\n\n"))
+	assert.Equal(t, "4", eval("twice(2)\n"))
+	assert.Equal(t, "None", eval("load(\"other.star\", \"other\")\n"))
+	assert.Equal(t, "1", eval("other\n"))
+	assert.Equal(t, `"{\"a\":1}"`, eval("json.encode({\"a\": 1})\n"))
+
+	assert.Equal(t, "None", eval("print(ctx.repo.name)\n"))
+	assert.Equal(t, "wccs\n", out.String())
+
+	assert.Equal(t, "<input-10>:1:7: undefined: missing", eval("twice(missing)\n"))
+	assert.Equal(t, "<input-11>:1:5: got newline, want primary expression", eval("1 + \n"))
+
+	t.Run("formats values as yaml", func(t *testing.T) {
+		v, err := repl.Eval(t.Context(), lines("main(ctx)\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, "steps:\n  - name: wccs\n    image: golang\n", repl.Format(v))
+
+		v, err = repl.Eval(t.Context(), lines("twice\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, "<function twice>\n", repl.Format(v))
+	})
+
+	t.Run("limits every input", func(t *testing.T) {
+		_, err := repl.Eval(t.Context(), lines("[x for x in range(100000)]\n"))
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+		for range 3 {
+			assert.Equal(t, "2", eval("twice(1)\n"))
+		}
+	})
+
+	t.Run("continues after a canceled input", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		_, err := repl.Eval(ctx, lines("twice(1)\n"))
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, "2", eval("twice(1)\n"))
+	})
+
+	t.Run("returns errors of readline", func(t *testing.T) {
+		_, err := repl.Eval(t.Context(), lines("def f():\n"))
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("returns errors of the file", func(t *testing.T) {
+		_, err := c.REPL(t.Context(), wccs.File{Name: "main.star", Data: "fail(\"broken\")\n"}, wccs.Environment{}, out)
+		assert.ErrorContains(t, err, "main.star:1:5: broken")
+	})
+}
+
+// lines returns a readline function which returns the lines of the given input and io.EOF at its end.
+func lines(input string) func() ([]byte, error) {
+	remaining := strings.SplitAfter(input, "\n")
+
+	return func() ([]byte, error) {
+		if len(remaining) == 0 || remaining[0] == "" {
+			return nil, io.EOF
+		}
+
+		line := remaining[0]
+		remaining = remaining[1:]
+
+		return []byte(line), nil
+	}
+}
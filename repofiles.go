@@ -0,0 +1,263 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.woodpecker-ci.org/woodpecker/v3/server/forge/types"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+)
+
+// starlarkRepoFilesKey is the thread local key which holds the repository files of the conversion.
+const starlarkRepoFilesKey = "wccs.files"
+
+// DirSource is a Source which can also list the entries of a directory.
+type DirSource interface {
+	Source
+	// Dir returns the names of the entries of the given directory, "." is the root of the source.
+	Dir(name string) ([]string, error)
+}
+
+// Dir returns the names of the entries of the given directory of the forge repository.
+func (s forgeSource) Dir(name string) ([]string, error) {
+	if name == "." {
+		name = ""
+	}
+
+	files, err := s.forge.Dir(s.ctx, &model.User{
+		AccessToken: s.env.Netrc.Login,
+	}, &s.env.Repo, &s.env.Pipeline, name)
+	if err != nil {
+		return nil, forgeError(err)
+	}
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, path.Base(f.Name))
+	}
+
+	return names, nil
+}
+
+// forgeError reports files which are missing on the forge as fs.ErrNotExist.
+func forgeError(err error) error {
+	if errors.Is(err, &types.ErrConfigNotFound{}) {
+		return fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	}
+
+	return err
+}
+
+// Dir returns the names of the entries of the given directory of the filesystem.
+func (s fsSource) Dir(name string) ([]string, error) {
+	entries, err := fs.ReadDir(s.fs, name)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names, nil
+}
+
+// repoFiles gives scripts read access to the repository of the entry file through the read_file,
+// list_dir and exists builtins. Paths are relative to the root of the repository and must not leave it,
+// every path is fetched at most once per conversion and the number of fetches is limited.
+// Only file contents and names are passed to the script, never the source itself.
+type repoFiles struct {
+	source Source
+	// limit is the maximal number of fetches, 0 disables the limit.
+	limit   int
+	fetches int
+	files   map[string]repoFile
+	dirs    map[string]repoDir
+}
+
+type repoFile struct {
+	data string
+	err  error
+}
+
+type repoDir struct {
+	names []string
+	err   error
+}
+
+func newRepoFiles(source Source, limit int) *repoFiles {
+	return &repoFiles{
+		source: source,
+		limit:  limit,
+		files:  map[string]repoFile{},
+		dirs:   map[string]repoDir{},
+	}
+}
+
+// fetch counts a fetch from the source and fails if the limit is exceeded.
+func (f *repoFiles) fetch() error {
+	if f.limit != 0 && f.fetches >= f.limit {
+		return fmt.Errorf("%w: max file reads %d", ErrLimitExceeded, f.limit)
+	}
+	f.fetches++
+
+	return nil
+}
+
+func (f *repoFiles) read(name string) (string, error) {
+	if cached, ok := f.files[name]; ok {
+		return cached.data, cached.err
+	}
+
+	if f.source == nil {
+		return "", fmt.Errorf("%w: %s", ErrNoSource, name)
+	}
+
+	if err := f.fetch(); err != nil {
+		return "", err
+	}
+
+	file, err := f.source.Read(name)
+	f.files[name] = repoFile{data: file.Data, err: err}
+
+	return file.Data, err
+}
+
+func (f *repoFiles) dir(name string) ([]string, error) {
+	if cached, ok := f.dirs[name]; ok {
+		return cached.names, cached.err
+	}
+
+	source, ok := f.source.(DirSource)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoSource, name)
+	}
+
+	if err := f.fetch(); err != nil {
+		return nil, err
+	}
+
+	names, err := source.Dir(name)
+	slices.Sort(names)
+	f.dirs[name] = repoDir{names: names, err: err}
+
+	return names, err
+}
+
+// exists reports whether the given file or directory exists, directories require a DirSource.
+func (f *repoFiles) exists(name string) (bool, error) {
+	_, err := f.read(name)
+	if err == nil {
+		return true, nil
+	}
+
+	if _, ok := f.source.(DirSource); !ok || errors.Is(err, ErrLimitExceeded) {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	// the path is either missing or a directory
+	_, dirErr := f.dir(name)
+	switch {
+	case dirErr == nil:
+		return true, nil
+	case errors.Is(dirErr, fs.ErrNotExist) && errors.Is(err, fs.ErrNotExist):
+		return false, nil
+	case errors.Is(dirErr, fs.ErrNotExist):
+		return false, err
+	default:
+		return false, dirErr
+	}
+}
+
+// resolveFilePath cleans the given path, it must be relative and must not leave the root.
+func resolveFilePath(name string) (string, error) {
+	name = path.Clean(name)
+	if name == ".." || path.IsAbs(name) || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, name)
+	}
+
+	return name, nil
+}
+
+// repoFilesBuiltin returns a builtin which calls f with the repository files of the thread and the resolved path.
+func repoFilesBuiltin(name string, f func(files *repoFiles, name string) (starlark.Value, error)) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var p string
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &p); err != nil {
+			return nil, err
+		}
+
+		files, ok := thread.Local(starlarkRepoFilesKey).(*repoFiles)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", b.Name(), ErrNoSource)
+		}
+
+		resolved, err := resolveFilePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+
+		v, err := f(files, resolved)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+
+		return v, nil
+	})
+}
+
+var (
+	readFileBuiltin = repoFilesBuiltin("read_file", func(files *repoFiles, name string) (starlark.Value, error) {
+		data, err := files.read(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return starlark.String(data), nil
+	})
+	listDirBuiltin = repoFilesBuiltin("list_dir", func(files *repoFiles, name string) (starlark.Value, error) {
+		names, err := files.dir(name)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]starlark.Value, 0, len(names))
+		for _, n := range names {
+			values = append(values, starlark.String(n))
+		}
+
+		return starlark.NewList(values), nil
+	})
+	existsBuiltin = repoFilesBuiltin("exists", func(files *repoFiles, name string) (starlark.Value, error) {
+		ok, err := files.exists(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return starlark.Bool(ok), nil
+	})
+)
@@ -0,0 +1,111 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.starlark.net/starlark"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkFilesModule(t *testing.T) {
+	env := wccs.Environment{}
+
+	t.Run("reads the base directory of the fs provider", func(t *testing.T) {
+		outside := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o600))
+
+		root := t.TempDir()
+		assert.NoError(t, os.Mkdir(filepath.Join(root, "web"), 0o700))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, "web", "package.json"), []byte("{}"), 0o600))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, "versions.yaml"), []byte("go: [\"1.25\", \"1.26\"]\n"), 0o600))
+		assert.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "secret")))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, "main.star"), []byte(`
+versions = yaml.decode(read_file("versions.yaml"))["go"]
+entries = list_dir(".")
+web = list_dir("./web/")
+frontend = exists("web/package.json")
+directory = exists("web")
+missing = exists("missing.json")
+`), 0o600))
+
+		provider, err := wccs.NewFSProvider(filepath.Join(root, "main.star"), noopLogger)
+		assert.NoError(t, err)
+		files, err := provider.Get(t.Context(), env)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+
+		c, err := wccs.NewStarlarkConverter(noopLogger)
+		assert.NoError(t, err)
+
+		globals, _, err := c.Exec(t.Context(), files[0], env)
+		assert.NoError(t, err)
+		assert.Equal(t, `["1.25", "1.26"]`, globals["versions"].String())
+		assert.Equal(t, `["main.star", "secret", "versions.yaml", "web"]`, globals["entries"].String())
+		assert.Equal(t, `["package.json"]`, globals["web"].String())
+		assert.Equal(t, starlark.True, globals["frontend"])
+		assert.Equal(t, starlark.True, globals["directory"])
+		assert.Equal(t, starlark.False, globals["missing"])
+
+		for _, src := range []string{`read_file("../versions.yaml")`, `read_file("/etc/passwd")`, `list_dir("web/../..")`} {
+			_, _, err := c.Exec(t.Context(), wccs.File{Name: "main.star", Data: src, Source: files[0].Source}, env)
+			assert.ErrorIs(t, err, wccs.ErrPathNotAllowed, src)
+		}
+
+		// symlinks must not leave the base directory either
+		_, _, err = c.Exec(t.Context(), wccs.File{Name: "main.star", Data: `read_file("secret")`, Source: files[0].Source}, env)
+		assert.ErrorContains(t, err, "path escapes from parent")
+	})
+
+	t.Run("caches and limits fetches", func(t *testing.T) {
+		source := newMapSource(map[string]string{"a": "a", "b": "b", "c": "c", "lib.star": `b = read_file("b")`})
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkLimits(wccs.StarlarkLimits{MaxFileReads: 3}))
+		assert.NoError(t, err)
+
+		// modules share the fetches of the conversion, loads are not counted
+		globals, _, err := c.Exec(t.Context(), wccs.File{Name: "main.star", Source: source, Data: `
+load("lib.star", "b")
+a = read_file("a") + read_file("./a") + b
+found, missing = exists("a"), exists("missing")
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, starlark.String("aab"), globals["a"])
+		assert.Equal(t, starlark.True, globals["found"])
+		// sources without directories report missing paths as missing files
+		assert.Equal(t, starlark.False, globals["missing"])
+		assert.Equal(t, 1, source.reads["a"])
+
+		_, _, err = c.Exec(t.Context(), wccs.File{Name: "main.star", Source: source, Data: `read_file("a") + read_file("b") + read_file("c") + read_file("lib.star")`}, env)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+	})
+
+	t.Run("fails without a source", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger)
+		assert.NoError(t, err)
+
+		for _, src := range []string{`read_file("a")`, `list_dir(".")`, `exists("a")`} {
+			_, _, err := c.Exec(t.Context(), wccs.File{Name: "main.star", Data: src}, env)
+			assert.ErrorIs(t, err, wccs.ErrNoSource, src)
+		}
+
+		_, _, err = c.Exec(t.Context(), wccs.File{Name: "main.star", Data: `list_dir(".")`, Source: newMapSource(nil)}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoSource)
+	})
+}
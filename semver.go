@@ -0,0 +1,154 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// semver is a semantic version as defined by https://semver.org.
+type semver struct {
+	major, minor, patch uint64
+	prerelease, build   string
+}
+
+func parseSemver(s string) (semver, error) {
+	match := semverPattern.FindStringSubmatch(s)
+	if match == nil {
+		return semver{}, fmt.Errorf("invalid semantic version %q", s)
+	}
+
+	var v semver
+	for i, n := range []*uint64{&v.major, &v.minor, &v.patch} {
+		var err error
+		if *n, err = strconv.ParseUint(match[i+1], 10, 64); err != nil {
+			return semver{}, fmt.Errorf("invalid semantic version %q: %w", s, err)
+		}
+	}
+	v.prerelease, v.build = match[4], match[5]
+
+	return v, nil
+}
+
+// compare returns the precedence of a compared to b, build metadata is ignored.
+func (a semver) compare(b semver) int {
+	if c := cmp.Compare(a.major, b.major); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(a.minor, b.minor); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(a.patch, b.patch); c != 0 {
+		return c
+	}
+
+	// a version without prerelease has a higher precedence
+	switch {
+	case a.prerelease == b.prerelease:
+		return 0
+	case a.prerelease == "":
+		return 1
+	case b.prerelease == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a.prerelease, "."), strings.Split(b.prerelease, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(as), len(bs))
+}
+
+var semverModule = &starlarkstruct.Module{
+	Name: "semver",
+	Members: starlark.StringDict{
+		"parse": starlark.NewBuiltin("semver.parse", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+				return nil, err
+			}
+
+			v, err := parseSemver(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			return starlarkstruct.FromStringDict(starlark.String("semver"), starlark.StringDict{
+				"major":      starlark.MakeUint64(v.major),
+				"minor":      starlark.MakeUint64(v.minor),
+				"patch":      starlark.MakeUint64(v.patch),
+				"prerelease": starlark.String(v.prerelease),
+				"build":      starlark.String(v.build),
+			}), nil
+		}),
+		"compare": starlark.NewBuiltin("semver.compare", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var x, y string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &x, &y); err != nil { //nolint:mnd
+				return nil, err
+			}
+
+			a, err := parseSemver(x)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			c, err := parseSemver(y)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			return starlark.MakeInt(a.compare(c)), nil
+		}),
+		"is_valid": starlark.NewBuiltin("semver.is_valid", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+				return nil, err
+			}
+
+			return starlark.Bool(semverPattern.MatchString(s)), nil
+		}),
+	},
+}
@@ -0,0 +1,368 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// StarlarkTestFileSuffix is the suffix of Starlark test files.
+	StarlarkTestFileSuffix = "_test.star"
+	// starlarkTestPrefix is the prefix of the test functions of a test file.
+	starlarkTestPrefix = "test_"
+)
+
+// StarlarkTestResult is the result of a single Starlark test function.
+type StarlarkTestResult struct {
+	File     string
+	Name     string
+	Duration time.Duration
+	// Failure describes why the test failed, it is empty for passed tests.
+	Failure string
+}
+
+// StarlarkTestResults are the results of one or more test files.
+type StarlarkTestResults []StarlarkTestResult
+
+// Failed returns the number of failed tests.
+func (r StarlarkTestResults) Failed() int {
+	return len(slices.DeleteFunc(slices.Clone(r), func(result StarlarkTestResult) bool {
+		return result.Failure == ""
+	}))
+}
+
+type (
+	junitTestSuites struct {
+		XMLName  xml.Name         `xml:"testsuites"`
+		Tests    int              `xml:"tests,attr"`
+		Failures int              `xml:"failures,attr"`
+		Suites   []junitTestSuite `xml:"testsuite"`
+	}
+	junitTestSuite struct {
+		Name     string          `xml:"name,attr"`
+		Tests    int             `xml:"tests,attr"`
+		Failures int             `xml:"failures,attr"`
+		Time     string          `xml:"time,attr"`
+		Cases    []junitTestCase `xml:"testcase"`
+	}
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		Classname string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+	}
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// JUnit writes the results as JUnit XML report, every test file is a test suite.
+func (r StarlarkTestResults) JUnit(w io.Writer) error {
+	report := junitTestSuites{Tests: len(r), Failures: r.Failed()}
+	var durations []time.Duration
+	for _, result := range r {
+		i := slices.IndexFunc(report.Suites, func(suite junitTestSuite) bool {
+			return suite.Name == result.File
+		})
+		if i < 0 {
+			report.Suites = append(report.Suites, junitTestSuite{Name: result.File})
+			durations = append(durations, 0)
+			i = len(report.Suites) - 1
+		}
+
+		testCase := junitTestCase{Name: result.Name, Classname: result.File, Time: junitTime(result.Duration)}
+		if result.Failure != "" {
+			message, _, _ := strings.Cut(result.Failure, "\n")
+			testCase.Failure = &junitFailure{Message: message, Text: result.Failure}
+			report.Suites[i].Failures++
+		}
+
+		report.Suites[i].Tests++
+		report.Suites[i].Cases = append(report.Suites[i].Cases, testCase)
+		durations[i] += result.Duration
+	}
+
+	for i := range report.Suites {
+		report.Suites[i].Time = junitTime(durations[i])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// Test runs all test_ functions of the given test file in alphabetical order,
+// the assert and testing modules are predeclared in addition to the configured modules.
+// Every test runs on a thread of its own, the limits apply to the file and to every test on their own.
+// Failing tests are part of the results, errors of the test file itself are returned.
+func (p StarlarkConverter) Test(ctx context.Context, f File) (StarlarkTestResults, error) {
+	p.predeclared = maps.Clone(p.predeclared)
+	p.predeclared["assert"] = starlarkAssertModule
+	p.predeclared["testing"] = p.starlarkTestingModule()
+
+	execCtx := ctx
+	if p.limits.Timeout != 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeoutCause(ctx, p.limits.Timeout, fmt.Errorf("%w: timeout %s", ErrLimitExceeded, p.limits.Timeout))
+		defer cancel()
+	}
+
+	globals, _, loader, err := p.exec(execCtx, f, Environment{})
+	defer loader.release()
+	if err != nil {
+		return nil, p.limitError(execCtx, loader, newDiagnostic(err, loader.sources))
+	}
+
+	var results StarlarkTestResults
+	for _, name := range slices.Sorted(maps.Keys(globals)) {
+		test, ok := globals[name].(starlark.Callable)
+		if !ok || !strings.HasPrefix(name, starlarkTestPrefix) {
+			continue
+		}
+
+		results = append(results, p.runTest(ctx, loader, f, name, test))
+	}
+
+	return results, nil
+}
+
+// runTest calls the test function on a new thread, a test which exceeds a limit does not affect the others.
+func (p StarlarkConverter) runTest(ctx context.Context, loader *starlarkLoader, f File, name string, test starlark.Callable) StarlarkTestResult {
+	if p.limits.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, p.limits.Timeout, fmt.Errorf("%w: timeout %s", ErrLimitExceeded, p.limits.Timeout))
+		defer cancel()
+	}
+
+	thread := p.newThread(ctx, Environment{}, loader)
+
+	start := time.Now()
+	_, err := starlark.Call(thread, test, nil, nil)
+	result := StarlarkTestResult{File: f.Name, Name: name, Duration: time.Since(start)}
+
+	var diagnostic *Diagnostic
+	switch err := p.threadLimitError(ctx, []*starlark.Thread{thread}, newDiagnostic(err, loader.sources)); {
+	case errors.As(err, &diagnostic) && !errors.Is(err, ErrLimitExceeded):
+		result.Failure = diagnostic.Report()
+	case err != nil:
+		result.Failure = err.Error()
+	}
+
+	return result
+}
+
+// starlarkAssertModule provides the assertions of Starlark tests.
+var starlarkAssertModule = &starlarkstruct.Module{
+	Name: "assert",
+	Members: starlark.StringDict{
+		"eq": starlarkAssertion("assert.eq", func(x, y starlark.Value) (bool, error) {
+			return starlark.Equal(x, y)
+		}, "%s != %s"),
+		"ne": starlarkAssertion("assert.ne", func(x, y starlark.Value) (bool, error) {
+			equal, err := starlark.Equal(x, y)
+			return !equal, err
+		}, "%s == %s"),
+		"contains": starlarkAssertion("assert.contains", func(x, y starlark.Value) (bool, error) {
+			contains, err := starlark.Binary(syntax.IN, y, x)
+			if err != nil {
+				return false, err
+			}
+
+			return bool(contains.Truth()), nil
+		}, "%s does not contain %s"),
+		"fails": starlark.NewBuiltin("assert.fails", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var fn starlark.Callable
+			var pattern string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "fn", &fn, "pattern?", &pattern); err != nil {
+				return nil, err
+			}
+
+			_, err := starlark.Call(thread, fn, nil, nil)
+			if err == nil {
+				return nil, fmt.Errorf("%s: %s did not fail", b.Name(), fn.Name())
+			}
+
+			message := err.Error()
+			var evalErr *starlark.EvalError
+			if errors.As(err, &evalErr) {
+				message = strings.TrimPrefix(evalErr.Msg, starlarkFailPrefix)
+			}
+
+			if pattern != "" {
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", b.Name(), err)
+				}
+
+				if !re.MatchString(message) {
+					return nil, fmt.Errorf("%s: error %q does not match %q", b.Name(), message, pattern)
+				}
+			}
+
+			return starlark.String(message), nil
+		}),
+	},
+}
+
+// starlarkAssertion returns an assertion builtin which fails with the formatted message if check returns false.
+func starlarkAssertion(name string, check func(x, y starlark.Value) (bool, error), format string) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x, y starlark.Value
+		var msg string
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "x", &x, "y", &y, "msg?", &msg); err != nil {
+			return nil, err
+		}
+
+		ok, err := check(x, y)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		case ok:
+			return starlark.None, nil
+		case msg != "":
+			return nil, fmt.Errorf("%s: %s: "+format, b.Name(), msg, x, y)
+		default:
+			return nil, fmt.Errorf("%s: "+format, b.Name(), x, y)
+		}
+	})
+}
+
+// starlarkTestingModule provides helpers to call main with a fake context.
+func (p StarlarkConverter) starlarkTestingModule() *starlarkstruct.Module {
+	// env unpacks the repo and build dicts into an environment and reports whether the drone mode is requested.
+	// the given pairs are unpacked before the optional repo, build and drone parameters.
+	env := func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple, pairs ...any) (Environment, bool, error) {
+		repo, build := starlark.NewDict(0), starlark.NewDict(0)
+		var drone bool
+		pairs = append(pairs, "repo?", &repo, "build?", &build, "drone?", &drone)
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, pairs...); err != nil {
+			return Environment{}, false, err
+		}
+
+		var env Environment
+		for _, v := range []struct {
+			dict   *starlark.Dict
+			target any
+		}{{repo, &env.Repo}, {build, &env.Pipeline}} {
+			data, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{v.dict}, nil)
+			if err != nil {
+				return Environment{}, false, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+
+			if err := json.Unmarshal([]byte(data.(starlark.String)), v.target); err != nil { //nolint:forcetypeassert
+				return Environment{}, false, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+		}
+
+		return env, drone || p.mode == StarlarkModeDrone, nil
+	}
+
+	mainContext := func(env Environment, drone bool) starlark.Value {
+		if drone {
+			return droneContext(env)
+		}
+
+		return starlarkContext(env)
+	}
+
+	return &starlarkstruct.Module{
+		Name: "testing",
+		Members: starlark.StringDict{
+			"ctx": starlark.NewBuiltin("testing.ctx", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				env, drone, err := env(thread, b, args, kwargs)
+				if err != nil {
+					return nil, err
+				}
+
+				return mainContext(env, drone), nil
+			}),
+			"convert": starlark.NewBuiltin("testing.convert", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				var main starlark.Callable
+				file := "main.star"
+				env, drone, err := env(thread, b, args, kwargs, "main", &main, "file?", &file)
+				if err != nil {
+					return nil, err
+				}
+
+				v, err := starlark.Call(thread, main, starlark.Tuple{mainContext(env, drone)}, nil)
+				if err != nil {
+					return nil, err
+				}
+
+				if drone {
+					if v, err = (droneTranslator{}).workflows(v); err != nil {
+						return nil, fmt.Errorf("%s: %w", b.Name(), err)
+					}
+				}
+
+				files, err := starlarkFiles(v, file)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", b.Name(), err)
+				}
+
+				// the converted workflows are decoded again, tests see exactly what woodpecker gets
+				workflows := starlark.NewDict(len(files))
+				for _, f := range files {
+					var node yaml.Node
+					if err := yaml.Unmarshal([]byte(f.Data), &node); err != nil {
+						return nil, fmt.Errorf("%s: %w", b.Name(), err)
+					}
+
+					workflow, err := YAMLToStarlark(&node)
+					if err != nil {
+						return nil, fmt.Errorf("%s: %w", b.Name(), err)
+					}
+
+					if err := workflows.SetKey(starlark.String(f.Name), workflow); err != nil {
+						return nil, err
+					}
+				}
+
+				return workflows, nil
+			}),
+		},
+	}
+}
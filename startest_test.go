@@ -0,0 +1,163 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+const (
+	testedMainStar = `
+def main(ctx):
+  if ctx.build.event == "tag":
+    return {"release.yaml": {"steps": [{"name": "release", "image": "alpine"}]}}
+  return [{"name": "test", "steps": [{"name": "test", "image": "golang"}]}]
+
+def check(x):
+  if x < 0:
+    fail("negative", x)
+  return x
+`
+	mainTestStar = `
+load("main.star", "main", "check")
+
+def test_push():
+  workflows = testing.convert(main, build = {"event": "push"})
+  assert.eq(workflows["test.yaml"]["steps"][0]["image"], "golang")
+
+def test_tag():
+  assert.contains(testing.convert(main, build = {"event": "tag"}), "release.yaml")
+
+def test_ctx():
+  ctx = testing.ctx(repo = {"full_name": "a/b"}, build = {"number": 2})
+  assert.eq(ctx.repo.full_name, "a/b")
+  assert.eq(ctx.build.number, 2)
+  assert.eq(testing.ctx(build = {"refspec": "a:b"}, drone = True).build.source, "a")
+
+def test_fails():
+  assert.eq(assert.fails(lambda: check(-1), "negative"), "negative -1")
+
+def test_failing():
+  assert.ne(1, 1, msg = "numbers")
+
+def helper():
+  fail("not a test")
+`
+)
+
+func TestStarlarkConverter_Test(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	source := newMapSource(map[string]string{"main.star": testedMainStar})
+	results, err := c.Test(t.Context(), wccs.File{Name: "main_test.star", Data: mainTestStar, Source: source})
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	assert.Equal(t, 1, results.Failed())
+
+	for _, result := range results {
+		if result.Name == "test_failing" {
+			assert.Contains(t, result.Failure, "main_test.star:21:12")
+			assert.Contains(t, result.Failure, "assert.ne: numbers: 1 == 1")
+			continue
+		}
+
+		assert.Empty(t, result.Failure, result.Name)
+	}
+
+	t.Run("assertions", func(t *testing.T) {
+		for src, expected := range map[string]string{
+			`assert.eq(1, 2)`:                              "assert.eq: 1 != 2",
+			`assert.contains([1], 2)`:                      "assert.contains: [1] does not contain 2",
+			`assert.fails(lambda: None)`:                   "did not fail",
+			`assert.fails(lambda: fail("a"), "^b$")`:       `assert.fails: error "a" does not match "^b$"`,
+			`assert.eq(testing.convert(lambda ctx: 1), 1)`: "testing.convert: unsupported type",
+		} {
+			results, err := c.Test(t.Context(), wccs.File{Name: "a_test.star", Data: "def test_assertion():\n  " + src})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, results.Failed(), src)
+			assert.Contains(t, results[0].Failure, expected, src)
+		}
+	})
+
+	t.Run("limits every test on its own", func(t *testing.T) {
+		c, err := wccs.NewStarlarkConverter(noopLogger, wccs.WithStarlarkLimits(wccs.StarlarkLimits{MaxExecutionSteps: 1000}))
+		assert.NoError(t, err)
+
+		results, err := c.Test(t.Context(), wccs.File{Name: "a_test.star", Data: `
+def loop(n):
+  for _ in range(n):
+    pass
+
+def test_a():
+  loop(100)
+
+def test_b():
+  loop(100)
+
+def test_c():
+  loop(100)
+
+def test_endless():
+  loop(100000)
+`})
+		assert.NoError(t, err)
+		assert.Len(t, results, 4)
+		assert.Equal(t, 1, results.Failed())
+
+		for _, result := range results {
+			if result.Name == "test_endless" {
+				assert.Contains(t, result.Failure, "limit exceeded")
+				continue
+			}
+
+			assert.Empty(t, result.Failure, result.Name)
+		}
+	})
+
+	t.Run("returns errors of the test file", func(t *testing.T) {
+		_, err := c.Test(t.Context(), wccs.File{Name: "a_test.star", Data: "def test_a(:\n"})
+		assert.Error(t, err)
+	})
+}
+
+func TestStarlarkTestResults_JUnit(t *testing.T) {
+	results := wccs.StarlarkTestResults{
+		{File: "a_test.star", Name: "test_a"},
+		{File: "a_test.star", Name: "test_b", Failure: "a_test.star:2:3: runtime error: broken\nbacktrace:\n"},
+		{File: "b_test.star", Name: "test_c"},
+	}
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, results.JUnit(buf))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="1">
+  <testsuite name="a_test.star" tests="2" failures="1" time="0.000">
+    <testcase name="test_a" classname="a_test.star" time="0.000"></testcase>
+    <testcase name="test_b" classname="a_test.star" time="0.000">
+      <failure message="a_test.star:2:3: runtime error: broken">a_test.star:2:3: runtime error: broken&#xA;backtrace:&#xA;</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="b_test.star" tests="1" failures="0" time="0.000">
+    <testcase name="test_c" classname="b_test.star" time="0.000"></testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
@@ -0,0 +1,386 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// templateExtensions are the extensions of the files the TemplateConverter renders.
+var templateExtensions = []string{".yaml.tmpl", ".yaml.gotmpl", ".yml.tmpl", ".yml.gotmpl"}
+
+// templateErrorPattern matches the position of text/template errors, e.g. template: build.yaml.tmpl:3:12: ...
+var templateErrorPattern = regexp.MustCompile(`^template: ([^:]+):(\d+):(?:(\d+):)? (.*)$`)
+
+// templateRangeFunc is the function which checks the values templates range over,
+// range is a keyword and cannot be called by templates themselves.
+const templateRangeFunc = "range"
+
+// TemplateLimits restricts the resources a conversion may use, a zero value disables the limit.
+type TemplateLimits struct {
+	// Timeout of the whole conversion.
+	Timeout time.Duration
+	// MaxRange is the maximal integer a template may range over.
+	MaxRange int
+	// MaxOutputSize is the maximal size of the rendered output in bytes.
+	MaxOutputSize int
+	// MaxWorkflows is the maximal number of generated workflows.
+	MaxWorkflows int
+}
+
+// TemplateConverter renders workflows from Go templates, the data is the context Starlark scripts receive.
+type TemplateConverter struct {
+	logger *slog.Logger
+	limits TemplateLimits
+}
+
+// TemplateOption configures the TemplateConverter.
+type TemplateOption func(*TemplateConverter)
+
+// WithTemplateLimits restricts the resources a conversion may use, conversions are unlimited by default.
+func WithTemplateLimits(limits TemplateLimits) TemplateOption {
+	return func(c *TemplateConverter) {
+		c.limits = limits
+	}
+}
+
+// NewTemplateConverter returns a new TemplateConverter.
+func NewTemplateConverter(logger *slog.Logger, options ...TemplateOption) TemplateConverter {
+	c := TemplateConverter{logger: logger}
+	for _, option := range options {
+		option(&c)
+	}
+
+	return c
+}
+
+func (c TemplateConverter) Compatible(f File) bool {
+	for _, ext := range templateExtensions {
+		if strings.HasSuffix(f.Name, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Convert renders the template and returns a file per YAML document.
+// Documents with a top-level name are written to the file of that name, the name is removed from the workflow.
+func (c TemplateConverter) Convert(ctx context.Context, f File, env Environment) ([]File, error) {
+	if f.Data == "" {
+		return nil, ErrNoContent
+	}
+
+	if c.limits.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, c.limits.Timeout, fmt.Errorf("%w: timeout %s", ErrLimitExceeded, c.limits.Timeout))
+		defer cancel()
+	}
+
+	tmpl, err := template.New(path.Base(f.Name)).Option("missingkey=error").Funcs(templateFuncs(env)).Funcs(template.FuncMap{
+		templateRangeFunc: templateRange(ctx, c.limits.MaxRange),
+	}).Parse(f.Data)
+	if err != nil {
+		return nil, templateDiagnostic(err, DiagnosticKindSyntax, f)
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			limitTemplateRanges(t.Tree, t.Tree.Root)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	w := &templateWriter{ctx: ctx, w: buf, limit: c.limits.MaxOutputSize}
+	done := make(chan error, 1)
+	go func() {
+		done <- tmpl.Execute(w, contextData(env))
+	}()
+
+	select {
+	case <-ctx.Done():
+		// functions cannot be interrupted, the execution ends in the background at its next write or iteration
+		return nil, context.Cause(ctx)
+	case err := <-done:
+		if err != nil {
+			if limitErr := w.err; limitErr != nil {
+				return nil, limitErr
+			}
+
+			return nil, templateDiagnostic(err, DiagnosticKindRuntime, f)
+		}
+	}
+
+	files, err := templateFiles(buf.String(), f.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, checkOutput(files, c.limits.MaxWorkflows, 0)
+}
+
+// templateRange returns the function which is called before and in every range action, it fails once the context
+// is done and if the template ranges over an integer above the limit. The value is returned unchanged.
+func templateRange(ctx context.Context, limit int) func(...any) (any, error) {
+	return func(v ...any) (any, error) {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+
+		if len(v) == 0 {
+			return "", nil
+		}
+
+		rv := reflect.ValueOf(v[0])
+		switch {
+		case limit == 0:
+		case rv.CanInt() && rv.Int() > int64(limit):
+			return nil, fmt.Errorf("%w: range over %d exceeds %d", ErrLimitExceeded, rv.Int(), limit)
+		case rv.CanUint() && rv.Uint() > uint64(limit):
+			return nil, fmt.Errorf("%w: range over %d exceeds %d", ErrLimitExceeded, rv.Uint(), limit)
+		}
+
+		return v[0], nil
+	}
+}
+
+// limitTemplateRanges appends the templateRangeFunc to the pipeline of every range action of the node
+// and calls it at the start of every iteration.
+func limitTemplateRanges(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+
+		for _, child := range n.Nodes {
+			limitTemplateRanges(tree, child)
+		}
+	case *parse.RangeNode:
+		n.Pipe.Cmds = append(n.Pipe.Cmds, templateRangeCommand(tree, n.Pipe.Pos))
+		n.List.Nodes = append([]parse.Node{&parse.ActionNode{
+			NodeType: parse.NodeAction,
+			Pos:      n.Pipe.Pos,
+			Line:     n.Line,
+			Pipe:     &parse.PipeNode{NodeType: parse.NodePipe, Pos: n.Pipe.Pos, Line: n.Line, Cmds: []*parse.CommandNode{templateRangeCommand(tree, n.Pipe.Pos)}},
+		}}, n.List.Nodes...)
+		limitTemplateRanges(tree, n.List)
+		limitTemplateRanges(tree, n.ElseList)
+	case *parse.IfNode:
+		limitTemplateRanges(tree, n.List)
+		limitTemplateRanges(tree, n.ElseList)
+	case *parse.WithNode:
+		limitTemplateRanges(tree, n.List)
+		limitTemplateRanges(tree, n.ElseList)
+	}
+}
+
+// templateRangeCommand returns a command which calls the templateRangeFunc.
+func templateRangeCommand(tree *parse.Tree, pos parse.Pos) *parse.CommandNode {
+	return &parse.CommandNode{
+		NodeType: parse.NodeCommand,
+		Pos:      pos,
+		Args:     []parse.Node{parse.NewIdentifier(templateRangeFunc).SetTree(tree).SetPos(pos)},
+	}
+}
+
+// templateFiles splits the rendered output into its YAML documents, empty documents are skipped.
+func templateFiles(out, entry string) ([]File, error) {
+	base := strings.TrimSuffix(path.Base(entry), path.Ext(entry))
+	base = strings.TrimSuffix(base, path.Ext(base))
+
+	var documents []*yaml.Node
+	dec := yaml.NewDecoder(strings.NewReader(out))
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// make sure woodpecker gets valid YAML, errors are easier to find here
+			return nil, fmt.Errorf("%w: rendered %s", err, entry)
+		}
+
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+
+		if node.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%w: workflow %d of %s must be a mapping", ErrUnsupportedType, len(documents)+1, entry)
+		}
+		documents = append(documents, &node)
+	}
+
+	files := make([]File, 0, len(documents))
+	for i, node := range documents {
+		name := base + ".yaml"
+		if len(documents) > 1 {
+			name = fmt.Sprintf("%s-%d.yaml", base, i+1)
+		}
+
+		workflow := node.Content[0]
+		for j := 0; j+1 < len(workflow.Content); j += 2 {
+			if key := workflow.Content[j]; key.Value == "name" && workflow.Content[j+1].Kind == yaml.ScalarNode {
+				name = strings.TrimSuffix(workflow.Content[j+1].Value, path.Ext(workflow.Content[j+1].Value)) + ".yaml"
+				workflow.Content = append(workflow.Content[:j], workflow.Content[j+2:]...)
+
+				break
+			}
+		}
+
+		name, err := resolveModulePath("", name)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := encodeYAML(node)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, File{Name: name, Data: data})
+	}
+
+	return files, nil
+}
+
+// templateWriter fails once the context is done or the output exceeds its limit, this ends long running templates.
+type templateWriter struct {
+	ctx   context.Context
+	w     io.Writer
+	limit int
+	size  int
+	err   error
+}
+
+func (w *templateWriter) Write(p []byte) (int, error) {
+	if w.ctx.Err() != nil {
+		w.err = context.Cause(w.ctx)
+		return 0, w.err
+	}
+
+	if w.size += len(p); w.limit != 0 && w.size > w.limit {
+		w.err = fmt.Errorf("%w: max output size %d bytes", ErrLimitExceeded, w.limit)
+		return 0, w.err
+	}
+
+	return w.w.Write(p)
+}
+
+// templateDiagnostic turns a text/template error into a Diagnostic, errors without a position are returned unchanged.
+func templateDiagnostic(err error, kind DiagnosticKind, f File) error {
+	match := templateErrorPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+
+	line, _ := strconv.Atoi(match[2])
+	d := &Diagnostic{Kind: kind, File: f.Name, Line: int32(line), Column: 1, Message: match[4], err: err}
+	// text/template counts columns from 0, parse errors have no column
+	if column, err := strconv.Atoi(match[3]); err == nil {
+		d.Column = int32(column) + 1
+	}
+	if lines := strings.Split(f.Data, "\n"); d.Line > 0 && int(d.Line) <= len(lines) {
+		d.Source = strings.TrimRight(lines[d.Line-1], "\r")
+	}
+
+	return d
+}
+
+// templateFuncs returns the functions which are available to templates, none of them has access to the environment
+// except changed, which matches the changed files of the pipeline.
+func templateFuncs(env Environment) template.FuncMap {
+	return template.FuncMap{
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join": func(sep string, elems any) (string, error) {
+			v := reflect.ValueOf(elems)
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return "", fmt.Errorf("%w: join of %T", ErrUnsupportedType, elems)
+			}
+
+			s := make([]string, 0, v.Len())
+			for i := range v.Len() {
+				s = append(s, fmt.Sprint(v.Index(i).Interface()))
+			}
+
+			return strings.Join(s, sep), nil
+		},
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"nindent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return "\n" + pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"quote": strconv.Quote,
+		"default": func(fallback, v any) any {
+			if v == nil || reflect.ValueOf(v).IsZero() {
+				return fallback
+			}
+
+			return v
+		},
+		"toYaml": func(v any) (string, error) {
+			node := new(yaml.Node)
+			if err := node.Encode(v); err != nil {
+				return "", err
+			}
+
+			out, err := encodeYAML(node)
+			return strings.TrimSuffix(out, "\n"), err
+		},
+		"match": func(pattern, name string) (bool, error) {
+			return doublestar.Match(pattern, name)
+		},
+		"changed": func(patterns ...string) (bool, error) {
+			for _, name := range env.Pipeline.ChangedFiles {
+				for _, pattern := range patterns {
+					if ok, err := doublestar.Match(pattern, name); ok || err != nil {
+						return ok, err
+					}
+				}
+			}
+
+			return false, nil
+		},
+	}
+}
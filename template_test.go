@@ -0,0 +1,142 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.woodpecker-ci.org/woodpecker/v3/server/model"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestTemplateConverter_Compatible(t *testing.T) {
+	c := wccs.NewTemplateConverter(noopLogger)
+	for name, compatible := range map[string]bool{
+		".woodpecker/build.yaml.tmpl":   true,
+		".woodpecker/build.yaml.gotmpl": true,
+		"build.yml.tmpl":                true,
+		"build.yaml":                    false,
+		"build.tmpl":                    false,
+		"build.star":                    false,
+	} {
+		assert.Equal(t, compatible, c.Compatible(wccs.File{Name: name}), name)
+	}
+}
+
+func TestTemplateConverter_Convert(t *testing.T) {
+	env := wccs.Environment{
+		Repo:     model.Repo{FullName: "opencloud-eu/web", Owner: "opencloud-eu"},
+		Pipeline: model.Pipeline{Event: model.EventPush, ChangedFiles: []string{"web/src/app.ts", "README.md"}},
+		Netrc:    model.Netrc{Login: "secret", Password: "secret"},
+	}
+	c := wccs.NewTemplateConverter(noopLogger)
+
+	t.Run("renders a file per document", func(t *testing.T) {
+		files, err := c.Convert(t.Context(), wccs.File{Name: ".woodpecker/build.yaml.tmpl", Data: `
+steps:
+  - name: {{ .repo.full_name | replace "/" "-" | upper }}
+    image: {{ default "alpine" (index .build.variables "IMAGE") }}
+    commands: {{ toYaml (split "," "make,make test") | nindent 6 }}
+{{- if changed "web/**" "ui/**" }}
+---
+name: frontend
+when: {event: {{ .build.event }}}
+steps:
+  - name: build
+    image: node
+{{- end }}
+{{- if hasPrefix "docs/" (index .build.changed_files 1) }}
+---
+name: docs
+{{- end }}
+---
+`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{
+			{Name: "build-1.yaml", Data: "steps:\n  - name: OPENCLOUD-EU-WEB\n    image: alpine\n    commands:\n      - make\n      - make test\n"},
+			{Name: "frontend.yaml", Data: "when: {event: push}\nsteps:\n  - name: build\n    image: node\n"},
+		}, files)
+
+		files, err = c.Convert(t.Context(), wccs.File{Name: "build.yaml.gotmpl", Data: `steps: [{name: test, image: {{ quote .repo.owner }}}]`}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{{Name: "build.yaml", Data: "steps: [{name: test, image: \"opencloud-eu\"}]\n"}}, files)
+	})
+
+	t.Run("reports template errors with their position", func(t *testing.T) {
+		for src, expected := range map[string]string{
+			"steps:\n  - name: {{ .repo.full_name\n":   "build.yaml.tmpl:3:1: unclosed action",
+			"steps:\n  - name: {{ .repo.fullname }}\n": "build.yaml.tmpl:2:19: executing",
+			"steps: {{ .netrc.login }}\n":              `map has no entry for key "netrc"`,
+			"steps: {{ unknown }}\n":                   `function "unknown" not defined`,
+		} {
+			_, err := c.Convert(t.Context(), wccs.File{Name: "build.yaml.tmpl", Data: src}, env)
+
+			var diagnostic *wccs.Diagnostic
+			assert.True(t, errors.As(err, &diagnostic), src)
+			assert.ErrorContains(t, err, expected, src)
+		}
+	})
+
+	t.Run("validates the rendered YAML", func(t *testing.T) {
+		_, err := c.Convert(t.Context(), wccs.File{Name: "build.yaml.tmpl", Data: "steps: [{{ .repo.owner }}\n"}, env)
+		assert.ErrorContains(t, err, "rendered build.yaml.tmpl")
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "build.yaml.tmpl", Data: "- {{ .repo.owner }}\n"}, env)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+	})
+
+	t.Run("limits the output", func(t *testing.T) {
+		src := wccs.File{Name: "build.yaml.tmpl", Data: "{{ range 100000 }}steps: []\n{{ end }}"}
+		_, err := wccs.NewTemplateConverter(noopLogger, wccs.WithTemplateLimits(wccs.TemplateLimits{MaxOutputSize: 1024})).Convert(t.Context(), src, env)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+
+		_, err = wccs.NewTemplateConverter(noopLogger, wccs.WithTemplateLimits(wccs.TemplateLimits{MaxWorkflows: 2})).Convert(t.Context(), wccs.File{Name: "build.yaml.tmpl", Data: "{{ range 3 }}---\nsteps: []\n{{ end }}"}, env)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		_, err = c.Convert(ctx, src, env)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = c.Convert(t.Context(), wccs.File{Name: "build.yaml.tmpl"}, env)
+		assert.ErrorIs(t, err, wccs.ErrNoContent)
+	})
+
+	t.Run("limits ranges and the time", func(t *testing.T) {
+		limited := wccs.NewTemplateConverter(noopLogger, wccs.WithTemplateLimits(wccs.TemplateLimits{Timeout: 100 * time.Millisecond, MaxRange: 1000}))
+
+		_, err := limited.Convert(t.Context(), wccs.File{Name: "build.yaml.tmpl", Data: "steps: []\n{{ define \"loop\" }}{{ range $i := . }}{{ end }}{{ end }}\n{{ template \"loop\" 100000000000 }}"}, env)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+		var diagnostic *wccs.Diagnostic
+		assert.True(t, errors.As(err, &diagnostic))
+		assert.Equal(t, int32(2), diagnostic.Line)
+		assert.Contains(t, diagnostic.Message, "range over 100000000000 exceeds 1000")
+
+		files, err := limited.Convert(t.Context(), wccs.File{Name: "build.yaml.tmpl", Data: "steps:\n{{- range $i := 2 }}\n  - name: step-{{ $i }}\n    image: alpine\n{{- end }}\n"}, env)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+
+		start := time.Now()
+		_, err = wccs.NewTemplateConverter(noopLogger, wccs.WithTemplateLimits(wccs.TemplateLimits{Timeout: 100 * time.Millisecond})).Convert(t.Context(), wccs.File{Name: "build.yaml.tmpl", Data: "{{ range 100000000000 }}{{ end }}"}, env)
+		assert.ErrorIs(t, err, wccs.ErrLimitExceeded)
+		assert.ErrorContains(t, err, "timeout 100ms")
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
@@ -0,0 +1,72 @@
+// The schema of the woodpecker workflow format which the workflows of CUE files are unified with.
+// The definitions are open, settings which are not declared here are passed to woodpecker unchanged.
+
+package wccs
+
+workflows: [string]: #Workflow
+
+#Workflow: {
+	when?: #When | [...#When]
+	depends_on?: [...string]
+	runs_on?: [...string]
+	labels?: [string]: string
+	skip_clone?: bool
+	clone?:      #Steps
+	steps:       #Steps
+	services?:   #Steps
+	workspace?: {
+		base?: string
+		path?: string
+	}
+	matrix?: {
+		include?: [...{[string]: #Scalar}]
+		[string]: [...#Scalar]
+	}
+	...
+}
+
+#Steps: [...#Step] | {[string]: #Step}
+
+#Step: {
+	name?:       string
+	image:       string
+	pull?:       bool
+	detach?:     bool
+	privileged?: bool
+	directory?:  string
+	commands?: string | [...string]
+	entrypoint?: string | [...string]
+	environment?: [string]: #Scalar | {from_secret: string}
+	settings?: [string]: _
+	volumes?: [...string]
+	ports?: [...(int | string)]
+	failure?: "fail" | "ignore"
+	depends_on?: string | [...string]
+	when?: #When | [...#When]
+	...
+}
+
+#When: {
+	event?:    #Constraint
+	branch?:   #Constraint
+	ref?:      #Constraint
+	status?:   #Constraint
+	platform?: #Constraint
+	repo?:     #Constraint
+	path?: string | [...string] | {
+		include?: string | [...string]
+		exclude?: string | [...string]
+		on_empty?:       bool
+		ignore_message?: string
+	}
+	evaluate?: string
+	matrix?: [string]: #Scalar
+	...
+}
+
+#Constraint: string | [...string] | {
+	include?: string | [...string]
+	exclude?: string | [...string]
+}
+
+#Scalar: string | number | bool
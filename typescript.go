@@ -0,0 +1,1085 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// scriptPunctuators are the punctuators of scripts, longer ones first.
+// > is always a token of its own, this keeps the end of nested type arguments like Array<Array<T>> apart.
+var scriptPunctuators = []string{
+	"...", "===", "!==", "**=", "<<=", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+	"&&", "||", "??", "?.", "++", "--", "**", "<<",
+}
+
+// scriptKeywords are the reserved words which never end an expression.
+var scriptKeywords = map[string]bool{
+	"await": true, "break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "export": true, "extends": true,
+	"finally": true, "for": true, "function": true, "if": true, "import": true, "in": true, "instanceof": true,
+	"let": true, "new": true, "return": true, "switch": true, "throw": true, "try": true, "typeof": true,
+	"var": true, "void": true, "while": true, "with": true, "yield": true,
+}
+
+// scriptTypeArgumentPunctuators are the punctuators which may be part of type arguments,
+// all others end the speculative parsing of f<T>(x) calls.
+var scriptTypeArgumentPunctuators = map[string]bool{
+	"<": true, ">": true, ",": true, ".": true, "|": true, "&": true, "=>": true,
+	"?": true, ":": true, "=": true, "[": true, "(": true, "{": true, "...": true, "-": true,
+}
+
+// scriptTokenKind classifies the tokens of a script.
+type scriptTokenKind int
+
+const (
+	scriptPunctuator scriptTokenKind = iota
+	scriptIdentifier
+	scriptNumber
+	scriptString
+	scriptTemplate
+	scriptRegexp
+)
+
+// scriptToken is a token of a script, start and end are byte offsets in the source.
+type scriptToken struct {
+	kind       scriptTokenKind
+	text       string
+	start, end int
+	// newline reports whether a line break precedes the token.
+	newline bool
+	// substitution reports whether the template part is followed by a substitution.
+	substitution bool
+}
+
+// is reports whether the token is a punctuator or identifier with one of the given texts.
+func (t scriptToken) is(texts ...string) bool {
+	if t.kind != scriptPunctuator && t.kind != scriptIdentifier {
+		return false
+	}
+
+	for _, text := range texts {
+		if t.text == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+// endsExpression reports whether an expression may end with the token.
+func (t scriptToken) endsExpression() bool {
+	switch t.kind {
+	case scriptNumber, scriptString, scriptRegexp:
+		return true
+	case scriptTemplate:
+		return !t.substitution
+	case scriptIdentifier:
+		return !scriptKeywords[t.text]
+	default:
+		return t.is(")", "]", "}")
+	}
+}
+
+// scriptError is a syntax error at the given byte offset of a script.
+type scriptError struct {
+	offset  int
+	message string
+}
+
+// Error implements the error interface.
+func (e *scriptError) Error() string {
+	return e.message
+}
+
+// scanScript splits the script into tokens, whitespace and comments are skipped.
+// The returned map points from every opening bracket and template part with a substitution
+// to the token which closes it.
+func scanScript(src string) ([]scriptToken, map[int]int, error) {
+	var tokens []scriptToken
+	match := map[int]int{}
+	// open brackets and template parts
+	var open []int
+	newline := false
+
+	for pos := 0; pos < len(src); {
+		c := src[pos]
+		switch {
+		case c == '\n' || c == '\r':
+			newline = true
+			pos++
+
+			continue
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			pos++
+
+			continue
+		case strings.HasPrefix(src[pos:], "//"):
+			end := strings.IndexAny(src[pos:], "\r\n")
+			if end < 0 {
+				end = len(src) - pos
+			}
+			pos += end
+
+			continue
+		case strings.HasPrefix(src[pos:], "/*"):
+			end := strings.Index(src[pos+2:], "*/")
+			if end < 0 {
+				return nil, nil, &scriptError{offset: pos, message: "unterminated comment"}
+			}
+			newline = newline || strings.ContainsAny(src[pos:pos+2+end], "\r\n")
+			pos += end + 4
+
+			continue
+		case c >= utf8.RuneSelf:
+			if r, size := utf8.DecodeRuneInString(src[pos:]); unicode.IsSpace(r) || r == '\ufeff' {
+				newline = newline || r == '\u2028' || r == '\u2029'
+				pos += size
+
+				continue
+			}
+		}
+
+		t := scriptToken{start: pos, newline: newline}
+		newline = false
+
+		var err error
+		switch {
+		case c == '}' && len(open) != 0 && tokens[open[len(open)-1]].kind == scriptTemplate:
+			t.kind = scriptTemplate
+			t.end, t.substitution, err = scanTemplate(src, pos+1)
+			match[open[len(open)-1]] = len(tokens)
+			open = open[:len(open)-1]
+		case c == '`':
+			t.kind = scriptTemplate
+			t.end, t.substitution, err = scanTemplate(src, pos+1)
+		case c == '"' || c == '\'':
+			t.kind = scriptString
+			t.end, err = scanString(src, pos)
+		case c >= '0' && c <= '9' || c == '.' && pos+1 < len(src) && src[pos+1] >= '0' && src[pos+1] <= '9':
+			t.kind = scriptNumber
+			t.end = scanNumber(src, pos)
+		case isScriptIdentifierStart(src, pos):
+			t.kind = scriptIdentifier
+			t.end = scanIdentifier(src, pos)
+		case c == '/' && (len(tokens) == 0 || regexpAllowed(tokens[len(tokens)-1])):
+			t.kind = scriptRegexp
+			t.end, err = scanRegexp(src, pos)
+		default:
+			t.kind = scriptPunctuator
+			t.end = pos + 1
+			for _, p := range scriptPunctuators {
+				// ?. followed by a digit is a conditional, a?.5:1
+				if strings.HasPrefix(src[pos:], p) && (p != "?." || pos+2 >= len(src) || src[pos+2] < '0' || src[pos+2] > '9') {
+					t.end = pos + len(p)
+					break
+				}
+			}
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		t.text = src[t.start:t.end]
+		pos = t.end
+
+		switch {
+		case t.kind == scriptTemplate && t.substitution:
+			open = append(open, len(tokens))
+		case t.is("(", "[", "{"):
+			open = append(open, len(tokens))
+		case t.is(")", "]", "}"):
+			if len(open) == 0 || tokens[open[len(open)-1]].text != map[string]string{")": "(", "]": "[", "}": "{"}[t.text] {
+				return nil, nil, &scriptError{offset: t.start, message: "unexpected " + t.text}
+			}
+			match[open[len(open)-1]] = len(tokens)
+			open = open[:len(open)-1]
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	if len(open) != 0 {
+		return nil, nil, &scriptError{offset: tokens[open[len(open)-1]].start, message: "unclosed " + tokens[open[len(open)-1]].text}
+	}
+
+	return tokens, match, nil
+}
+
+// scanTemplate returns the end of the template part which starts at pos and whether a substitution follows it.
+func scanTemplate(src string, pos int) (int, bool, error) {
+	for i := pos; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == '`':
+			return i + 1, false, nil
+		case strings.HasPrefix(src[i:], "${"):
+			return i + 2, true, nil
+		}
+	}
+
+	return 0, false, &scriptError{offset: pos - 1, message: "unterminated template literal"}
+}
+
+// scanString returns the end of the string literal which starts at pos.
+func scanString(src string, pos int) (int, error) {
+	for i := pos + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case src[pos]:
+			return i + 1, nil
+		case '\n', '\r':
+			return 0, &scriptError{offset: pos, message: "unterminated string literal"}
+		}
+	}
+
+	return 0, &scriptError{offset: pos, message: "unterminated string literal"}
+}
+
+// scanNumber returns the end of the numeric literal which starts at pos.
+func scanNumber(src string, pos int) int {
+	i := pos
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_':
+			i++
+		case c == '.' && !strings.Contains(src[pos:i], ".") && !strings.ContainsAny(src[pos:i], "xXbBoO"):
+			i++
+		case (c == '+' || c == '-') && i > pos && (src[i-1] == 'e' || src[i-1] == 'E') && !strings.ContainsAny(src[pos:i], "xX"):
+			i++
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+// isScriptIdentifierStart reports whether an identifier or private name starts at pos.
+func isScriptIdentifierStart(src string, pos int) bool {
+	r, _ := utf8.DecodeRuneInString(src[pos:])
+	if r == '#' && pos+1 < len(src) {
+		r, _ = utf8.DecodeRuneInString(src[pos+1:])
+	}
+
+	return r == '_' || r == '$' || r == '\\' || unicode.IsLetter(r)
+}
+
+// scanIdentifier returns the end of the identifier or private name which starts at pos.
+func scanIdentifier(src string, pos int) int {
+	i := pos
+	if src[i] == '#' {
+		i++
+	}
+
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		if r != '_' && r != '$' && r != '\\' && r != '\u200c' && r != '\u200d' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		i += size
+	}
+
+	return i
+}
+
+// regexpAllowed reports whether a slash after the given token starts a regular expression instead of a division.
+func regexpAllowed(prev scriptToken) bool {
+	if prev.kind == scriptIdentifier {
+		return scriptKeywords[prev.text]
+	}
+
+	return !prev.endsExpression() || prev.is("}")
+}
+
+// scanRegexp returns the end of the regular expression literal which starts at pos.
+func scanRegexp(src string, pos int) (int, error) {
+	class := false
+	for i := pos + 1; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\\':
+			i++
+		case c == '\n' || c == '\r':
+			return 0, &scriptError{offset: pos, message: "unterminated regular expression"}
+		case c == '[':
+			class = true
+		case c == ']':
+			class = false
+		case c == '/' && !class:
+			// flags
+			return scanIdentifier(src, i+1), nil
+		}
+	}
+
+	return 0, &scriptError{offset: pos, message: "unterminated regular expression"}
+}
+
+// scriptFrameKind classifies the brackets a token is enclosed by.
+type scriptFrameKind int
+
+const (
+	// scriptBlock is the script itself or a block of statements.
+	scriptBlock scriptFrameKind = iota
+	// scriptClass is a class body.
+	scriptClass
+	// scriptObject is an object literal or pattern.
+	scriptObject
+	// scriptParams are the parameters of a function.
+	scriptParams
+	// scriptParen are all other parentheses and template substitutions.
+	scriptParen
+	// scriptBracket is an array literal, pattern or computed member.
+	scriptBracket
+)
+
+// scriptFrame is the state of the brackets a token is enclosed by.
+type scriptFrame struct {
+	kind scriptFrameKind
+	// ternary counts the conditionals whose colon is missing.
+	ternary int
+	// declaration reports whether a let, const or var declaration is in progress.
+	declaration bool
+	// initializer reports whether the initializer of a class field is in progress.
+	initializer bool
+}
+
+// scriptStripper strips the module syntax and TypeScript types of a script, erased tokens are replaced with spaces.
+type scriptStripper struct {
+	tokens []scriptToken
+	match  map[int]int
+	out    []byte
+	erased []bool
+	// types reports whether TypeScript types are stripped.
+	types bool
+	// classBodies are the opening braces of class bodies.
+	classBodies map[int]bool
+	// labels are the colons of labels and case clauses.
+	labels map[int]bool
+	// statement is a token which starts a statement after an erased export.
+	statement int
+	// main is the name of a function which is exported as main or as default export under another name.
+	main string
+}
+
+// stripScript returns the script without import and export declarations, goja runs scripts instead of modules.
+// For TypeScript the types are stripped as well. Only erasable syntax is supported,
+// enums, namespaces and parameter properties are reported as errors.
+// The stripped syntax is replaced with spaces, positions in the returned script are the positions of the source.
+func stripScript(src string, typescript bool) (string, error) {
+	tokens, match, err := scanScript(src)
+	if err != nil {
+		return "", err
+	}
+
+	s := &scriptStripper{
+		tokens:      tokens,
+		match:       match,
+		out:         []byte(src),
+		erased:      make([]bool, len(tokens)),
+		types:       typescript,
+		classBodies: map[int]bool{},
+		labels:      map[int]bool{},
+		statement:   -1,
+	}
+	if err := s.strip(); err != nil {
+		return "", err
+	}
+
+	// the alias is appended, positions of the source do not change
+	if s.main != "" {
+		s.out = append(s.out, "\nvar main = "+s.main+";\n"...)
+	}
+
+	return string(s.out), nil
+}
+
+// tok returns the token at i, an empty token past the end.
+func (s *scriptStripper) tok(i int) scriptToken {
+	if i < 0 || i >= len(s.tokens) {
+		return scriptToken{kind: scriptPunctuator}
+	}
+
+	return s.tokens[i]
+}
+
+// is reports whether the token at i has one of the given texts.
+func (s *scriptStripper) is(i int, texts ...string) bool {
+	return s.tok(i).is(texts...)
+}
+
+// before returns the index of the last token before i which is not erased.
+func (s *scriptStripper) before(i int) int {
+	for i--; i >= 0 && s.erased[i]; i-- {
+	}
+
+	return i
+}
+
+// erase replaces the tokens from up to excluding to and everything between them with spaces, line breaks are kept.
+func (s *scriptStripper) erase(from, to int) {
+	if from >= to {
+		return
+	}
+
+	for i := from; i < to; i++ {
+		s.erased[i] = true
+	}
+
+	for b := s.tokens[from].start; b < s.tokens[to-1].end; b++ {
+		if s.out[b] != '\n' && s.out[b] != '\r' {
+			s.out[b] = ' '
+		}
+	}
+}
+
+// errorAt returns a scriptError at the token i.
+func (s *scriptStripper) errorAt(i int, message string) error {
+	offset := len(s.out)
+	if i < len(s.tokens) {
+		offset = s.tokens[i].start
+	}
+
+	return &scriptError{offset: offset, message: message}
+}
+
+func (s *scriptStripper) strip() error {
+	frames := []scriptFrame{{kind: scriptBlock}}
+	prev := -1
+	for i := 0; i < len(s.tokens); i++ {
+		if s.erased[i] {
+			continue
+		}
+
+		t := s.tokens[i]
+		f := &frames[len(frames)-1]
+		start := s.statementStart(i, prev, *f)
+		// declarations and class fields without semicolon end with the line
+		if t.newline && t.kind == scriptIdentifier && prev >= 0 && s.tokens[prev].endsExpression() {
+			f.declaration, f.initializer = false, false
+		}
+
+		switch {
+		case t.kind == scriptTemplate:
+			if strings.HasPrefix(t.text, "}") {
+				frames = frames[:len(frames)-1]
+			}
+			if t.substitution {
+				frames = append(frames, scriptFrame{kind: scriptParen})
+			}
+		case t.is(")", "]", "}"):
+			closed := frames[len(frames)-1]
+			frames = frames[:len(frames)-1]
+			if closed.kind == scriptParams && s.types && s.is(i+1, ":") {
+				// return type
+				end, ok := s.skipType(i + 2)
+				if !ok {
+					return s.errorAt(end, "type expected")
+				}
+				s.erase(i+1, end)
+			}
+			if closed.kind == scriptBlock && frames[len(frames)-1].kind == scriptClass {
+				frames[len(frames)-1].initializer = false
+			}
+		case t.is("("):
+			frames = append(frames, scriptFrame{kind: s.parenKind(i, prev, *f)})
+		case t.is("["):
+			frames = append(frames, scriptFrame{kind: scriptBracket})
+		case t.is("{"):
+			kind := scriptObject
+			switch {
+			case s.classBodies[i]:
+				kind = scriptClass
+			case s.braceIsBlock(prev):
+				kind = scriptBlock
+			}
+			frames = append(frames, scriptFrame{kind: kind})
+		case start && len(frames) == 1 && t.is("import", "export"):
+			if err := s.moduleDeclaration(i); err != nil {
+				return err
+			}
+		case start && s.types && t.kind == scriptIdentifier:
+			if err := s.typeDeclaration(i); err != nil {
+				return err
+			}
+		}
+
+		if !s.erased[i] && s.types {
+			if err := s.typeSyntax(i, prev, f); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case s.erased[i]:
+			continue
+		case t.is(";"):
+			f.ternary, f.declaration, f.initializer = 0, false, false
+		case t.is("let", "const", "var"):
+			f.declaration = true
+		case t.is("=") && f.kind == scriptClass:
+			f.initializer = true
+		case t.is("class") && !s.is(prev, "."):
+			s.classHeader(i)
+		}
+
+		prev = i
+	}
+
+	return nil
+}
+
+// statementStart reports whether the token at i may start a statement.
+func (s *scriptStripper) statementStart(i, prev int, f scriptFrame) bool {
+	if f.kind != scriptBlock {
+		return false
+	}
+
+	return i == s.statement || prev < 0 || s.tokens[i].newline || s.is(prev, ";", "{", "}")
+}
+
+// parenKind returns whether the parenthesis at i encloses the parameters of a function.
+func (s *scriptStripper) parenKind(i, prev int, f scriptFrame) scriptFrameKind {
+	if !s.types {
+		return scriptParen
+	}
+
+	p := s.tok(prev)
+	switch {
+	case p.is("function", "catch"):
+		return scriptParams
+	case p.kind == scriptIdentifier && !scriptKeywords[p.text], p.kind == scriptString, p.is("]"):
+		pp := s.before(prev)
+		if s.is(pp, "*") {
+			pp = s.before(pp)
+		}
+
+		switch {
+		case s.is(pp, "function"):
+			return scriptParams
+		case f.kind == scriptClass && !f.initializer:
+			// methods
+			return scriptParams
+		case f.kind == scriptObject && s.is(pp, "{", ",", "get", "set", "async", "*"):
+			// method shorthands
+			return scriptParams
+		}
+	}
+
+	if s.isArrow(i) {
+		return scriptParams
+	}
+
+	return scriptParen
+}
+
+// isArrow reports whether the parenthesis at i encloses the parameters of an arrow function.
+func (s *scriptStripper) isArrow(i int) bool {
+	end := s.match[i] + 1
+	if s.is(end, "=>") {
+		return true
+	}
+
+	if !s.is(end, ":") {
+		return false
+	}
+
+	end, ok := s.skipType(end + 1)
+
+	return ok && s.is(end, "=>")
+}
+
+// braceIsBlock reports whether a brace after the token prev opens a block instead of an object literal.
+func (s *scriptStripper) braceIsBlock(prev int) bool {
+	if prev < 0 || s.labels[prev] {
+		return true
+	}
+
+	return s.is(prev, ";", "{", "}", ")", "=>", "else", "do", "try", "finally")
+}
+
+// moduleDeclaration strips the import or export declaration at i, only type imports, the export keyword
+// of declarations, default exported functions and export lists can be stripped.
+func (s *scriptStripper) moduleDeclaration(i int) error {
+	next := s.tok(i + 1)
+	if s.is(i, "import") {
+		switch {
+		case next.is("(", "."):
+			// dynamic imports and import.meta are expressions
+			return nil
+		case s.types && next.is("type") && !s.is(i+2, "from", ",", "="):
+			s.erase(i, s.moduleSpecifierEnd(i))
+			return nil
+		}
+
+		return s.errorAt(i, "import declarations are not supported, scripts cannot load other files")
+	}
+
+	switch {
+	case s.types && next.is("type") && s.is(i+2, "{", "*"):
+		s.erase(i, s.moduleSpecifierEnd(i))
+	case s.types && next.is("type", "interface", "declare", "enum", "abstract"),
+		next.is("function", "async", "class", "const", "let", "var"):
+		s.erase(i, i+1)
+		s.statement = i + 1
+	case next.is("default"):
+		return s.defaultExport(i)
+	case next.is("{"):
+		return s.exportList(i)
+	default:
+		return s.errorAt(i, "only declarations can be exported, e.g. export function main(ctx)")
+	}
+
+	return nil
+}
+
+// defaultExport strips export default of the function declaration at i, the function becomes main.
+func (s *scriptStripper) defaultExport(i int) error {
+	name := i + 2
+	if s.is(name, "async") {
+		name++
+	}
+	if !s.is(name, "function") {
+		return s.errorAt(i, "only functions can be exported as default, e.g. export default function main(ctx)")
+	}
+	if name++; s.is(name, "*") {
+		name++
+	}
+	if s.tok(name).kind != scriptIdentifier || scriptKeywords[s.tok(name).text] {
+		return s.errorAt(name, "default exported functions must be named, e.g. export default function main(ctx)")
+	}
+
+	if err := s.exportMain(i, s.tok(name).text); err != nil {
+		return err
+	}
+	s.erase(i, i+2)
+	s.statement = i + 2
+
+	return nil
+}
+
+// exportList strips the export list at i, a binding which is exported as main becomes main.
+func (s *scriptStripper) exportList(i int) error {
+	end := s.match[i+1]
+	if s.is(end+1, "from") {
+		return s.errorAt(i, "re-exports are not supported, scripts cannot load other files")
+	}
+
+	for j := i + 2; j < end; j++ {
+		local := j
+		// inline type exports are removed with the list
+		if s.types && s.is(j, "type") && s.tok(j+1).kind == scriptIdentifier && !s.is(j+1, "as") {
+			j++
+			local = -1
+		}
+		exported := j
+		if s.is(j+1, "as") {
+			exported = j + 2
+			j += 2
+		}
+		if !s.is(j+1, ",") && j+1 != end {
+			return s.errorAt(j+1, "} expected")
+		}
+		j++
+
+		if local >= 0 && s.tok(exported).text == "main" {
+			if err := s.exportMain(i, s.tok(local).text); err != nil {
+				return err
+			}
+		}
+	}
+	s.erase(i, s.moduleSpecifierEnd(i))
+
+	return nil
+}
+
+// exportMain records that the function name is exported as main.
+func (s *scriptStripper) exportMain(i int, name string) error {
+	if s.main != "" {
+		return s.errorAt(i, "main is exported more than once")
+	}
+	if name != "main" {
+		s.main = name
+	}
+
+	return nil
+}
+
+// moduleSpecifierEnd returns the end of the import or export declaration at i, including the semicolon.
+func (s *scriptStripper) moduleSpecifierEnd(i int) int {
+	for i++; i < len(s.tokens); i++ {
+		switch {
+		case s.is(i, "{"):
+			if i = s.match[i]; !s.is(i+1, "from", ";") {
+				return i + 1
+			}
+		case s.is(i, ";"):
+			return i + 1
+		case s.tokens[i].kind == scriptString && s.is(i-1, "from"):
+			if s.is(i+1, ";") {
+				return i + 2
+			}
+
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+// typeDeclaration strips the type alias, interface or ambient declaration at i.
+func (s *scriptStripper) typeDeclaration(i int) error {
+	next := s.tok(i + 1)
+	if next.newline || next.kind != scriptIdentifier {
+		return nil
+	}
+
+	switch {
+	case s.is(i, "type") && s.is(i+2, "=", "<"):
+		end := i + 2
+		if s.is(end, "<") {
+			var ok bool
+			if end, ok = s.skipTypeArguments(end); !ok {
+				return s.errorAt(end, "type parameters expected")
+			}
+		}
+		if !s.is(end, "=") {
+			return s.errorAt(end, "= expected")
+		}
+
+		end, ok := s.skipType(end + 1)
+		if !ok {
+			return s.errorAt(end, "type expected")
+		}
+		if s.is(end, ";") {
+			end++
+		}
+		s.erase(i, end)
+	case s.is(i, "interface"):
+		end := i + 2
+		for end < len(s.tokens) && !s.is(end, "{") {
+			if s.is(end, "<") {
+				end, _ = s.skipTypeArguments(end)
+				continue
+			}
+			end++
+		}
+		if end >= len(s.tokens) {
+			return s.errorAt(end, "{ expected")
+		}
+		s.erase(i, s.match[end]+1)
+	case s.is(i, "declare"):
+		s.erase(i, s.ambientEnd(i))
+	case s.is(i, "enum") || s.is(i, "const") && next.is("enum"):
+		return s.errorAt(i, "enums are not supported, only types can be stripped")
+	case s.is(i, "namespace", "module") && (s.is(i+2, "{", ".")):
+		return s.errorAt(i, "namespaces are not supported, only types can be stripped")
+	case s.is(i, "abstract") && next.is("class"):
+		return s.errorAt(i, "abstract classes are not supported, only types can be stripped")
+	}
+
+	return nil
+}
+
+// ambientEnd returns the end of the declare statement or class member at i.
+func (s *scriptStripper) ambientEnd(i int) int {
+	for i++; i < len(s.tokens); i++ {
+		switch {
+		case s.is(i, ";"):
+			return i + 1
+		case s.is(i, "{", "(", "["):
+			i = s.match[i]
+			if s.is(i, "}") && !s.is(i+1, "|", "&", "[", ";") {
+				// the body of a namespace, module or class
+				return i + 1
+			}
+		}
+
+		// declarations without semicolon end with the line unless the type continues
+		t := s.tokens[i]
+		if next := s.tok(i + 1); next.newline && (t.kind != scriptPunctuator || t.is(")", "]", "}", ">")) &&
+			!next.is("|", "&", ".", "=>", "extends", "?", ":", "[") {
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+// typeSyntax strips the type syntax which starts at the token i.
+func (s *scriptStripper) typeSyntax(i, prev int, f *scriptFrame) error {
+	t := s.tokens[i]
+	p := s.tok(prev)
+
+	switch {
+	case t.is("?"):
+		if s.isOptionalMarker(i, prev, *f) {
+			s.erase(i, i+1)
+			return nil
+		}
+		f.ternary++
+	case t.is(":"):
+		switch {
+		case f.ternary > 0:
+			f.ternary--
+		case f.kind == scriptParams,
+			f.kind == scriptClass && !f.initializer,
+			(f.kind == scriptBlock || f.kind == scriptParen) && f.declaration:
+			end, ok := s.skipType(i + 1)
+			if !ok {
+				return s.errorAt(end, "type expected")
+			}
+			s.erase(i, end)
+		case f.kind == scriptBlock:
+			s.labels[i] = true
+		}
+	case t.is("!") && p.endsExpression() && !t.newline:
+		// non-null assertions and definite assignments, a prefix ! is followed by its operand
+		next := s.tok(i + 1)
+		postfix := next.kind == scriptPunctuator && !next.is("!", "~", "{")
+		if p.is(")") {
+			// if (x) !y
+			postfix = next.newline || next.is("", ".", "?.", ";", ")", "]", "}", ",", ":", "=")
+		}
+		if postfix {
+			s.erase(i, i+1)
+		}
+	case t.is("<"):
+		end, ok := s.skipTypeArguments(i)
+		if ok && s.is(end, "(") && (p.endsExpression() && !t.newline || !p.endsExpression() && s.isArrow(end)) {
+			s.erase(i, end)
+		}
+	case t.is("as", "satisfies") && p.endsExpression() && !t.newline:
+		end := i + 2
+		if !s.is(i+1, "const") {
+			var ok bool
+			if end, ok = s.skipType(i + 1); !ok {
+				return s.errorAt(end, "type expected")
+			}
+		}
+		s.erase(i, end)
+	case t.is("public", "private", "protected", "readonly", "override"):
+		next := s.tok(i + 1)
+		if next.newline || next.kind != scriptIdentifier && !next.is("[", "{") {
+			return nil
+		}
+
+		switch {
+		case f.kind == scriptParams:
+			return s.errorAt(i, "parameter properties are not supported, only types can be stripped")
+		case f.kind == scriptClass && !f.initializer:
+			s.erase(i, i+1)
+		}
+	case t.is("declare") && f.kind == scriptClass && !f.initializer && !s.tok(i+1).newline && s.tok(i+1).kind == scriptIdentifier:
+		s.erase(i, s.ambientEnd(i))
+	case t.is("abstract") && f.kind == scriptClass && !f.initializer:
+		return s.errorAt(i, "abstract members are not supported, only types can be stripped")
+	}
+
+	return nil
+}
+
+// isOptionalMarker reports whether the question mark at i marks an optional parameter or class member.
+func (s *scriptStripper) isOptionalMarker(i, prev int, f scriptFrame) bool {
+	p := s.tok(prev)
+	switch f.kind {
+	case scriptParams:
+		return p.kind == scriptIdentifier && s.is(i+1, ":", ",", ")", "=")
+	case scriptClass:
+		return !f.initializer && (p.kind == scriptIdentifier || p.kind == scriptString || p.is("]")) && s.is(i+1, ":", "(", ";", "=", "<")
+	default:
+		return false
+	}
+}
+
+// classHeader strips the type parameters, type arguments and implements clause of the class at i and marks its body.
+func (s *scriptStripper) classHeader(i int) {
+	end := i + 1
+	if s.tok(end).kind == scriptIdentifier && !s.is(end, "extends", "implements") {
+		end++
+	}
+
+	for end < len(s.tokens) && !s.is(end, "{") {
+		switch {
+		case s.types && s.is(end, "<"):
+			next, ok := s.skipTypeArguments(end)
+			if !ok {
+				return
+			}
+			s.erase(end, next)
+			end = next
+		case s.types && s.is(end, "implements"):
+			next := end
+			for ok := true; ok; ok = s.is(next, ",") {
+				if next, ok = s.skipType(next + 1); !ok {
+					return
+				}
+			}
+			s.erase(end, next)
+			end = next
+		case s.is(end, "(", "["):
+			end = s.match[end] + 1
+		default:
+			end++
+		}
+	}
+
+	s.classBodies[end] = true
+}
+
+// skipType returns the index of the token after the type which starts at i, ok is false if there is no type.
+func (s *scriptStripper) skipType(i int) (int, bool) {
+	i, ok := s.skipUnionType(i)
+	if !ok {
+		return i, false
+	}
+
+	// conditional types, T extends U ? X : Y
+	if s.is(i, "extends") && !s.tok(i).newline {
+		if i, ok = s.skipUnionType(i + 1); !ok || !s.is(i, "?") {
+			return i, false
+		}
+		if i, ok = s.skipType(i + 1); !ok || !s.is(i, ":") {
+			return i, false
+		}
+
+		return s.skipType(i + 1)
+	}
+
+	return i, true
+}
+
+// skipUnionType skips a union or intersection of types.
+func (s *scriptStripper) skipUnionType(i int) (int, bool) {
+	if s.is(i, "|", "&") {
+		i++
+	}
+
+	for {
+		var ok bool
+		if i, ok = s.skipPrimaryType(i); !ok {
+			return i, false
+		}
+
+		if !s.is(i, "|", "&") {
+			return i, true
+		}
+		i++
+	}
+}
+
+// skipPrimaryType skips a type which is not a union, intersection or conditional type.
+func (s *scriptStripper) skipPrimaryType(i int) (int, bool) {
+	for s.is(i, "keyof", "unique", "readonly", "abstract") && !s.tok(i+1).newline && !s.is(i+1, "|", "&", ",", ")", "]", "}", ";", "=", ">") {
+		i++
+	}
+
+	if s.is(i, "new") {
+		i++
+	}
+
+	t := s.tok(i)
+	switch {
+	case t.is("("):
+		end := s.match[i] + 1
+		if s.is(end, "=>") {
+			return s.skipType(end + 1)
+		}
+		i = end
+	case t.is("<"):
+		end, ok := s.skipTypeArguments(i)
+		if !ok || !s.is(end, "(") {
+			return end, false
+		}
+		if end = s.match[end] + 1; !s.is(end, "=>") {
+			return end, false
+		}
+
+		return s.skipType(end + 1)
+	case t.is("{", "["):
+		i = s.match[i] + 1
+	case t.kind == scriptString, t.kind == scriptNumber:
+		i++
+	case t.kind == scriptTemplate:
+		for s.tok(i).substitution {
+			i = s.match[i]
+		}
+		i++
+	case t.is("-") && s.tok(i+1).kind == scriptNumber:
+		i += 2
+	case t.is("infer"):
+		i += 2
+	case t.kind == scriptIdentifier:
+		if t.is("typeof", "asserts") && s.tok(i+1).kind == scriptIdentifier && !s.tok(i+1).newline {
+			i++
+		}
+		// type predicates, x is T
+		if s.is(i+1, "is") && !s.tok(i+1).newline {
+			return s.skipType(i + 2)
+		}
+
+		i++
+		for s.is(i, ".") && s.tok(i+1).kind == scriptIdentifier {
+			i += 2
+		}
+		if s.is(i, "<") && !s.tok(i).newline {
+			var ok bool
+			if i, ok = s.skipTypeArguments(i); !ok {
+				return i, false
+			}
+		}
+	default:
+		return i, false
+	}
+
+	// array types and indexed access types
+	for s.is(i, "[") && !s.tok(i).newline {
+		i = s.match[i] + 1
+	}
+
+	return i, true
+}
+
+// skipTypeArguments returns the index of the token after the type arguments or parameters which start at i.
+func (s *scriptStripper) skipTypeArguments(i int) (int, bool) {
+	depth := 0
+	for ; i < len(s.tokens); i++ {
+		t := s.tokens[i]
+		switch {
+		case t.kind == scriptTemplate:
+			for s.tok(i).substitution {
+				i = s.match[i]
+			}
+		case t.kind != scriptPunctuator:
+		case !scriptTypeArgumentPunctuators[t.text]:
+			return i, false
+		case t.is("<"):
+			depth++
+		case t.is(">"):
+			if depth--; depth == 0 {
+				return i + 1, true
+			}
+		case t.is("(", "[", "{"):
+			i = s.match[i]
+		}
+	}
+
+	return i, false
+}
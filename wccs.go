@@ -33,6 +33,22 @@ var (
 	ErrNoEntrypoint = fmt.Errorf("no entrypoint found")
 	// ErrMissingParam is returned when a parameter is missing.
 	ErrMissingParam = fmt.Errorf("missing parameter")
+	// ErrUnsupportedType is returned when a value cannot be serialized.
+	ErrUnsupportedType = fmt.Errorf("unsupported type")
+	// ErrNoSource is returned when a file has no source to read further files from.
+	ErrNoSource = fmt.Errorf("no source available")
+	// ErrPathNotAllowed is returned when a path leaves the allowed root.
+	ErrPathNotAllowed = fmt.Errorf("path not allowed")
+	// ErrLoadCycle is returned when modules load each other.
+	ErrLoadCycle = fmt.Errorf("load cycle detected")
+	// ErrModuleNotLocked is returned when a remote module has no lock file entry.
+	ErrModuleNotLocked = fmt.Errorf("module not locked")
+	// ErrDigestMismatch is returned when a remote module does not match its lock file entry.
+	ErrDigestMismatch = fmt.Errorf("digest mismatch")
+	// ErrLimitExceeded is returned when a conversion exceeds one of its limits.
+	ErrLimitExceeded = fmt.Errorf("limit exceeded")
+	// ErrNameConflict is returned when a name is predeclared twice.
+	ErrNameConflict = fmt.Errorf("name conflict")
 )
 
 type (
@@ -50,14 +66,24 @@ type (
 
 	// Converter converts the given data to a slice of files.
 	Converter interface {
-		Convert(File, Environment) ([]File, error)
+		Convert(context.Context, File, Environment) ([]File, error)
 		Compatible(f File) bool
 	}
 
+	// Source gives access to further files from the origin of a provided file,
+	// e.g. the forge repository at the pipeline commit.
+	Source interface {
+		Read(name string) (File, error)
+	}
+
 	// File represents a file.
 	File struct {
 		Name string `json:"name"`
 		Data string `json:"data"`
+		// Source the file was provided by, empty if the file was not provided by a Provider.
+		Source Source `json:"-"`
+		// Warnings about the conversion of the file, e.g. settings which were left out.
+		Warnings []string `json:"-"`
 	}
 )
 
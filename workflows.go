@@ -0,0 +1,234 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"gopkg.in/yaml.v3"
+)
+
+// starlarkFileConstructor marks the structs created by wccs.file.
+var starlarkFileConstructor = starlark.String("wccs.file")
+
+// starlarkWCCSModule is the predeclared wccs module, it is available independent of the configured modules.
+var starlarkWCCSModule = &starlarkstruct.Module{
+	Name: "wccs",
+	Members: starlark.StringDict{
+		"file": starlark.NewBuiltin("wccs.file", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var name string
+			var content starlark.Value
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "content", &content); err != nil {
+				return nil, err
+			}
+
+			if _, isString := content.(starlark.String); !isString {
+				if _, isDict := content.(*starlark.Dict); !isDict {
+					return nil, fmt.Errorf("%s: content must be a string or dict, got %s", b.Name(), content.Type())
+				}
+			}
+
+			return starlarkstruct.FromStringDict(starlarkFileConstructor, starlark.StringDict{
+				"name":    starlark.String(name),
+				"content": content,
+			}), nil
+		}),
+	},
+}
+
+// starlarkFiles turns the value returned by main into files, the entry name is used for unnamed workflows.
+// main may return
+//   - a list of named workflow dicts or wccs.file structs
+//   - a single workflow dict
+//   - a dict mapping file names to workflow dicts or YAML strings
+//   - a YAML string
+func starlarkFiles(v starlark.Value, entry string) ([]File, error) {
+	unnamed := strings.TrimSuffix(path.Base(entry), path.Ext(entry)) + ".yaml"
+
+	switch v := v.(type) {
+	case *starlark.List:
+		files := make([]File, 0, v.Len())
+		for i := range v.Len() {
+			f, err := starlarkListFile(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, f)
+		}
+
+		return files, nil
+	case starlark.String:
+		f, err := starlarkFile(unnamed, v)
+		return []File{f}, err
+	case *starlark.Dict:
+		if !isStarlarkFileMap(v) {
+			if _, found, _ := v.Get(starlark.String("name")); found {
+				f, err := starlarkNamedWorkflow(v)
+				return []File{f}, err
+			}
+
+			f, err := starlarkFile(unnamed, v)
+			return []File{f}, err
+		}
+
+		files := make([]File, 0, v.Len())
+		for _, item := range v.Items() {
+			name, _ := starlark.AsString(item[0])
+			f, err := starlarkFile(name, item[1])
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, f)
+		}
+
+		return files, nil
+	default:
+		return nil, fmt.Errorf("%w: main must return a list, dict or string, got %s", ErrUnsupportedType, v.Type())
+	}
+}
+
+// starlarkListFile converts an element of the list returned by main.
+func starlarkListFile(v starlark.Value) (File, error) {
+	switch v := v.(type) {
+	case *starlark.Dict:
+		return starlarkNamedWorkflow(v)
+	case *starlarkstruct.Struct:
+		if v.Constructor() != starlarkFileConstructor {
+			break
+		}
+
+		nameValue, _ := v.Attr("name")
+		name, _ := starlark.AsString(nameValue)
+		content, _ := v.Attr("content")
+
+		return starlarkFile(name, content)
+	}
+
+	return File{}, fmt.Errorf("%w: workflow must be a dict or wccs.file, got %s", ErrUnsupportedType, v.Type())
+}
+
+// starlarkNamedWorkflow converts a workflow whose name is used as file name,
+// the name is only used for the file name and is not part of the workflow itself.
+func starlarkNamedWorkflow(workflow *starlark.Dict) (File, error) {
+	nameValue, _, err := workflow.Get(starlark.String("name"))
+	if err != nil {
+		return File{}, err
+	}
+
+	name, ok := starlark.AsString(nameValue)
+	if !ok || name == "" {
+		return File{}, fmt.Errorf("%w: name", ErrMissingParam)
+	}
+
+	body := starlark.NewDict(workflow.Len())
+	for _, item := range workflow.Items() {
+		if item[0] == starlark.String("name") {
+			continue
+		}
+
+		if err := body.SetKey(item[0], item[1]); err != nil {
+			return File{}, err
+		}
+	}
+
+	return starlarkFile(strings.TrimSuffix(name, path.Ext(name))+".yaml", body)
+}
+
+// starlarkFile converts a workflow dict or YAML string into a file with the given name.
+func starlarkFile(name string, content starlark.Value) (File, error) {
+	name, err := resolveModulePath("", name)
+	if err != nil {
+		return File{}, err
+	}
+
+	switch content := content.(type) {
+	case starlark.String:
+		// make sure woodpecker gets valid YAML, errors are easier to find here
+		if err := checkWorkflowYAML(string(content)); err != nil {
+			return File{}, fmt.Errorf("%w: workflow %s", err, name)
+		}
+
+		return File{Name: name, Data: string(content)}, nil
+	case *starlark.Dict:
+		node, err := StarlarkToYAML(content)
+		if err != nil {
+			return File{}, fmt.Errorf("%w: workflow %s", err, name)
+		}
+
+		data, err := encodeYAML(node)
+		if err != nil {
+			return File{}, err
+		}
+
+		return File{Name: name, Data: data}, nil
+	default:
+		return File{}, fmt.Errorf("%w: workflow %s must be a dict or string, got %s", ErrUnsupportedType, name, content.Type())
+	}
+}
+
+// checkWorkflowYAML decodes all documents of the YAML string, a workflow file must not contain more than one.
+func checkWorkflowYAML(data string) error {
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	for documents := 0; ; {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// empty documents, e.g. of a trailing separator, are ignored by woodpecker as well
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+
+		if documents++; documents > 1 {
+			return fmt.Errorf("%w: multiple YAML documents, return a workflow per file instead", ErrUnsupportedType)
+		}
+	}
+}
+
+// isStarlarkFileMap reports whether the dict maps YAML file names to workflows.
+func isStarlarkFileMap(d *starlark.Dict) bool {
+	if d.Len() == 0 {
+		return false
+	}
+
+	for _, item := range d.Items() {
+		name, ok := starlark.AsString(item[0])
+		if !ok || !slices.Contains([]string{".yaml", ".yml"}, path.Ext(name)) {
+			return false
+		}
+
+		switch item[1].(type) {
+		case starlark.String, *starlark.Dict:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 OpenCloud GmbH
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wccs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wccs "github.com/opencloud-eu/woodpecker-ci-config-service"
+)
+
+func TestStarlarkConverter_ReturnShapes(t *testing.T) {
+	c, err := wccs.NewStarlarkConverter(noopLogger)
+	assert.NoError(t, err)
+
+	convert := func(t *testing.T, result string) ([]wccs.File, error) {
+		t.Helper()
+
+		return c.Convert(t.Context(), wccs.File{Name: ".woodpecker/ci.star", Data: "def main(ctx):\n  return " + result}, wccs.Environment{})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		result   string
+		expected []wccs.File
+	}{
+		{
+			name:     "a list of named workflows",
+			result:   `[{"name": "test.star", "steps": []}]`,
+			expected: []wccs.File{{Name: "test.yaml", Data: "steps: []\n"}},
+		},
+		{
+			name:     "a single named workflow",
+			result:   `{"name": "test", "steps": []}`,
+			expected: []wccs.File{{Name: "test.yaml", Data: "steps: []\n"}},
+		},
+		{
+			name:     "a single unnamed workflow",
+			result:   `{"steps": []}`,
+			expected: []wccs.File{{Name: "ci.yaml", Data: "steps: []\n"}},
+		},
+		{
+			name:   "a dict of file names",
+			result: `{"build/test.yaml": {"name": "unit tests", "steps": []}, "lint.yml": "steps: []\n"}`,
+			expected: []wccs.File{
+				{Name: "build/test.yaml", Data: "name: unit tests\nsteps: []\n"},
+				{Name: "lint.yml", Data: "steps: []\n"},
+			},
+		},
+		{
+			name:     "a YAML string",
+			result:   `"steps: []\n"`,
+			expected: []wccs.File{{Name: "ci.yaml", Data: "steps: []\n"}},
+		},
+		{
+			name:   "a list of files",
+			result: `[wccs.file(name="test.yaml", content={"name": "test", "steps": []}), wccs.file(name="raw.yaml", content="steps: []\n")]`,
+			expected: []wccs.File{
+				{Name: "test.yaml", Data: "name: test\nsteps: []\n"},
+				{Name: "raw.yaml", Data: "steps: []\n"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			files, err := convert(t, tc.result)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, files)
+		})
+	}
+
+	t.Run("fails on invalid file names", func(t *testing.T) {
+		_, err := convert(t, `{"../test.yaml": {"steps": []}}`)
+		assert.ErrorIs(t, err, wccs.ErrPathNotAllowed)
+
+		_, err = convert(t, `[wccs.file(name="/test.yaml", content="steps: []")]`)
+		assert.ErrorIs(t, err, wccs.ErrPathNotAllowed)
+	})
+
+	t.Run("fails on invalid YAML strings", func(t *testing.T) {
+		_, err := convert(t, `"steps: ["`)
+		assert.ErrorContains(t, err, "ci.yaml")
+
+		_, err = convert(t, `{"lint.yaml": "steps: []\n---\nsteps: [\n"}`)
+		assert.ErrorContains(t, err, "lint.yaml")
+	})
+
+	t.Run("fails on YAML strings with multiple documents", func(t *testing.T) {
+		_, err := convert(t, `"steps: []\n---\nsteps: []\n"`)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+		assert.ErrorContains(t, err, "ci.yaml")
+
+		files, err := convert(t, `"---\nsteps: []\n---\n"`)
+		assert.NoError(t, err)
+		assert.Equal(t, []wccs.File{{Name: "ci.yaml", Data: "---\nsteps: []\n---\n"}}, files)
+	})
+
+	t.Run("fails on unsupported values", func(t *testing.T) {
+		_, err := convert(t, `1`)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+
+		_, err = convert(t, `[struct(name="test")]`)
+		assert.ErrorIs(t, err, wccs.ErrUnsupportedType)
+
+		_, err = convert(t, `[wccs.file(name="test.yaml", content=1)]`)
+		assert.ErrorContains(t, err, "content must be a string or dict")
+	})
+}